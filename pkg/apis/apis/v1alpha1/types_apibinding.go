@@ -18,6 +18,7 @@ package v1alpha1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
 )
@@ -126,6 +127,12 @@ type WorkspaceExportReference struct {
 	// +kubebuilder:validation:Required
 	// +kube:validation:MinLength=1
 	ExportName string `json:"exportName"`
+
+	// uid is the UID of the APIExport. If it is set, only the APIExport of this UID is referenced,
+	// i.e. not another APIExport recreated with the same name.
+	//
+	// +optional
+	UID types.UID `json:"uid,omitempty"`
 }
 
 // APIBindingPhaseType is the type of the current phase of an APIBinding.
@@ -138,6 +145,12 @@ const (
 
 // APIBindingStatus records which schemas are bound.
 type APIBindingStatus struct {
+	// apiExportClusterName records the name (not path) of the logical cluster of the referenced APIExport,
+	// once resolved.
+	//
+	// +optional
+	APIExportClusterName string `json:"apiExportClusterName,omitempty"`
+
 	// boundResources records the state of bound APIs.
 	//
 	// +optional
@@ -266,6 +279,14 @@ type BoundAPIResource struct {
 	// +optional
 	// +listType=set
 	StorageVersions []string `json:"storageVersions,omitempty"`
+
+	// verbs restricts the verbs permitted on the bound resource before the maximal permission policy
+	// of the APIExport is evaluated, as listed in boundResourceVerbs of the APIExport. "*" permits all
+	// verbs. If empty, all verbs are permitted.
+	//
+	// +optional
+	// +listType=set
+	Verbs []string `json:"verbs,omitempty"`
 }
 
 // BoundAPIResourceSchema is a reference to an APIResourceSchema.
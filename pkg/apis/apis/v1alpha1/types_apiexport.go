@@ -35,6 +35,10 @@ const (
 	APIExportVirtualWorkspaceURLsReady conditionsv1alpha1.ConditionType = "VirtualWorkspaceURLsReady"
 
 	ErrorGeneratingURLsReason = "ErrorGeneratingURLs"
+
+	// APIExportDeprecated is set to true by the owner of an APIExport that is going away. The message
+	// is surfaced as a warning to the clients of resources bound to the APIExport.
+	APIExportDeprecated conditionsv1alpha1.ConditionType = "Deprecated"
 )
 
 // These are for APIExport identity.
@@ -138,6 +142,13 @@ type APIExportSpec struct {
 	// +optional
 	MaximalPermissionPolicy *MaximalPermissionPolicy `json:"maximalPermissionPolicy,omitempty"`
 
+	// boundResourceVerbs restricts the verbs permitted on the resources of this APIExport in the
+	// workspaces binding it, before the maximal permission policy is evaluated. The verbs are recorded
+	// in the bound resources of the APIBindings. Resources not listed permit all verbs.
+	//
+	// +optional
+	BoundResourceVerbs []ResourceVerbsPolicy `json:"boundResourceVerbs,omitempty"`
+
 	// permissionClaims make resources available in APIExport's virtual workspace that are not part
 	// of the actual APIExport resources.
 	//
@@ -170,6 +181,35 @@ type MaximalPermissionPolicy struct {
 	// local is the policy that is defined in same workspace as the API Export.
 	// +optional
 	Local *LocalAPIExportPolicy `json:"local,omitempty"`
+
+	// global is the policy that is defined in same workspace as the API Export,
+	// granting to the users and groups of the consumers as they are.
+	// +optional
+	Global *GlobalAPIExportPolicy `json:"global,omitempty"`
+
+	// verbs is a lightweight alternative to the local policy, listing the allowed verbs
+	// per resource directly instead of through RBAC. Resources not listed are not permitted.
+	// +optional
+	Verbs []ResourceVerbsPolicy `json:"verbs,omitempty"`
+}
+
+// ResourceVerbsPolicy lists the verbs allowed on a resource by a maximal permission policy.
+type ResourceVerbsPolicy struct {
+	// group is the API group of the resource. Empty means the core group.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// resource is the name of the resource, or "<resource>/<subresource>" for a subresource.
+	// +required
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Resource string `json:"resource"`
+
+	// verbs are the allowed verbs. "*" allows all verbs.
+	// +required
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	Verbs []string `json:"verbs"`
 }
 
 // LocalAPIExportPolicy is a maximal permission policy
@@ -179,6 +219,14 @@ type MaximalPermissionPolicy struct {
 // with "apis.kcp.dev:binding:".
 type LocalAPIExportPolicy struct{}
 
+// GlobalAPIExportPolicy is a maximal permission policy
+// that checks RBAC in the workspace of the API Export.
+//
+// Unlike with LocalAPIExportPolicy, the user and group names are not prefixed,
+// i.e. RBAC in the workspace of the API Export grants to the users and groups
+// of the consumers directly, regardless of the workspace they live in.
+type GlobalAPIExportPolicy struct{}
+
 const (
 	APIExportPermissionClaimLabelPrefix = "claimed.internal.apis.kcp.dev/"
 )
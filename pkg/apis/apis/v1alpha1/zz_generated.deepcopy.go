@@ -231,6 +231,13 @@ func (in *APIExportSpec) DeepCopyInto(out *APIExportSpec) {
 		*out = new(MaximalPermissionPolicy)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.BoundResourceVerbs != nil {
+		in, out := &in.BoundResourceVerbs, &out.BoundResourceVerbs
+		*out = make([]ResourceVerbsPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.PermissionClaims != nil {
 		in, out := &in.PermissionClaims, &out.PermissionClaims
 		*out = make([]PermissionClaim, len(*in))
@@ -415,6 +422,11 @@ func (in *BoundAPIResource) DeepCopyInto(out *BoundAPIResource) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Verbs != nil {
+		in, out := &in.Verbs, &out.Verbs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -465,6 +477,22 @@ func (in *ExportReference) DeepCopy() *ExportReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GlobalAPIExportPolicy) DeepCopyInto(out *GlobalAPIExportPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalAPIExportPolicy.
+func (in *GlobalAPIExportPolicy) DeepCopy() *GlobalAPIExportPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(GlobalAPIExportPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GroupResource) DeepCopyInto(out *GroupResource) {
 	*out = *in
@@ -526,6 +554,18 @@ func (in *MaximalPermissionPolicy) DeepCopyInto(out *MaximalPermissionPolicy) {
 		*out = new(LocalAPIExportPolicy)
 		**out = **in
 	}
+	if in.Global != nil {
+		in, out := &in.Global, &out.Global
+		*out = new(GlobalAPIExportPolicy)
+		**out = **in
+	}
+	if in.Verbs != nil {
+		in, out := &in.Verbs, &out.Verbs
+		*out = make([]ResourceVerbsPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -556,6 +596,27 @@ func (in *PermissionClaim) DeepCopy() *PermissionClaim {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceVerbsPolicy) DeepCopyInto(out *ResourceVerbsPolicy) {
+	*out = *in
+	if in.Verbs != nil {
+		in, out := &in.Verbs, &out.Verbs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceVerbsPolicy.
+func (in *ResourceVerbsPolicy) DeepCopy() *ResourceVerbsPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceVerbsPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtualWorkspace) DeepCopyInto(out *VirtualWorkspace) {
 	*out = *in
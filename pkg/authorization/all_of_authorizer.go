@@ -0,0 +1,45 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+// NewAllOfAuthorizer returns an authorizer allowing a request only if all of the given authorizers allow it,
+// e.g. to chain the MaximalPermissionPolicyAuthorizer with custom checks. The authorizers are called in order
+// until the first one not allowing the request, whose decision, reason and error are returned. Without
+// authorizers it has no opinion.
+func NewAllOfAuthorizer(authorizers ...authorizer.Authorizer) authorizer.Authorizer {
+	return authorizer.AuthorizerFunc(func(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+		if len(authorizers) == 0 {
+			return authorizer.DecisionNoOpinion, "no authorizers", nil
+		}
+
+		var reason string
+		for _, a := range authorizers {
+			dec, r, err := a.Authorize(ctx, attr)
+			if dec != authorizer.DecisionAllow {
+				return dec, r, err
+			}
+			reason = r
+		}
+		return authorizer.DecisionAllow, reason, nil
+	})
+}
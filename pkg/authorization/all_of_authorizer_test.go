@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+func TestAllOfAuthorizer(t *testing.T) {
+	errQuota := errors.New("quota unavailable")
+
+	for _, tt := range []struct {
+		name       string
+		decisions  []authorizer.Decision
+		errs       []error
+		wantDec    authorizer.Decision
+		wantReason string
+		wantErr    error
+		wantCalls  []int
+	}{
+		{name: "no authorizers", wantDec: authorizer.DecisionNoOpinion, wantReason: "no authorizers"},
+		{name: "all allow", decisions: []authorizer.Decision{authorizer.DecisionAllow, authorizer.DecisionAllow}, wantDec: authorizer.DecisionAllow, wantReason: "reason 1", wantCalls: []int{1, 1}},
+		{name: "first denies", decisions: []authorizer.Decision{authorizer.DecisionDeny, authorizer.DecisionAllow}, wantDec: authorizer.DecisionDeny, wantReason: "reason 0", wantCalls: []int{1, 0}},
+		{name: "second has no opinion", decisions: []authorizer.Decision{authorizer.DecisionAllow, authorizer.DecisionNoOpinion, authorizer.DecisionAllow}, wantDec: authorizer.DecisionNoOpinion, wantReason: "reason 1", wantCalls: []int{1, 1, 0}},
+		{name: "error", decisions: []authorizer.Decision{authorizer.DecisionNoOpinion, authorizer.DecisionAllow}, errs: []error{errQuota, nil}, wantDec: authorizer.DecisionNoOpinion, wantReason: "reason 0", wantErr: errQuota, wantCalls: []int{1, 0}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := make([]int, len(tt.decisions))
+			authorizers := make([]authorizer.Authorizer, len(tt.decisions))
+			for i := range tt.decisions {
+				i := i
+				authorizers[i] = authorizer.AuthorizerFunc(func(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+					calls[i]++
+					var err error
+					if tt.errs != nil {
+						err = tt.errs[i]
+					}
+					return tt.decisions[i], fmt.Sprintf("reason %d", i), err
+				})
+			}
+
+			dec, reason, err := NewAllOfAuthorizer(authorizers...).Authorize(context.Background(), &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "create",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.Equal(t, tt.wantErr, err)
+			require.Equal(t, tt.wantDec, dec)
+			require.Equal(t, tt.wantReason, reason)
+			if tt.wantCalls != nil {
+				require.Equal(t, tt.wantCalls, calls)
+			}
+		})
+	}
+}
+
+func TestAllOfAuthorizerWithMaximalPermissionPolicy(t *testing.T) {
+	quotaCalled := false
+	quota := authorizer.AuthorizerFunc(func(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+		quotaCalled = true
+		return authorizer.DecisionAllow, "", nil
+	})
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		)},
+		[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+		&recordingAuthorizer{decision: authorizer.DecisionNoOpinion}, quota,
+	)
+
+	ctx, ev := withAuditEvent(withCluster("root:consumer"))
+	dec, reason, err := NewAllOfAuthorizer(a, quota).Authorize(ctx, &authorizer.AttributesRecord{
+		User:            &user.DefaultInfo{Name: "user-1"},
+		Verb:            "create",
+		APIGroup:        "widgets.example.io",
+		Resource:        "widgets",
+		ResourceRequest: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, authorizer.DecisionNoOpinion, dec)
+	require.Equal(t, ReasonPolicyDenied, reason)
+	require.Equal(t, DecisionNoOpinion, ev.Annotations[MaximalPermissionPolicyAuditDecision])
+	require.False(t, quotaCalled, "expected the quota check to be skipped")
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package authorizationtest provides test support for authorizer chains embedding the authorizers of the
+// authorization package. It is not meant for production use.
+package authorizationtest
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	"github.com/kcp-dev/kcp/pkg/authorization"
+)
+
+// VerbResource keys the canned decisions of the FakeMaximalPermissionPolicyAuthorizer. Resource is the requested
+// resource, followed by "/" and the subresource if any, e.g. "widgets/status".
+type VerbResource struct {
+	Verb     string
+	Resource string
+}
+
+// FakeMaximalPermissionPolicyAuthorizer is an authorizer.Authorizer returning canned decisions in place of a
+// MaximalPermissionPolicyAuthorizer, without informers or RBAC. It is meant for tests only, e.g. of authorizer
+// chains, and evaluates no maximal permission policy at all.
+type FakeMaximalPermissionPolicyAuthorizer struct {
+	decisions map[VerbResource]authorizer.Decision
+
+	// DefaultDecision is returned for requests without canned decision, DecisionNoOpinion unless set.
+	DefaultDecision authorizer.Decision
+
+	lock     sync.Mutex
+	requests []authorizer.Attributes
+}
+
+var _ authorizer.Authorizer = &FakeMaximalPermissionPolicyAuthorizer{}
+
+// NewFakeMaximalPermissionPolicyAuthorizer returns a fake authorizer returning the given decisions by verb and
+// resource. It is not meant for production use.
+func NewFakeMaximalPermissionPolicyAuthorizer(decisions map[VerbResource]authorizer.Decision) *FakeMaximalPermissionPolicyAuthorizer {
+	return &FakeMaximalPermissionPolicyAuthorizer{
+		decisions:       decisions,
+		DefaultDecision: authorizer.DecisionNoOpinion,
+	}
+}
+
+// Authorize returns the canned decision of the verb and resource of the request, along with the reason code
+// the MaximalPermissionPolicyAuthorizer would return, or the default decision.
+func (f *FakeMaximalPermissionPolicyAuthorizer) Authorize(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+	f.lock.Lock()
+	f.requests = append(f.requests, attr)
+	f.lock.Unlock()
+
+	resource := attr.GetResource()
+	if subresource := attr.GetSubresource(); subresource != "" {
+		resource += "/" + subresource
+	}
+	dec, ok := f.decisions[VerbResource{Verb: attr.GetVerb(), Resource: resource}]
+	if !ok {
+		return f.DefaultDecision, authorization.ReasonNotBound, nil
+	}
+	if dec == authorizer.DecisionAllow {
+		return dec, authorization.ReasonPolicyAllowed, nil
+	}
+	return dec, authorization.ReasonPolicyDenied, nil
+}
+
+// Requests returns the attributes of the requests authorized so far, in order.
+func (f *FakeMaximalPermissionPolicyAuthorizer) Requests() []authorizer.Attributes {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]authorizer.Attributes(nil), f.requests...)
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorizationtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	"github.com/kcp-dev/kcp/pkg/authorization"
+)
+
+func TestFakeMaximalPermissionPolicyAuthorizer(t *testing.T) {
+	fake := NewFakeMaximalPermissionPolicyAuthorizer(map[VerbResource]authorizer.Decision{
+		{Verb: "get", Resource: "widgets"}:           authorizer.DecisionAllow,
+		{Verb: "delete", Resource: "widgets"}:        authorizer.DecisionDeny,
+		{Verb: "update", Resource: "widgets/status"}: authorizer.DecisionNoOpinion,
+	})
+
+	for _, tt := range []struct {
+		name         string
+		attr         authorizer.AttributesRecord
+		wantDecision authorizer.Decision
+		wantReason   string
+	}{
+		{name: "allowed", attr: authorizer.AttributesRecord{Verb: "get", Resource: "widgets"}, wantDecision: authorizer.DecisionAllow, wantReason: authorization.ReasonPolicyAllowed},
+		{name: "denied", attr: authorizer.AttributesRecord{Verb: "delete", Resource: "widgets"}, wantDecision: authorizer.DecisionDeny, wantReason: authorization.ReasonPolicyDenied},
+		{name: "subresource", attr: authorizer.AttributesRecord{Verb: "update", Resource: "widgets", Subresource: "status"}, wantDecision: authorizer.DecisionNoOpinion, wantReason: authorization.ReasonPolicyDenied},
+		{name: "unmatched verb", attr: authorizer.AttributesRecord{Verb: "list", Resource: "widgets"}, wantDecision: authorizer.DecisionNoOpinion, wantReason: authorization.ReasonNotBound},
+		{name: "unmatched subresource", attr: authorizer.AttributesRecord{Verb: "get", Resource: "widgets", Subresource: "status"}, wantDecision: authorizer.DecisionNoOpinion, wantReason: authorization.ReasonNotBound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dec, reason, err := fake.Authorize(context.Background(), &tt.attr)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, tt.wantReason, reason)
+		})
+	}
+	require.Len(t, fake.Requests(), 5)
+
+	fake.DefaultDecision = authorizer.DecisionAllow
+	dec, _, err := fake.Authorize(context.Background(), &authorizer.AttributesRecord{Verb: "list", Resource: "gadgets"})
+	require.NoError(t, err)
+	require.Equal(t, authorizer.DecisionAllow, dec)
+}
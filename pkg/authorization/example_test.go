@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization_test
+
+import (
+	"context"
+	"fmt"
+
+	kcpfakeclient "github.com/kcp-dev/client-go/clients/clientset/versioned/fake"
+	kcpkubernetesinformers "github.com/kcp-dev/client-go/clients/informers"
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/authorization"
+	kcpfakeinformerclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/fake"
+	kcpinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+// Wiring of the maximal permission policy authorizer: a consumer workspace binds widgets of an API export
+// whose maximal permission policy only allows to get widgets.
+func ExampleNewMaximalPermissionPolicyAuthorizer() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the API export in root:provider and its RBAC, granting get on widgets to all users of bindings
+	export := &apisv1alpha1.APIExport{
+		ObjectMeta: metav1.ObjectMeta{Name: "widgets", Annotations: map[string]string{logicalcluster.AnnotationKey: "root:provider"}},
+		Spec:       apisv1alpha1.APIExportSpec{MaximalPermissionPolicy: &apisv1alpha1.MaximalPermissionPolicy{Local: &apisv1alpha1.LocalAPIExportPolicy{}}},
+	}
+	clusterRole := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "widgets-getter", Annotations: map[string]string{logicalcluster.AnnotationKey: "root:provider"}},
+		Rules:      []rbacv1.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}}},
+	}
+	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "widgets-getter", Annotations: map[string]string{logicalcluster.AnnotationKey: "root:provider"}},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "widgets-getter"},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, APIGroup: rbacv1.GroupName, Name: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + user.AllAuthenticated}},
+	}
+
+	// the API binding in root:consumer, binding the widgets of the API export
+	binding := &apisv1alpha1.APIBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "widgets", Annotations: map[string]string{logicalcluster.AnnotationKey: "root:consumer"}},
+		Spec: apisv1alpha1.APIBindingSpec{
+			Reference: apisv1alpha1.ExportReference{Workspace: &apisv1alpha1.WorkspaceExportReference{Path: "root:provider", ExportName: "widgets"}},
+		},
+		Status: apisv1alpha1.APIBindingStatus{
+			BoundResources: []apisv1alpha1.BoundAPIResource{{Group: "widgets.example.io", Resource: "widgets"}},
+		},
+	}
+
+	kubeInformers := kcpkubernetesinformers.NewSharedInformerFactory(kcpfakeclient.NewSimpleClientset(clusterRole, clusterRoleBinding), 0)
+	kcpInformers := kcpinformers.NewSharedInformerFactoryWithOptions(kcpfakeinformerclient.NewSimpleClientset(export, binding), 0,
+		kcpinformers.WithExtraClusterScopedIndexers(indexers.ClusterScoped()),
+	)
+
+	// the delegate stands for the authorizers of the consumer workspace, here allowing everything
+	delegate := authorizer.AuthorizerFunc(func(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+		return authorizer.DecisionAllow, "", nil
+	})
+	maximalPermissionPolicyAuthorizer, err := authorization.NewMaximalPermissionPolicyAuthorizer(kubeInformers, kcpInformers, delegate)
+	if err != nil {
+		panic(err)
+	}
+
+	kubeInformers.Start(ctx.Done())
+	kcpInformers.Start(ctx.Done())
+	kubeInformers.WaitForCacheSync(ctx.Done())
+	kcpInformers.WaitForCacheSync(ctx.Done())
+
+	consumerCtx := genericapirequest.WithCluster(ctx, genericapirequest.Cluster{Name: logicalcluster.New("root:consumer")})
+	for _, verb := range []string{"get", "delete"} {
+		dec, _, err := maximalPermissionPolicyAuthorizer.Authorize(consumerCtx, &authorizer.AttributesRecord{
+			User:            &user.DefaultInfo{Name: "user-1", Groups: []string{user.AllAuthenticated}},
+			Verb:            verb,
+			APIGroup:        "widgets.example.io",
+			Resource:        "widgets",
+			ResourceRequest: true,
+		})
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s widgets: %s\n", verb, authorization.DecisionString(dec))
+	}
+
+	// Output:
+	// get widgets: Allowed
+	// delete widgets: NoOpinion
+}
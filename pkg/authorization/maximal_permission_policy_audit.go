@@ -0,0 +1,190 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	kaudit "k8s.io/apiserver/pkg/audit"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/klog/v2"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+// Audit annotations recorded by the MaximalPermissionPolicyAuthorizer, see addAuditAnnotations.
+const (
+	MaximalPermissionPolicyAuditPrefix   = "maxpermissionpolicy.authorization.kcp.dev/"
+	MaximalPermissionPolicyAuditDecision = MaximalPermissionPolicyAuditPrefix + "decision"
+	MaximalPermissionPolicyAuditReason   = MaximalPermissionPolicyAuditPrefix + "reason"
+
+	// MaximalPermissionPolicyAuditReasonCode is the reason code of requests allowed by the maximal permission policy,
+	// e.g. ReasonPolicyAllowed or ReasonNotBound, whether allowed right away or delegated, and ReasonRequestCanceled if
+	// canceled before delegating.
+	MaximalPermissionPolicyAuditReasonCode = MaximalPermissionPolicyAuditPrefix + "reason-code"
+
+	// MaximalPermissionPolicyAuditRBACDecision records the decision of the RBAC evaluation in the API export cluster.
+	// It distinguishes an explicit deny from the lack of a grant, both of which are not permitted by the policy.
+	MaximalPermissionPolicyAuditRBACDecision = MaximalPermissionPolicyAuditPrefix + "rbac-decision"
+
+	// MaximalPermissionPolicyAuditRBACUser and MaximalPermissionPolicyAuditRBACGroupCount record the prefixed user name
+	// and the number of prefixed groups the RBAC evaluation in the API export cluster did not permit, e.g. to tell
+	// users without groups apart.
+	MaximalPermissionPolicyAuditRBACUser       = MaximalPermissionPolicyAuditPrefix + "rbac-user"
+	MaximalPermissionPolicyAuditRBACGroupCount = MaximalPermissionPolicyAuditPrefix + "rbac-group-count"
+
+	// MaximalPermissionPolicyAuditAdminClusterMerge is set to "disabled" if the RBAC of the local admin cluster
+	// was not merged for the request, see WithoutAdminClusterRBACMerge and MaximalPermissionPolicyAdminClusterMergeAnnotationKey.
+	MaximalPermissionPolicyAuditAdminClusterMerge = MaximalPermissionPolicyAuditPrefix + "admin-cluster-merge"
+
+	// MaximalPermissionPolicyAuditSystemMastersBypass is set to "true" if the maximal permission policy was bypassed
+	// for a member of the system:masters group, see WithSystemMastersBypass.
+	MaximalPermissionPolicyAuditSystemMastersBypass = MaximalPermissionPolicyAuditPrefix + "system-masters-bypass"
+
+	// MaximalPermissionPolicyAuditExemptGroup records the exempt group of the user whose request was delegated
+	// without evaluating the maximal permission policy, see WithExemptGroups.
+	MaximalPermissionPolicyAuditExemptGroup = MaximalPermissionPolicyAuditPrefix + "exempt-group"
+
+	// MaximalPermissionPolicyAuditExemptNamespace records the exempt namespace of the request that was delegated
+	// without evaluating the maximal permission policy, see WithExemptNamespaces.
+	MaximalPermissionPolicyAuditExemptNamespace = MaximalPermissionPolicyAuditPrefix + "exempt-namespace"
+
+	// MaximalPermissionPolicyAuditPolicyVariant records the variant of the maximal permission policy that was evaluated,
+	// i.e. "verbs", "local" or "global".
+	MaximalPermissionPolicyAuditPolicyVariant = MaximalPermissionPolicyAuditPrefix + "policy-variant"
+
+	// MaximalPermissionPolicyAuditCanceled is set if the request was not authorized because its context was done,
+	// to "rbac" if before the RBAC evaluation in the API export cluster, to "delegate" if before delegating.
+	MaximalPermissionPolicyAuditCanceled = MaximalPermissionPolicyAuditPrefix + "canceled"
+
+	// MaximalPermissionPolicyAuditDecisionCache is set to "hit" if the RBAC decision was served from the cache
+	// enabled with WithDecisionCache.
+	MaximalPermissionPolicyAuditDecisionCache = MaximalPermissionPolicyAuditPrefix + "decision-cache"
+
+	// MaximalPermissionPolicyAuditBinding records the name of the API binding binding the requested resource.
+	MaximalPermissionPolicyAuditBinding = MaximalPermissionPolicyAuditPrefix + "binding"
+
+	// MaximalPermissionPolicyAuditEnforcement is set to "shadow" if the decision recorded by the other annotations
+	// was not enforced, but the request delegated, see WithEnforcement.
+	MaximalPermissionPolicyAuditEnforcement = MaximalPermissionPolicyAuditPrefix + "enforcement"
+
+	// MaximalPermissionPolicyAuditInheritedFrom records the ancestor cluster of the requesting cluster whose API binding
+	// binds the requested resource, see WithInheritanceLookup.
+	MaximalPermissionPolicyAuditInheritedFrom = MaximalPermissionPolicyAuditPrefix + "inherited-from"
+
+	// MaximalPermissionPolicyAuditVirtualResource is "true" if the requested resource is not bound by an API binding,
+	// but served by the API export identified by the matcher of WithVirtualResourceMatcher.
+	MaximalPermissionPolicyAuditVirtualResource = MaximalPermissionPolicyAuditPrefix + "virtual-resource"
+
+	// MaximalPermissionPolicyAuditMatchingAPIBindings lists the comma separated names of all API bindings binding
+	// the requested resource if there are multiple. The first one by name is evaluated.
+	MaximalPermissionPolicyAuditMatchingAPIBindings = MaximalPermissionPolicyAuditPrefix + "matching-api-bindings"
+
+	// MaximalPermissionPolicyAuditCandidateDecision records the decision the candidate policy would have made.
+	MaximalPermissionPolicyAuditCandidateDecision = MaximalPermissionPolicyAuditPrefix + "candidate-decision"
+)
+
+// auditAnnotations buffers the audit annotations of a request to the MaximalPermissionPolicyAuthorizer, which writes
+// them once the decision is final. Audit annotations cannot be overwritten, i.e. without buffering an annotation written
+// by an intermediate step, e.g. the decision of the RBAC evaluation before delegating, would hide the final one.
+type auditAnnotations struct {
+	keys   []string
+	values map[string]string
+}
+
+// withAuditAnnotations returns a context buffering the audit annotations of the authorizer.
+func withAuditAnnotations(ctx context.Context) (context.Context, *auditAnnotations) {
+	annotations := &auditAnnotations{values: map[string]string{}}
+	return context.WithValue(ctx, auditAnnotationsKey, annotations), annotations
+}
+
+// addAuditAnnotations records the audit annotations of the key value pairs, replacing earlier values of the same keys.
+// Outside of a context buffering the audit annotations, they are written right away.
+func addAuditAnnotations(ctx context.Context, keysAndValues ...string) {
+	annotations, ok := ctx.Value(auditAnnotationsKey).(*auditAnnotations)
+	if !ok {
+		kaudit.AddAuditAnnotations(ctx, keysAndValues...)
+		return
+	}
+
+	if len(keysAndValues)%2 != 0 {
+		klog.Errorf("Dropping mismatched audit annotation %q", keysAndValues[len(keysAndValues)-1])
+		keysAndValues = keysAndValues[:len(keysAndValues)-1]
+	}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, value := keysAndValues[i], keysAndValues[i+1]
+		if _, ok := annotations.values[key]; !ok {
+			annotations.keys = append(annotations.keys, key)
+		}
+		annotations.values[key] = value
+	}
+}
+
+// write writes the buffered audit annotations at once, in the order they were first recorded.
+func (a *auditAnnotations) write(ctx context.Context) {
+	if len(a.keys) == 0 {
+		return
+	}
+	kaudit.AddAuditAnnotations(ctx, a.keysAndValues()...)
+}
+
+// keysAndValues returns the buffered audit annotations as key value pairs, in the order they were first recorded.
+func (a *auditAnnotations) keysAndValues() []string {
+	keysAndValues := make([]string, 0, 2*len(a.keys))
+	for _, key := range a.keys {
+		keysAndValues = append(keysAndValues, key, a.values[key])
+	}
+	return keysAndValues
+}
+
+// auditCandidatePolicy evaluates the candidate policy of the API export, if any, and records its would-be decision.
+func (a *MaximalPermissionPolicyAuthorizer) auditCandidatePolicy(ctx context.Context, attr authorizer.Attributes, apiExport *apisv1alpha1.APIExport, lcluster logicalcluster.Name, group string) {
+	value, ok := apiExport.Annotations[MaximalPermissionPolicyCandidateAnnotationKey]
+	if !ok || ctx.Err() != nil {
+		return
+	}
+
+	decision := DecisionAllowed
+	var candidate apisv1alpha1.MaximalPermissionPolicy
+	if err := json.Unmarshal([]byte(value), &candidate); err != nil {
+		decision = "Error"
+	} else if len(candidate.Verbs) > 0 {
+		if !verbsPolicyAllows(candidate.Verbs, group, resourceWithSubresource(attr), attr.GetVerb()) {
+			decision = DecisionNoOpinion
+		}
+	} else if candidate.Local != nil {
+		clusterAuthorizer := a.newAuthorizer(logicalcluster.From(apiExport), a.rbacMergeClusters(ctx, lcluster, apiExport))
+		prefixedAttr := prefixedAttributes(attr, group, MaximalPermissionPolicyCandidateRBACUserGroupPrefix, a.userNameNormalizer)
+		if a.collectionGetAsList {
+			prefixedAttr = collectionGetAsList(prefixedAttr)
+		}
+		dec, _, err := clusterAuthorizer.Authorize(ctx, prefixedAttr)
+		if err != nil {
+			decision = "Error"
+		} else if dec != authorizer.DecisionAllow {
+			decision = DecisionNoOpinion
+		}
+	}
+
+	if a.metrics {
+		candidateDecisions.WithLabelValues(decision).Inc()
+	}
+	addAuditAnnotations(ctx, MaximalPermissionPolicyAuditCandidateDecision, decision)
+}
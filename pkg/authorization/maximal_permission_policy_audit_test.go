@@ -0,0 +1,188 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/kubernetes/plugin/pkg/auth/authorizer/rbac"
+	"k8s.io/utils/pointer"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+func TestAuditAnnotations(t *testing.T) {
+	ctx, ev := withAuditEvent(context.Background())
+	ctx, annotations := withAuditAnnotations(ctx)
+
+	addAuditAnnotations(ctx,
+		MaximalPermissionPolicyAuditDecision, DecisionAllowed,
+		MaximalPermissionPolicyAuditReason, "allowed by RBAC",
+	)
+	addAuditAnnotations(ctx, MaximalPermissionPolicyAuditBinding, "widgets")
+	addAuditAnnotations(ctx,
+		MaximalPermissionPolicyAuditDecision, DecisionNoOpinion,
+		MaximalPermissionPolicyAuditReason, "request canceled",
+	)
+	require.Empty(t, ev.Annotations, "expected no annotations before the decision is final")
+
+	annotations.write(ctx)
+	require.Equal(t, map[string]string{
+		MaximalPermissionPolicyAuditDecision: DecisionNoOpinion,
+		MaximalPermissionPolicyAuditReason:   "request canceled",
+		MaximalPermissionPolicyAuditBinding:  "widgets",
+	}, ev.Annotations)
+}
+
+func TestMaximalPermissionPolicyAuthorizerFinalAuditDecision(t *testing.T) {
+	ctx, ev := withAuditEvent(withCluster("root:consumer"))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the request is canceled after the policy permitted it, before delegating
+	inner := authorizer.AuthorizerFunc(func(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+		cancel()
+		return authorizer.DecisionAllow, "", nil
+	})
+	delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		)},
+		[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+		inner, delegate,
+	)
+
+	dec, reason, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+		User:            &user.DefaultInfo{Name: "user-1"},
+		Verb:            "get",
+		APIGroup:        "widgets.example.io",
+		Resource:        "widgets",
+		ResourceRequest: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, authorizer.DecisionNoOpinion, dec)
+	require.Equal(t, ReasonRequestCanceled, reason)
+	require.Nil(t, delegate.recordedAttributes, "expected the delegate not to be called")
+
+	require.Equal(t, DecisionNoOpinion, ev.Annotations[MaximalPermissionPolicyAuditDecision], "expected the final decision, not the one of the RBAC evaluation")
+	require.Contains(t, ev.Annotations[MaximalPermissionPolicyAuditReason], "request canceled before delegating")
+	require.Equal(t, DecisionAllowed, ev.Annotations[MaximalPermissionPolicyAuditRBACDecision])
+	require.Equal(t, "delegate", ev.Annotations[MaximalPermissionPolicyAuditCanceled])
+}
+
+func TestMaximalPermissionPolicyAuthorizerRBACDenialAudit(t *testing.T) {
+	for _, tt := range []struct {
+		name           string
+		groups         []string
+		rbacDecision   authorizer.Decision
+		wantUser       string
+		wantGroupCount string
+	}{
+		{name: "user without groups", rbacDecision: authorizer.DecisionNoOpinion, wantUser: "apis.kcp.dev:binding:user-1", wantGroupCount: "0"},
+		{name: "user with groups", groups: []string{"team-a", "system:authenticated"}, rbacDecision: authorizer.DecisionNoOpinion, wantUser: "apis.kcp.dev:binding:user-1", wantGroupCount: "2"},
+		{name: "allowed", groups: []string{"team-a"}, rbacDecision: authorizer.DecisionAllow},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				&recordingAuthorizer{decision: tt.rbacDecision}, &recordingAuthorizer{decision: authorizer.DecisionAllow},
+			)
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			_, reason, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1", Groups: tt.groups},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantUser, ev.Annotations[MaximalPermissionPolicyAuditRBACUser])
+			require.Equal(t, tt.wantGroupCount, ev.Annotations[MaximalPermissionPolicyAuditRBACGroupCount])
+			if tt.wantUser != "" {
+				require.Equal(t, ReasonPolicyDenied, reason, "expected the details in the audit annotations only")
+			}
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerCandidatePolicy(t *testing.T) {
+	kubeInformers := newKubeInformers(t,
+		inCluster("root:provider", newClusterRole("widgets", rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}})),
+		inCluster("root:provider", newClusterRoleBinding("widgets", "widgets", rbacv1.Subject{Kind: rbacv1.UserKind, Name: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "user-1"})),
+		inCluster("root:provider", newClusterRoleBinding("candidate-widgets", "widgets", rbacv1.Subject{Kind: rbacv1.UserKind, Name: MaximalPermissionPolicyCandidateRBACUserGroupPrefix + "user-2"})),
+	)
+
+	for _, tt := range []struct {
+		name              string
+		candidate         *string
+		user              string
+		wantDecision      authorizer.Decision
+		wantCandidate     string
+		wantCandidateSeen bool
+	}{
+		{name: "no candidate", user: "user-1", wantDecision: authorizer.DecisionAllow},
+		{name: "candidate local policy would deny", candidate: pointer.String(`{"local":{}}`), user: "user-1", wantDecision: authorizer.DecisionAllow, wantCandidate: DecisionNoOpinion, wantCandidateSeen: true},
+		{name: "candidate local policy would allow", candidate: pointer.String(`{"local":{}}`), user: "user-2", wantDecision: authorizer.DecisionNoOpinion, wantCandidate: DecisionAllowed, wantCandidateSeen: true},
+		{name: "candidate verbs policy would allow", candidate: pointer.String(`{"verbs":[{"group":"widgets.example.io","resource":"widgets","verbs":["get"]}]}`), user: "user-1", wantDecision: authorizer.DecisionAllow, wantCandidate: DecisionAllowed, wantCandidateSeen: true},
+		{name: "invalid candidate", candidate: pointer.String(`{`), user: "user-1", wantDecision: authorizer.DecisionAllow, wantCandidate: "Error", wantCandidateSeen: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			export := newAPIExport("root:provider", "widgets", withLocalPolicy())
+			if tt.candidate != nil {
+				export.Annotations[MaximalPermissionPolicyCandidateAnnotationKey] = *tt.candidate
+			}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{export},
+				nil,
+				&recordingAuthorizer{decision: authorizer.DecisionAllow},
+			)
+			a.newAuthorizer = func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+				return rbac.New(newMergedRBACGetters(kubeInformers, clusterName, mergeClusters...))
+			}
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: tt.user},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec, "expected only the enforced policy to decide")
+
+			candidate, found := ev.Annotations[MaximalPermissionPolicyAuditCandidateDecision]
+			require.Equal(t, tt.wantCandidateSeen, found)
+			require.Equal(t, tt.wantCandidate, candidate)
+		})
+	}
+}
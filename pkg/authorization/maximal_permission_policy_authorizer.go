@@ -18,20 +18,39 @@ package authorization
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	kcpkubernetesinformers "github.com/kcp-dev/client-go/clients/informers"
 	"github.com/kcp-dev/logicalcluster/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
-	kaudit "k8s.io/apiserver/pkg/audit"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/apiserver/pkg/authorization/authorizer"
 	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/warning"
+	rbacv1listers "k8s.io/client-go/listers/rbac/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/kubernetes/pkg/genericcontrolplane"
 	"k8s.io/kubernetes/plugin/pkg/auth/authorizer/rbac"
 
 	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
 	kcpinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
 	"github.com/kcp-dev/kcp/pkg/indexers"
 	rbacwrapper "github.com/kcp-dev/kcp/pkg/virtual/framework/wrappers/rbac"
@@ -40,194 +59,1518 @@ import (
 const (
 	MaximalPermissionPolicyAccessNotPermittedReason = "access not permitted by maximal permission policy"
 
-	MaximalPermissionPolicyAuditPrefix   = "maxpermissionpolicy.authorization.kcp.dev/"
-	MaximalPermissionPolicyAuditDecision = MaximalPermissionPolicyAuditPrefix + "decision"
-	MaximalPermissionPolicyAuditReason   = MaximalPermissionPolicyAuditPrefix + "reason"
+	// MaximalPermissionPolicyCeilingExceededReasonCode is the reason of requests not permitted by the maximal permission
+	// policy of an API export, e.g. in the status of SubjectAccessReviews, to tell them apart from ordinary RBAC denials.
+	MaximalPermissionPolicyCeilingExceededReasonCode = "MaximalPermissionPolicyCeilingExceeded"
+
+	// MaximalPermissionPolicyGroupAliasesAnnotationKey is an experimental APIBinding annotation mapping API groups
+	// used by consumers to the canonical API groups of the bound resources, e.g. "alias.example.io=example.io".
+	// Multiple aliases are comma separated. The maximal permission policy is evaluated against the canonical group.
+	MaximalPermissionPolicyGroupAliasesAnnotationKey = "experimental.maxpermissionpolicy.authorization.kcp.dev/group-aliases"
+
+	// MaximalPermissionPolicyOldGroupsAnnotationKey is an experimental APIExport annotation recording the API groups
+	// the exported resources were migrated from, e.g. "old.example.io=example.io". Multiple migrations are comma separated.
+	// Requests for resources bound before the migration are evaluated against the maximal permission policy of the new group.
+	MaximalPermissionPolicyOldGroupsAnnotationKey = "experimental.maxpermissionpolicy.authorization.kcp.dev/old-groups"
+
+	// MaximalPermissionPolicyAdminClusterMergeAnnotationKey is an experimental APIExport annotation overriding whether
+	// the RBAC of the local admin cluster is merged when evaluating the maximal permission policy of the API export.
+	// Valid values are "true" and "false", other values are ignored. WithoutAdminClusterRBACMerge takes precedence.
+	MaximalPermissionPolicyAdminClusterMergeAnnotationKey = "experimental.maxpermissionpolicy.authorization.kcp.dev/admin-cluster-merge"
+
+	// MaximalPermissionPolicyCandidateAnnotationKey is an experimental APIExport annotation holding a JSON serialized
+	// candidate MaximalPermissionPolicy. The candidate is evaluated alongside the enforced policy, but only its would-be
+	// decision is recorded in the MaximalPermissionPolicyAuditCandidateDecision audit annotation. A candidate local policy
+	// is evaluated against RBAC with user and groups prefixed with MaximalPermissionPolicyCandidateRBACUserGroupPrefix.
+	MaximalPermissionPolicyCandidateAnnotationKey = "experimental.maxpermissionpolicy.authorization.kcp.dev/candidate-policy"
+
+	// MaximalPermissionPolicyCandidateRBACUserGroupPrefix is the prefix for the user and group names
+	// when verifying a candidate local policy.
+	MaximalPermissionPolicyCandidateRBACUserGroupPrefix = "apis.kcp.dev:candidate-binding:"
+)
+
+// Reason codes returned by the MaximalPermissionPolicyAuthorizer for the decisions it makes itself. They are stable for
+// tooling to switch on, while the human readable details are recorded in the MaximalPermissionPolicyAuditReason audit
+// annotation. Delegated requests return the reason of the delegate.
+const (
+	// ReasonPolicyDenied is the reason of requests not permitted by the maximal permission policy of an API export.
+	// They are denied if the RBAC of the API export cluster denies them explicitly, and have no opinion otherwise.
+	ReasonPolicyDenied = MaximalPermissionPolicyCeilingExceededReasonCode
+	// ReasonPolicyAllowed is the reason of requests allowed by the maximal permission policy, see WithTerminalDecision.
+	ReasonPolicyAllowed = "MaximalPermissionPolicyAllowed"
+	// ReasonExportNotFound is the reason of requests for resources bound to an API export that does not exist.
+	ReasonExportNotFound = "APIExportNotFound"
+	// ReasonExportLookupFailed is the reason of requests whose API export could not be looked up.
+	ReasonExportLookupFailed = "APIExportLookupFailed"
+	// ReasonBindingLookupFailed is the reason of requests whose API binding could not be looked up, including
+	// exceeding the limit of WithAPIBindingScanLimit.
+	ReasonBindingLookupFailed = "APIBindingLookupFailed"
+	// ReasonRBACEvaluationFailed is the reason of requests whose RBAC evaluation in the API export cluster failed
+	// or was not started, e.g. exceeding the limit of WithRBACConcurrencyLimit.
+	ReasonRBACEvaluationFailed = "RBACEvaluationFailed"
+	// ReasonIncompleteRequestInfo is the reason of requests rejected for lacking a resource or path.
+	ReasonIncompleteRequestInfo = "IncompleteRequestInfo"
+	// ReasonClusterUnknown is the reason of requests without logical cluster.
+	ReasonClusterUnknown = "ClusterUnknown"
+	// ReasonRequestCanceled is the reason of requests not authorized because their context is done.
+	ReasonRequestCanceled = "RequestCanceled"
+	// ReasonNoDelegate is the reason of requests not allowed because the authorizer has no delegate.
+	ReasonNoDelegate = "NoDelegate"
+	// ReasonCircuitBreakerOpen is the reason of requests not evaluated because of consecutive RBAC evaluation
+	// errors in the API export cluster, see WithCircuitBreaker.
+	ReasonCircuitBreakerOpen = "CircuitBreakerOpen"
+	// ReasonVerbNotAllowed is the reason of requests whose verb is not among the verbs of the bound resource.
+	ReasonVerbNotAllowed = "BoundResourceVerbNotAllowed"
+	// ReasonBindingPending is the reason of requests for unbound resources in clusters with API bindings whose
+	// bound resources are not known yet, see WithPendingAPIBindingsNoOpinion.
+	ReasonBindingPending = "APIBindingPending"
+	// ReasonPolicyEmpty is the reason of requests denied because the local maximal permission policy of the API export
+	// grants nothing on the requested resource, see WithStrictEmptyPolicy.
+	ReasonPolicyEmpty = "MaximalPermissionPolicyEmpty"
+)
+
+// Reason codes of requests the MaximalPermissionPolicyAuthorizer allows and delegates, recorded in the
+// MaximalPermissionPolicyAuditReasonCode audit annotation.
+const (
+	// ReasonExemptGroup is the reason of requests of users in a group bypassing the maximal permission policy.
+	ReasonExemptGroup = "ExemptGroup"
+	// ReasonExemptNamespace is the reason of requests to a namespace bypassing the maximal permission policy.
+	ReasonExemptNamespace = "ExemptNamespace"
+	// ReasonNonResourceRequest is the reason of requests to non-resource URLs, which are never bound.
+	ReasonNonResourceRequest = "NonResourceRequest"
+	// ReasonNotBound is the reason of requests for resources not bound by an API binding.
+	ReasonNotBound = "APIBindingNotBound"
+	// ReasonMaintenanceExempt is the reason of requests for resources of an API export exempt for maintenance.
+	ReasonMaintenanceExempt = "APIExportMaintenanceExempt"
+	// ReasonPolicyNotPresent is the reason of requests for resources of an API export without maximal permission policy
+	// applicable to them.
+	ReasonPolicyNotPresent = "MaximalPermissionPolicyNotPresent"
+	// ReasonVerbNotEvaluated is the reason of requests whose verb the policy is not evaluated for, see WithVerbFilter.
+	ReasonVerbNotEvaluated = "VerbNotEvaluated"
+)
+
+// reasonCodes are the reason codes returned by the MaximalPermissionPolicyAuthorizer.
+var reasonCodes = sets.NewString(
+	ReasonPolicyDenied,
+	ReasonPolicyAllowed,
+	ReasonExportNotFound,
+	ReasonExportLookupFailed,
+	ReasonBindingLookupFailed,
+	ReasonRBACEvaluationFailed,
+	ReasonIncompleteRequestInfo,
+	ReasonClusterUnknown,
+	ReasonRequestCanceled,
+	ReasonNoDelegate,
+	ReasonCircuitBreakerOpen,
+	ReasonVerbNotAllowed,
+	ReasonBindingPending,
+	ReasonPolicyEmpty,
 )
 
+// maximalPermissionPolicyKeyType is the type of the context keys of this package. It is unexported
+// so that the keys cannot collide with those of other packages.
+type maximalPermissionPolicyKeyType int
+
+const (
+	withoutAdminClusterRBACMergeKey maximalPermissionPolicyKeyType = iota
+	authorizeBatchKey
+	forceFreshKey
+	auditAnnotationsKey
+	withoutDelegationKey
+)
+
+// WithoutAdminClusterRBACMerge returns a context for which the maximal permission policy is evaluated
+// without merging the RBAC of the local admin cluster. It is meant for tests and special system paths.
+func WithoutAdminClusterRBACMerge(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutAdminClusterRBACMergeKey, true)
+}
+
+// isWithoutAdminClusterRBACMerge returns whether the context disables the admin cluster RBAC merge.
+func isWithoutAdminClusterRBACMerge(ctx context.Context) bool {
+	without, _ := ctx.Value(withoutAdminClusterRBACMergeKey).(bool)
+	return without
+}
+
+// boundResourceWildcard is the resource of a bound resource binding all resources of its group.
+const boundResourceWildcard = "*"
+
+// WithForceFresh returns a context for which API exports are read from the informer indexer, bypassing the
+// API exports memoized by AuthorizeBatch and the cache of WithCrossShardExportResolver. It is meant for tests and
+// for admission flows that just created an API export.
+func WithForceFresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceFreshKey, true)
+}
+
+// isForceFresh returns whether the context bypasses the API export caches.
+func isForceFresh(ctx context.Context) bool {
+	forceFresh, _ := ctx.Value(forceFreshKey).(bool)
+	return forceFresh
+}
+
+// writeVerbs are the verbs for which denials are surfaced as warnings if enabled with WithDenialWarnings.
+var writeVerbs = sets.NewString("create", "update", "patch", "delete", "deletecollection")
+
 // NewMaximalPermissionPolicyAuthorizer returns an authorizer that first checks if the request is for a
 // bound resource or not. If the resource is bound it checks the maximal permission policy of the underlying API export.
-func NewMaximalPermissionPolicyAuthorizer(kubeInformers kcpkubernetesinformers.SharedInformerFactory, kcpInformers kcpinformers.SharedInformerFactory, delegate authorizer.Authorizer) (authorizer.Authorizer, error) {
+// The delegate may be nil, in which case requests are only allowed by policies with WithTerminalDecision.
+// It returns an error if any of the options is invalid.
+func NewMaximalPermissionPolicyAuthorizer(kubeInformers kcpkubernetesinformers.SharedInformerFactory, kcpInformers kcpinformers.SharedInformerFactory, delegate authorizer.Authorizer, opts ...MaximalPermissionPolicyAuthorizerOption) (authorizer.Authorizer, error) {
 	apiBindingIndexer := kcpInformers.Apis().V1alpha1().APIBindings().Informer().GetIndexer()
 	apiExportIndexer := kcpInformers.Apis().V1alpha1().APIExports().Informer().GetIndexer()
 
+	indexers.AddIfNotPresentOrDie(apiExportIndexer, cache.Indexers{
+		indexers.APIExportByMaximalPermissionPolicy: indexers.IndexAPIExportByMaximalPermissionPolicy,
+		indexers.APIExportByClusterAndName:          indexers.IndexAPIExportByClusterAndName,
+		indexers.ByLogicalCluster:                   indexers.IndexByLogicalCluster,
+	})
+	indexers.AddIfNotPresentOrDie(apiBindingIndexer, cache.Indexers{
+		indexers.APIBindingByClusterAndBoundGroupResource: indexers.IndexAPIBindingByClusterAndBoundGroupResource(MaximalPermissionPolicyGroupAliasesAnnotationKey),
+		indexers.ByLogicalCluster:                         indexers.IndexByLogicalCluster,
+	})
+
 	// Make sure informer knows what to watch
 	kubeInformers.Rbac().V1().Roles().Lister()
 	kubeInformers.Rbac().V1().RoleBindings().Lister()
 	kubeInformers.Rbac().V1().ClusterRoles().Lister()
 	kubeInformers.Rbac().V1().ClusterRoleBindings().Lister()
 
-	return &MaximalPermissionPolicyAuthorizer{
-		getAPIBindingReferenceForAttributes: func(attr authorizer.Attributes, clusterName logicalcluster.Name) (*apisv1alpha1.ExportReference, bool, error) {
-			return getAPIBindingReferenceForAttributes(apiBindingIndexer, attr, clusterName)
+	a := &MaximalPermissionPolicyAuthorizer{
+		getAPIExportByReference: func(exportRef *apisv1alpha1.ExportReference, exportClusterName logicalcluster.Name) (*apisv1alpha1.APIExport, bool, error) {
+			return getAPIExportByReference(apiExportIndexer, exportRef, exportClusterName)
 		},
-		getAPIExportByReference: func(exportRef *apisv1alpha1.ExportReference) (*apisv1alpha1.APIExport, bool, error) {
-			return getAPIExportByReference(apiExportIndexer, exportRef)
+		listAPIBindings: func() ([]*apisv1alpha1.APIBinding, error) {
+			return kcpInformers.Apis().V1alpha1().APIBindings().Lister().List(labels.Everything())
 		},
-		newAuthorizer: func(clusterName logicalcluster.Name) authorizer.Authorizer {
-			return rbac.New(
-				&rbac.RoleGetter{Lister: rbacwrapper.NewMergedRoleLister(
-					kubeInformers.Rbac().V1().Roles().Lister().Cluster(clusterName),
-					kubeInformers.Rbac().V1().Roles().Lister().Cluster(genericcontrolplane.LocalAdminCluster),
-				)},
-				&rbac.RoleBindingLister{Lister: kubeInformers.Rbac().V1().RoleBindings().Lister().Cluster(clusterName)},
-				&rbac.ClusterRoleGetter{Lister: rbacwrapper.NewMergedClusterRoleLister(
-					kubeInformers.Rbac().V1().ClusterRoles().Lister().Cluster(clusterName),
-					kubeInformers.Rbac().V1().ClusterRoles().Lister().Cluster(genericcontrolplane.LocalAdminCluster),
-				)},
-				&rbac.ClusterRoleBindingLister{Lister: rbacwrapper.NewMergedClusterRoleBindingLister(
-					kubeInformers.Rbac().V1().ClusterRoleBindings().Lister().Cluster(clusterName),
-					kubeInformers.Rbac().V1().ClusterRoleBindings().Lister().Cluster(genericcontrolplane.LocalAdminCluster),
-				)},
-			)
+		listPendingAPIBindings: func(clusterName logicalcluster.Name) ([]*apisv1alpha1.APIBinding, error) {
+			return getPendingAPIBindings(apiBindingIndexer, clusterName)
+		},
+		prefixedBindingsGrantResource: func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name, prefix, group, resource, subresource string) (bool, error) {
+			return prefixedBindingsGrantResource(kubeInformers, clusterName, mergeClusters, prefix, group, resource, subresource)
+		},
+		listAPIExportsWithPolicy: func() ([]*apisv1alpha1.APIExport, error) {
+			return indexers.ByIndex[*apisv1alpha1.APIExport](apiExportIndexer, indexers.APIExportByMaximalPermissionPolicy, "true")
+		},
+		listClusterRoleBindings: func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) ([]*rbacv1.ClusterRoleBinding, error) {
+			_, _, _, clusterRoleBindingLister := newMergedRBACGetters(kubeInformers, clusterName, mergeClusters...)
+			return clusterRoleBindingLister.ListClusterRoleBindings()
+		},
+		hasSynced: []cache.InformerSynced{
+			kcpInformers.Apis().V1alpha1().APIBindings().Informer().HasSynced,
+			kcpInformers.Apis().V1alpha1().APIExports().Informer().HasSynced,
+			kubeInformers.Rbac().V1().Roles().Informer().HasSynced,
+			kubeInformers.Rbac().V1().RoleBindings().Informer().HasSynced,
+			kubeInformers.Rbac().V1().ClusterRoles().Informer().HasSynced,
+			kubeInformers.Rbac().V1().ClusterRoleBindings().Informer().HasSynced,
 		},
-		delegate: delegate,
-	}, nil
+		exemptGroups:                  sets.NewString(user.SystemPrivilegedGroup),
+		incompleteRequestInfoDecision: authorizer.DecisionNoOpinion,
+		delegate:                      delegate,
+	}
+	rbacAuthorizers := newRBACAuthorizerCache(func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+		if a.clusterAuthorizerFactory != nil {
+			return a.clusterAuthorizerFactory(clusterName)
+		}
+		return newRBACAuthorizer(kubeInformers, clusterName, mergeClusters, a.explicitRBACVerbs)
+	})
+	a.newAuthorizer = rbacAuthorizers.get
+	a.closers = append(a.closers, rbacAuthorizers.clear)
+	a.bindingMatcher = BindingMatcherFunc(func(attr authorizer.Attributes, clusterName logicalcluster.Name) (*APIBindingMatch, bool, error) {
+		return getAPIBindingReferenceForAttributes(apiBindingIndexer, attr, clusterName, a.apiBindingScanLimit)
+	})
+	a.matchAllAPIBindings = func(attr authorizer.Attributes, clusterName logicalcluster.Name) ([]*APIBindingMatch, error) {
+		return getAPIBindingMatchesForAttributes(apiBindingIndexer, attr, clusterName, a.apiBindingScanLimit)
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if err := utilerrors.NewAggregate(a.optionErrs); err != nil {
+		return nil, fmt.Errorf("invalid maximal permission policy authorizer options: %w", err)
+	}
+	if a.strictEmptyPolicy && a.identityRewriter == nil {
+		addPrefixedBindingsIndexers(kubeInformers, a.rbacUserGroupPrefix())
+	}
+
+	return a, nil
+}
+
+// HasSynced returns whether the APIBinding, APIExport and RBAC informers the authorizer was constructed with
+// have synced, e.g. for a readiness check. Until then, requests may be decided on incomplete data.
+func (a *MaximalPermissionPolicyAuthorizer) HasSynced() bool {
+	for _, hasSynced := range a.hasSynced {
+		if !hasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// Close releases the resources of the authorizer, i.e. it clears the cached RBAC authorizers, RBAC decisions,
+// cross-shard API exports and denials, and closes the circuit breakers. The authorizer does not run goroutines
+// of its own. Close is idempotent and always returns nil. The authorizer stays usable, starting over with empty caches.
+func (a *MaximalPermissionPolicyAuthorizer) Close() error {
+	a.closeOnce.Do(func() {
+		for _, closer := range a.closers {
+			closer()
+		}
+		if a.decisionCache != nil {
+			a.decisionCache.clear()
+		}
+		if a.crossShardExportResolver != nil {
+			a.crossShardExportResolver.clear()
+		}
+		if a.denialDeduplicator != nil {
+			a.denialDeduplicator.clear()
+		}
+		if a.circuitBreakers != nil {
+			a.circuitBreakers.reset()
+		}
+	})
+	return nil
+}
+
+// newRBACAuthorizer returns an RBAC authorizer for the given cluster, merging in the RBAC of the merge clusters.
+// With explicitVerbs, "*" verbs of (Cluster)Roles are ignored.
+func newRBACAuthorizer(kubeInformers kcpkubernetesinformers.SharedInformerFactory, clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name, explicitVerbs bool) authorizer.Authorizer {
+	roleGetter, roleBindingLister, clusterRoleGetter, clusterRoleBindingLister := newMergedRBACGetters(kubeInformers, clusterName, mergeClusters...)
+	if !explicitVerbs {
+		return rbac.New(roleGetter, roleBindingLister, clusterRoleGetter, clusterRoleBindingLister)
+	}
+	return rbac.New(&explicitVerbsRoleGetter{delegate: roleGetter}, roleBindingLister, &explicitVerbsClusterRoleGetter{delegate: clusterRoleGetter}, clusterRoleBindingLister)
+}
+
+// newMergedRBACGetters returns the RBAC getters and listers of the given cluster, merged with those of the merge clusters.
+// The aggregation rules of the merged ClusterRoles are resolved.
+func newMergedRBACGetters(kubeInformers kcpkubernetesinformers.SharedInformerFactory, clusterName logicalcluster.Name, mergeClusters ...logicalcluster.Name) (*rbac.RoleGetter, *rbac.RoleBindingLister, *rbac.ClusterRoleGetter, *rbac.ClusterRoleBindingLister) {
+	roleListers := []rbacv1listers.RoleLister{kubeInformers.Rbac().V1().Roles().Lister().Cluster(clusterName)}
+	clusterRoleListers := []rbacv1listers.ClusterRoleLister{kubeInformers.Rbac().V1().ClusterRoles().Lister().Cluster(clusterName)}
+	clusterRoleBindingListers := []rbacv1listers.ClusterRoleBindingLister{kubeInformers.Rbac().V1().ClusterRoleBindings().Lister().Cluster(clusterName)}
+	for _, mergeCluster := range mergeClusters {
+		roleListers = append(roleListers, kubeInformers.Rbac().V1().Roles().Lister().Cluster(mergeCluster))
+		clusterRoleListers = append(clusterRoleListers, kubeInformers.Rbac().V1().ClusterRoles().Lister().Cluster(mergeCluster))
+		clusterRoleBindingListers = append(clusterRoleBindingListers, kubeInformers.Rbac().V1().ClusterRoleBindings().Lister().Cluster(mergeCluster))
+	}
+
+	return &rbac.RoleGetter{Lister: rbacwrapper.NewMergedRoleLister(roleListers...)},
+		&rbac.RoleBindingLister{Lister: kubeInformers.Rbac().V1().RoleBindings().Lister().Cluster(clusterName)},
+		&rbac.ClusterRoleGetter{Lister: rbacwrapper.NewAggregatingClusterRoleLister(rbacwrapper.NewMergedClusterRoleLister(clusterRoleListers...))},
+		&rbac.ClusterRoleBindingLister{Lister: rbacwrapper.NewMergedClusterRoleBindingLister(clusterRoleBindingListers...)}
 }
 
 type MaximalPermissionPolicyAuthorizer struct {
 	delegate authorizer.Authorizer
 
-	getAPIBindingReferenceForAttributes func(attr authorizer.Attributes, clusterName logicalcluster.Name) (ref *apisv1alpha1.ExportReference, found bool, err error)
-	getAPIExportByReference             func(exportRef *apisv1alpha1.ExportReference) (ref *apisv1alpha1.APIExport, found bool, err error)
-	newAuthorizer                       func(clusterName logicalcluster.Name) authorizer.Authorizer
+	// optionErrs are the errors of invalid options, failing the constructor.
+	optionErrs []error
+
+	bindingMatcher          BindingMatcher
+	getAPIExportByReference func(exportRef *apisv1alpha1.ExportReference, exportClusterName logicalcluster.Name) (ref *apisv1alpha1.APIExport, found bool, err error)
+	// listAPIBindings returns all API bindings.
+	listAPIBindings func() ([]*apisv1alpha1.APIBinding, error)
+	// listPendingAPIBindings returns the API bindings of the given cluster without bound resources yet.
+	listPendingAPIBindings func(clusterName logicalcluster.Name) ([]*apisv1alpha1.APIBinding, error)
+	// prefixedBindingsGrantResource returns whether any (Cluster)RoleBinding of the RBAC of the given cluster, merged with
+	// the merge clusters, binds a user or group bearing the prefix to a rule for the resource of the group.
+	prefixedBindingsGrantResource func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name, prefix, group, resource, subresource string) (bool, error)
+	// listAPIExportsWithPolicy returns the API exports with a maximal permission policy.
+	listAPIExportsWithPolicy func() ([]*apisv1alpha1.APIExport, error)
+	// listClusterRoleBindings returns the ClusterRoleBindings of the given cluster, merged with those of the merge clusters.
+	listClusterRoleBindings func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) ([]*rbacv1.ClusterRoleBinding, error)
+	// newAuthorizer returns an RBAC authorizer for the given cluster, merging in the RBAC of the merge clusters.
+	newAuthorizer func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer
+
+	// denialWarnings enables warnings for write requests denied by the maximal permission policy.
+	denialWarnings bool
+
+	// apiBindingScanLimit is the maximum number of API bindings scanned per request, zero meaning unlimited.
+	apiBindingScanLimit            int
+	apiBindingScanOverflowDecision authorizer.Decision
+
+	// consumerParentRBAC enables merging the RBAC of the parent of the requesting cluster.
+	consumerParentRBAC bool
+
+	// closers release resources registered by the constructor on Close, called once by closeOnce.
+	closers   []func()
+	closeOnce sync.Once
+
+	// tracer records spans of the authorization steps if set by WithTracerProvider.
+	tracer trace.Tracer
+
+	// fallbackClusters are the clusters merged instead of the local admin cluster if set.
+	fallbackClusters []logicalcluster.Name
+
+	// denialRecorder is called with the requests for bound resources not allowed, if set.
+	denialRecorder func(DeniedRequest)
+
+	// strictEmptyPolicy enables denying requests if the local policy grants nothing on the requested resource.
+	strictEmptyPolicy bool
+
+	// verbFilter returns whether the policy is evaluated for requests of a verb, if set.
+	verbFilter func(verb string) bool
+
+	// multiExportPolicy determines which API exports constrain resources bound by multiple API bindings.
+	multiExportPolicy MultiExportPolicy
+
+	// pendingAPIBindingsNoOpinion enables having no opinion on unbound resources of clusters with pending API bindings.
+	pendingAPIBindingsNoOpinion bool
+
+	// inheritanceLookup returns the parent of a cluster whose API bindings are inherited, if set.
+	inheritanceLookup func(clusterName logicalcluster.Name) logicalcluster.Name
+	// virtualResourceMatcher returns the API export serving a requested resource not bound by API bindings, if set.
+	virtualResourceMatcher func(attr authorizer.Attributes) (*apisv1alpha1.ExportReference, bool)
+
+	// incompleteRequestInfoDecision is returned for requests with incomplete request info.
+	incompleteRequestInfoDecision authorizer.Decision
+
+	// maintenanceExemptExportsLock guards maintenanceExemptExports, the API exports exempt from
+	// enforcement during maintenance windows, see SetMaintenanceExemptExports.
+	maintenanceExemptExportsLock sync.RWMutex
+	maintenanceExemptExports     map[apisv1alpha1.WorkspaceExportReference]bool
+
+	// crossShardExportResolver resolves API exports not found locally, if set.
+	crossShardExportResolver *cachingCrossShardExportResolver
+
+	// explicitRBACVerbs enables ignoring "*" verbs of RBAC rules.
+	explicitRBACVerbs bool
+
+	// rbacRetryAttempts is the number of attempts of the RBAC evaluation failing with transient errors, zero or one meaning no retry.
+	rbacRetryAttempts int
+	rbacRetryBackoff  time.Duration
+
+	// withoutAdminClusterRBACMergeByDefault disables merging the RBAC of the local admin cluster for API exports without override.
+	withoutAdminClusterRBACMergeByDefault bool
+
+	// exemptGroups are the groups whose requests are delegated without evaluating the policy.
+	exemptGroups sets.String
+	// exemptNamespaces are the namespaces whose requests are delegated without evaluating the policy.
+	exemptNamespaces sets.String
+
+	// decisionLogs enables logging every decision.
+	decisionLogs bool
+
+	// metrics enables the metrics, see WithMetrics.
+	metrics bool
+
+	// userGroupPrefix overrides the prefix of the user and group names of a local policy, if set.
+	userGroupPrefix string
+	// userNameNormalizer normalizes the user and group names before they are prefixed, if set.
+	userNameNormalizer func(name string) string
+	// identityRewriter replaces prefixing the user and group names for local policies, if set.
+	identityRewriter IdentityRewriter
+
+	// collectionGetAsList enables evaluating a get on a collection as list.
+	collectionGetAsList bool
+
+	// denialDeduplicator suppresses the warnings of duplicate denials, if set.
+	denialDeduplicator *denialDeduplicator
+
+	// decisionCache caches RBAC decisions, if set.
+	decisionCache *decisionCache
+
+	// rbacSemaphores limits the concurrent RBAC evaluations per API export cluster, if set.
+	rbacSemaphores *clusterSemaphores
+
+	// circuitBreakers stop evaluating the RBAC of API export clusters with consecutive errors, if set.
+	circuitBreakers *circuitBreakers
+
+	// terminalDecision enables allowing requests permitted by the policy instead of delegating them.
+	terminalDecision bool
+
+	// withoutEnforcement disables enforcing the decisions, delegating every request.
+	withoutEnforcement bool
+
+	// failurePolicy determines the decision of requests whose evaluation fails, FailOpen if empty.
+	failurePolicy FailurePolicy
+
+	// warningHandler is called with a warning for deprecated API exports, if set.
+	warningHandler func(ctx context.Context, message string)
+
+	// hasSynced are the sync checks of the informers the authorizer was constructed with.
+	hasSynced []cache.InformerSynced
+
+	// customBindingMatcher is set if the binding matcher was replaced with WithBindingMatcher.
+	customBindingMatcher bool
+	// matchAllAPIBindings returns a match for each API binding binding the requested resource, unlike the binding matcher.
+	matchAllAPIBindings func(attr authorizer.Attributes, clusterName logicalcluster.Name) ([]*APIBindingMatch, error)
+
+	// clusterAuthorizerFactory replaces the RBAC authorizers of the API export clusters, if set.
+	clusterAuthorizerFactory func(clusterName logicalcluster.Name) authorizer.Authorizer
+}
+
+// ErrUnsupportedExportReference is returned when resolving an export reference of a kind other than Workspace,
+// e.g. of a future API version. Requests for resources bound through such a reference are not permitted.
+var ErrUnsupportedExportReference = errors.New("unsupported export reference: Workspace is nil")
+
+// ExportResolutionError is returned by the MaximalPermissionPolicyAuthorizer if the API export of the API binding
+// binding the requested resource cannot be looked up. It implements apierrors.APIStatus, i.e. it is rendered as
+// internal error naming the API export rather than as a bare error.
+type ExportResolutionError struct {
+	// Path is the path of the workspace of the API export.
+	Path string
+	// ExportName is the name of the API export.
+	ExportName string
+	// Err is the error looking up the API export.
+	Err error
+}
+
+var _ apierrors.APIStatus = &ExportResolutionError{}
+
+func (e *ExportResolutionError) Error() string {
+	return fmt.Sprintf("error resolving API export %q, path: %q: %v", e.ExportName, e.Path, e.Err)
+}
+
+func (e *ExportResolutionError) Unwrap() error {
+	return e.Err
+}
+
+// Status returns the status of an internal error with the API export as details.
+func (e *ExportResolutionError) Status() metav1.Status {
+	status := apierrors.NewInternalError(e).Status()
+	status.Details.Group = apisv1alpha1.SchemeGroupVersion.Group
+	status.Details.Kind = "apiexports"
+	status.Details.Name = e.ExportName
+	return status
+}
+
+// errAPIBindingScanLimitExceeded is returned when more API bindings than the scan limit
+// have been scanned without finding one binding the requested resource.
+var errAPIBindingScanLimitExceeded = errors.New("API binding scan limit exceeded")
+
+// APIBindingMatch is the APIBinding reference matching the requested resource.
+type APIBindingMatch struct {
+	ExportReference *apisv1alpha1.ExportReference
+	// ExportClusterName is the resolved logical cluster of the referenced API export, if known.
+	// The API export is then looked up by it rather than by the path of the reference.
+	ExportClusterName logicalcluster.Name
+
+	// APIBindingName is the name of the matched API binding, if any.
+	APIBindingName string
+	// MatchingAPIBindingNames are the names of all API bindings binding the requested resource, sorted by name.
+	// The first one is matched. More than one is a misconfiguration, e.g. during a migration.
+	MatchingAPIBindingNames []string
+	// BoundResource is the bound resource entry of the API binding status matching the requested resource, if any.
+	BoundResource *apisv1alpha1.BoundAPIResource
+
+	// Group is the canonical API group of the bound resource. It differs from the requested
+	// API group if the request used a group alias of the APIBinding. If empty, the requested API group is used.
+	Group string
+}
+
+// BindingMatcher finds the API binding binding the requested resource in the given cluster.
+type BindingMatcher interface {
+	MatchAPIBinding(attr authorizer.Attributes, clusterName logicalcluster.Name) (match *APIBindingMatch, found bool, err error)
+}
+
+// BindingMatcherFunc is a function implementing BindingMatcher.
+type BindingMatcherFunc func(attr authorizer.Attributes, clusterName logicalcluster.Name) (match *APIBindingMatch, found bool, err error)
+
+func (f BindingMatcherFunc) MatchAPIBinding(attr authorizer.Attributes, clusterName logicalcluster.Name) (*APIBindingMatch, bool, error) {
+	return f(attr, clusterName)
+}
+
+// MaximalPermissionPolicyDecisionDetails explains a decision of the MaximalPermissionPolicyAuthorizer.
+type MaximalPermissionPolicyDecisionDetails struct {
+	// Bound is true if the requested resource is bound by an API binding. The following fields explain why.
+	Bound bool
+
+	// APIBindingName is the name of the API binding binding the requested resource.
+	APIBindingName string
+	// BoundResource is the bound resource entry of the API binding status matching the requested resource.
+	BoundResource *apisv1alpha1.BoundAPIResource
+	// ExportReference is the resolved reference to the API export of the API binding.
+	ExportReference *apisv1alpha1.ExportReference
+
+	// PolicyApplicable is true if the maximal permission policy of the API export was enforced, including failing
+	// closed because the API export was not found. It is false if the decision was delegated without enforcement,
+	// e.g. because the resource is not bound or the API export has no maximal permission policy.
+	PolicyApplicable bool
+
+	// Delegated is true if the decision was made by the delegate.
+	Delegated bool
+
+	// trace records the evaluation of the API export for Explain, if set.
+	trace *DecisionTrace
+	// dryRun is set by Explain to evaluate the request without side effects on metrics, warnings, the
+	// denial deduplication and the decision cache.
+	dryRun bool
 }
 
 func (a *MaximalPermissionPolicyAuthorizer) Authorize(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+	return a.authorizeAndRecord(ctx, attr, &MaximalPermissionPolicyDecisionDetails{})
+}
+
+// AuthorizeWithDetails authorizes like Authorize, and additionally returns details explaining the decision.
+func (a *MaximalPermissionPolicyAuthorizer) AuthorizeWithDetails(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, *MaximalPermissionPolicyDecisionDetails, error) {
+	details := &MaximalPermissionPolicyDecisionDetails{}
+	dec, reason, err := a.authorizeAndRecord(ctx, attr, details)
+	return dec, reason, details, err
+}
+
+// authorizeAndRecord authorizes the request, logs and counts the decision, and enforces it.
+func (a *MaximalPermissionPolicyAuthorizer) authorizeAndRecord(ctx context.Context, attr authorizer.Attributes, details *MaximalPermissionPolicyDecisionDetails) (authorizer.Decision, string, error) {
+	ctx, annotations := withAuditAnnotations(ctx)
+	defer annotations.write(ctx)
+
+	dec, reason, err := a.authorize(ctx, attr, details)
+	a.recordDecision(ctx, attr, details, dec, reason, err)
+	return a.enforce(ctx, attr, details, dec, reason, err)
+}
+
+// recordDecision logs, counts and records the decision.
+func (a *MaximalPermissionPolicyAuthorizer) recordDecision(ctx context.Context, attr authorizer.Attributes, details *MaximalPermissionPolicyDecisionDetails, dec authorizer.Decision, reason string, err error) {
+	a.logDecision(ctx, attr, details, dec, reason, err)
+	if dec == authorizer.DecisionNoOpinion {
+		logNoOpinion(ctx, attr, details, reason)
+	}
+	a.countDecision(details, dec)
+	a.recordDeniedRequest(ctx, attr, details, dec, reason)
+}
+
+// countDecision counts the decision if enabled with WithMetrics.
+func (a *MaximalPermissionPolicyAuthorizer) countDecision(details *MaximalPermissionPolicyDecisionDetails, dec authorizer.Decision) {
+	if !a.metrics {
+		return
+	}
+	var exportName string
+	if details.ExportReference != nil && details.ExportReference.Workspace != nil {
+		exportName = details.ExportReference.Workspace.ExportName
+	}
+	decisions.WithLabelValues(decisionLabel(dec), strconv.FormatBool(details.Bound), exportName).Inc()
+}
+
+func (a *MaximalPermissionPolicyAuthorizer) authorize(ctx context.Context, attr authorizer.Attributes, details *MaximalPermissionPolicyDecisionDetails) (authorizer.Decision, string, error) {
 	// get the cluster from the ctx.
 	lcluster, err := genericapirequest.ClusterNameFrom(ctx)
 	if err != nil {
-		kaudit.AddAuditAnnotations(
+		addAuditAnnotations(
 			ctx,
 			MaximalPermissionPolicyAuditDecision, DecisionNoOpinion,
 			MaximalPermissionPolicyAuditReason, fmt.Sprintf("error getting cluster from request: %v", err),
 		)
-		return authorizer.DecisionNoOpinion, MaximalPermissionPolicyAccessNotPermittedReason, err
+		return authorizer.DecisionNoOpinion, ReasonClusterUnknown, err
 	}
 
-	bindingLogicalCluster, bound, err := a.getAPIBindingReferenceForAttributes(attr, lcluster)
-	if err != nil {
-		kaudit.AddAuditAnnotations(
+	if group, exempt := a.exemptGroup(attr.GetUser()); exempt {
+		if group == user.SystemPrivilegedGroup {
+			addAuditAnnotations(ctx, MaximalPermissionPolicyAuditSystemMastersBypass, "true")
+		}
+		return a.allow(ctx, attr, details, ReasonExemptGroup, fmt.Sprintf("%s group bypasses maximal permission policy", group),
+			MaximalPermissionPolicyAuditExemptGroup, group,
+		)
+	}
+
+	if namespace := attr.GetNamespace(); namespace != "" && a.exemptNamespaces.Has(namespace) {
+		return a.allow(ctx, attr, details, ReasonExemptNamespace, fmt.Sprintf("%s namespace bypasses maximal permission policy", namespace),
+			MaximalPermissionPolicyAuditExemptNamespace, namespace,
+		)
+	}
+
+	// A subresource without resource cannot be matched against bound resources. Fail closed.
+	if attr.IsResourceRequest() && attr.GetResource() == "" && attr.GetSubresource() != "" {
+		addAuditAnnotations(
 			ctx,
 			MaximalPermissionPolicyAuditDecision, DecisionNoOpinion,
-			MaximalPermissionPolicyAuditReason, fmt.Sprintf("error getting API binding reference: %v", err),
+			MaximalPermissionPolicyAuditReason, fmt.Sprintf("subresource %q without resource", attr.GetSubresource()),
 		)
-		return authorizer.DecisionNoOpinion, MaximalPermissionPolicyAccessNotPermittedReason, err
+		return authorizer.DecisionNoOpinion, ReasonIncompleteRequestInfo, nil
 	}
 
-	if !bound {
-		kaudit.AddAuditAnnotations(
+	if isIncompleteRequestInfo(attr) {
+		addAuditAnnotations(
 			ctx,
-			MaximalPermissionPolicyAuditDecision, DecisionAllowed,
-			MaximalPermissionPolicyAuditReason, "no API binding bound",
+			MaximalPermissionPolicyAuditDecision, DecisionString(a.incompleteRequestInfoDecision),
+			MaximalPermissionPolicyAuditReason, "incomplete request info",
 		)
-		return a.delegate.Authorize(ctx, attr)
+		return a.incompleteRequestInfoDecision, ReasonIncompleteRequestInfo, nil
+	}
+
+	// non-resource URLs are never bound by API bindings
+	if !attr.IsResourceRequest() {
+		return a.allow(ctx, attr, details, ReasonNonResourceRequest, fmt.Sprintf("non-resource request of path %q", attr.GetPath()))
 	}
 
-	apiExport, found, err := a.getAPIExportByReference(bindingLogicalCluster)
+	if a.verbFilter != nil && !a.verbFilter(attr.GetVerb()) {
+		return a.allow(ctx, attr, details, ReasonVerbNotEvaluated, fmt.Sprintf("maximal permission policy not evaluated for verb %q", attr.GetVerb()))
+	}
+
+	// the cluster of the API bindings binding the requested resource
+	matchCluster := lcluster
+	spanCtx, span := a.startSpan(ctx, spanMatchAPIBinding, attribute.String(spanAttributeCluster, lcluster.String()))
+	bindingMatch, bound, err := a.matchAPIBinding(spanCtx, attr, lcluster)
+	if err == nil && !bound && a.inheritanceLookup != nil {
+		var inheritedFrom logicalcluster.Name
+		bindingMatch, bound, inheritedFrom, err = a.matchInheritedAPIBinding(spanCtx, attr, lcluster)
+		if bound {
+			matchCluster = inheritedFrom
+			addAuditAnnotations(ctx, MaximalPermissionPolicyAuditInheritedFrom, inheritedFrom.String())
+		}
+	}
+	if err == nil && !bound && a.virtualResourceMatcher != nil {
+		if exportRef, ok := a.virtualResourceMatcher(attr); ok && exportRef != nil {
+			bindingMatch, bound = &APIBindingMatch{ExportReference: exportRef}, true
+			addAuditAnnotations(ctx, MaximalPermissionPolicyAuditVirtualResource, "true")
+		}
+	}
+	span.End()
+	if errors.Is(err, errAPIBindingScanLimitExceeded) {
+		if a.metrics && !details.dryRun {
+			apiBindingScanOverflows.Inc()
+		}
+		addAuditAnnotations(
+			ctx,
+			MaximalPermissionPolicyAuditDecision, DecisionString(a.apiBindingScanOverflowDecision),
+			MaximalPermissionPolicyAuditReason, err.Error(),
+		)
+		return a.apiBindingScanOverflowDecision, ReasonBindingLookupFailed, nil
+	}
 	if err != nil {
-		kaudit.AddAuditAnnotations(
+		dec := a.failureDecision()
+		addAuditAnnotations(
 			ctx,
-			MaximalPermissionPolicyAuditDecision, DecisionNoOpinion,
-			MaximalPermissionPolicyAuditReason, fmt.Sprintf("error getting API export: %v", err),
+			MaximalPermissionPolicyAuditDecision, DecisionString(dec),
+			MaximalPermissionPolicyAuditReason, fmt.Sprintf("error getting API binding reference: %v", err),
 		)
-		return authorizer.DecisionNoOpinion, MaximalPermissionPolicyAccessNotPermittedReason, err
+		return dec, ReasonBindingLookupFailed, err
+	}
+
+	if !bound && a.pendingAPIBindingsNoOpinion {
+		pending, err := a.pendingAPIBindingsServing(ctx, attr, lcluster)
+		if err != nil {
+			dec := a.failureDecision()
+			addAuditAnnotations(
+				ctx,
+				MaximalPermissionPolicyAuditDecision, DecisionString(dec),
+				MaximalPermissionPolicyAuditReason, fmt.Sprintf("error getting pending API bindings: %v", err),
+			)
+			return dec, ReasonBindingLookupFailed, err
+		}
+		if len(pending) > 0 {
+			details.PolicyApplicable = true
+			addAuditAnnotations(
+				ctx,
+				MaximalPermissionPolicyAuditDecision, DecisionNoOpinion,
+				MaximalPermissionPolicyAuditReason, fmt.Sprintf("bound resources of API bindings %s pending", strings.Join(pending, ",")),
+			)
+			return authorizer.DecisionNoOpinion, ReasonBindingPending, nil
+		}
+	}
+
+	if !bound {
+		return a.allow(ctx, attr, details, ReasonNotBound, "no API binding bound")
+	}
+
+	if bindingMatch.APIBindingName != "" {
+		addAuditAnnotations(ctx, MaximalPermissionPolicyAuditBinding, bindingMatch.APIBindingName)
+	}
+
+	if len(bindingMatch.MatchingAPIBindingNames) > 1 {
+		addAuditAnnotations(ctx, MaximalPermissionPolicyAuditMatchingAPIBindings, strings.Join(bindingMatch.MatchingAPIBindingNames, ","))
+	}
+
+	if a.multiExportPolicy == RequireAll && len(bindingMatch.MatchingAPIBindingNames) > 1 {
+		return a.authorizeAllBindingMatches(ctx, attr, lcluster, matchCluster, details)
+	}
+	return a.authorizeBindingMatch(ctx, attr, lcluster, bindingMatch, details)
+}
+
+// authorizeBindingMatch evaluates the maximal permission policy of the API export of the given API binding match
+// for a request to the given cluster, and delegates if permitted.
+func (a *MaximalPermissionPolicyAuthorizer) authorizeBindingMatch(ctx context.Context, attr authorizer.Attributes, lcluster logicalcluster.Name, bindingMatch *APIBindingMatch, details *MaximalPermissionPolicyDecisionDetails) (authorizer.Decision, string, error) {
+	details.Bound = true
+	details.APIBindingName = bindingMatch.APIBindingName
+	details.BoundResource = bindingMatch.BoundResource
+	details.ExportReference = bindingMatch.ExportReference
+
+	if ref := bindingMatch.ExportReference.Workspace; ref != nil && a.isMaintenanceExempt(*ref) {
+		return a.allow(ctx, attr, details, ReasonMaintenanceExempt, fmt.Sprintf("maintenance exempt API export %q, path: %q", ref.ExportName, ref.Path))
 	}
 
 	path := "unknown"
 	exportName := "unknown"
-	if bindingLogicalCluster.Workspace != nil {
-		exportName = bindingLogicalCluster.Workspace.ExportName
-		path = bindingLogicalCluster.Workspace.Path
+	if bindingMatch.ExportReference.Workspace != nil {
+		exportName = bindingMatch.ExportReference.Workspace.ExportName
+		path = bindingMatch.ExportReference.Workspace.Path
+	}
+
+	// the verbs of the bound resource are checked before evaluating the policy
+	if br := bindingMatch.BoundResource; br != nil && len(br.Verbs) > 0 && !verbsAllow(br.Verbs, attr.GetVerb()) {
+		details.PolicyApplicable = true
+		addAuditAnnotations(
+			ctx,
+			MaximalPermissionPolicyAuditDecision, DecisionNoOpinion,
+			MaximalPermissionPolicyAuditReason, fmt.Sprintf("verb %q not allowed by bound resource %q of API binding %q", attr.GetVerb(), br.Resource, bindingMatch.APIBindingName),
+		)
+		a.recordDenial(ctx, attr, details, lcluster, exportName, path, "verb not allowed by bound resource")
+		return authorizer.DecisionNoOpinion, ReasonVerbNotAllowed, nil
+	}
+
+	spanCtx, span := a.startSpan(ctx, spanResolveAPIExport,
+		attribute.String(spanAttributeExport, exportName),
+		attribute.String(spanAttributeExportWS, path),
+	)
+	apiExport, found, err := a.resolveAPIExport(spanCtx, bindingMatch.ExportReference, bindingMatch.ExportClusterName)
+	if err == nil && !found && a.crossShardExportResolver != nil {
+		apiExport, found, err = a.crossShardExportResolver.ResolveAPIExport(spanCtx, bindingMatch.ExportReference)
+	}
+	span.End()
+	if err != nil {
+		dec := a.failureDecision()
+		addAuditAnnotations(
+			ctx,
+			MaximalPermissionPolicyAuditDecision, DecisionString(dec),
+			MaximalPermissionPolicyAuditReason, fmt.Sprintf("error getting API export: %v", err),
+		)
+		return dec, ReasonExportLookupFailed, &ExportResolutionError{Path: path, ExportName: exportName, Err: err}
 	}
 
 	// If we can't find the export default to close
 	if !found {
-		kaudit.AddAuditAnnotations(
+		details.PolicyApplicable = true
+		addAuditAnnotations(
 			ctx,
 			MaximalPermissionPolicyAuditDecision, DecisionNoOpinion,
 			MaximalPermissionPolicyAuditReason, fmt.Sprintf("API export %q not found, path: %q", exportName, path),
 		)
-		return authorizer.DecisionNoOpinion, MaximalPermissionPolicyAccessNotPermittedReason, err
+		a.recordDenial(ctx, attr, details, lcluster, exportName, path, "API export not found")
+		return authorizer.DecisionNoOpinion, ReasonExportNotFound, nil
+	}
+
+	// the API group the maximal permission policy is evaluated against
+	group := attr.GetAPIGroup()
+	if bindingMatch.Group != "" {
+		group = bindingMatch.Group
+	}
+	return a.authorizeExport(ctx, attr, apiExport, lcluster, exportName, path, group, details)
+}
+
+// AuthorizeForExport evaluates the maximal permission policy of the given API export for the request, without
+// resolving the API binding of the requested resource, e.g. for virtual workspaces serving the resources of a known
+// API export. If the policy permits the request, or there is no policy, the request is delegated. Exempt groups do not apply.
+func (a *MaximalPermissionPolicyAuthorizer) AuthorizeForExport(ctx context.Context, attr authorizer.Attributes, apiExport *apisv1alpha1.APIExport) (authorizer.Decision, string, error) {
+	ctx, annotations := withAuditAnnotations(ctx)
+	defer annotations.write(ctx)
+
+	path := logicalcluster.From(apiExport).String()
+	details := &MaximalPermissionPolicyDecisionDetails{
+		Bound: true,
+		ExportReference: &apisv1alpha1.ExportReference{
+			Workspace: &apisv1alpha1.WorkspaceExportReference{Path: path, ExportName: apiExport.Name},
+		},
+	}
+
+	var dec authorizer.Decision
+	var reason string
+	lcluster, err := genericapirequest.ClusterNameFrom(ctx)
+	if err != nil {
+		addAuditAnnotations(
+			ctx,
+			MaximalPermissionPolicyAuditDecision, DecisionNoOpinion,
+			MaximalPermissionPolicyAuditReason, fmt.Sprintf("error getting cluster from request: %v", err),
+		)
+		dec, reason = authorizer.DecisionNoOpinion, ReasonClusterUnknown
+	} else {
+		dec, reason, err = a.authorizeExport(ctx, attr, apiExport, lcluster, apiExport.Name, path, attr.GetAPIGroup(), details)
+	}
+	a.recordDecision(ctx, attr, details, dec, reason, err)
+	return a.enforce(ctx, attr, details, dec, reason, err)
+}
+
+// authorizeExport evaluates the maximal permission policy of the given API export for a request to the given cluster,
+// against the given API group, and delegates if permitted.
+func (a *MaximalPermissionPolicyAuthorizer) authorizeExport(ctx context.Context, attr authorizer.Attributes, apiExport *apisv1alpha1.APIExport, lcluster logicalcluster.Name, exportName, path, group string, details *MaximalPermissionPolicyDecisionDetails) (authorizer.Decision, string, error) {
+	a.warnDeprecated(ctx, apiExport, exportName, path, details)
+	if details.trace != nil {
+		details.trace.APIExportCluster = logicalcluster.From(apiExport)
+		details.trace.PolicyPresent = apiExport.Spec.MaximalPermissionPolicy != nil
 	}
 
 	if apiExport.Spec.MaximalPermissionPolicy == nil {
-		kaudit.AddAuditAnnotations(
+		return a.allow(ctx, attr, details, ReasonPolicyNotPresent, fmt.Sprintf("no maximal permission policy present in API export %q, path: %q, owning cluster: %q", exportName, path, logicalcluster.From(apiExport)))
+	}
+
+	group = mappedGroup(apiExport.Annotations, MaximalPermissionPolicyOldGroupsAnnotationKey, group)
+
+	a.auditCandidatePolicy(ctx, attr, apiExport, lcluster, group)
+
+	if verbs := apiExport.Spec.MaximalPermissionPolicy.Verbs; len(verbs) > 0 {
+		details.PolicyApplicable = true
+		addAuditAnnotations(ctx, MaximalPermissionPolicyAuditPolicyVariant, "verbs")
+		resource := resourceWithSubresource(attr)
+		if verbsPolicyAllows(verbs, group, resource, attr.GetVerb()) {
+			addAuditAnnotations(
+				ctx,
+				MaximalPermissionPolicyAuditDecision, DecisionAllowed,
+				MaximalPermissionPolicyAuditReason, fmt.Sprintf("verbs policy of API export %q, path: %q allows verb %q on %q", exportName, path, attr.GetVerb(), resource),
+			)
+			return a.policyAllowed(ctx, attr, exportName, path, details)
+		}
+
+		reason := fmt.Sprintf("verbs policy of API export %q, path: %q does not allow verb %q on %q", exportName, path, attr.GetVerb(), resource)
+		addAuditAnnotations(
 			ctx,
-			MaximalPermissionPolicyAuditDecision, DecisionAllowed,
-			MaximalPermissionPolicyAuditReason, fmt.Sprintf("no maximal permission policy present in API export %q, path: %q, owning cluster: %q", exportName, path, logicalcluster.From(apiExport)),
+			MaximalPermissionPolicyAuditDecision, DecisionNoOpinion,
+			MaximalPermissionPolicyAuditReason, reason,
 		)
-		return a.delegate.Authorize(ctx, attr)
+		a.recordDenial(ctx, attr, details, lcluster, exportName, path, reason)
+		return authorizer.DecisionNoOpinion, ReasonPolicyDenied, nil
 	}
 
+	// a global policy grants to the users and groups as they are, a local one to the prefixed or rewritten ones
+	variant, prefix, rewriter := "local", a.rbacUserGroupPrefix(), a.identityRewriter
 	if apiExport.Spec.MaximalPermissionPolicy.Local == nil {
-		kaudit.AddAuditAnnotations(
+		if apiExport.Spec.MaximalPermissionPolicy.Global == nil {
+			return a.allow(ctx, attr, details, ReasonPolicyNotPresent, fmt.Sprintf("no maximal local permission policy present in API export %q, path: %q, owning cluster: %q", apiExport.Name, path, logicalcluster.From(apiExport)))
+		}
+		variant, prefix, rewriter = "global", "", nil
+	}
+	if rewriter == nil {
+		rewriter = PrefixIdentityRewriter(prefix, a.userNameNormalizer)
+	}
+
+	details.PolicyApplicable = true
+	addAuditAnnotations(ctx, MaximalPermissionPolicyAuditPolicyVariant, variant)
+
+	// don't bother evaluating RBAC for a request that is gone
+	if err := ctx.Err(); err != nil {
+		addAuditAnnotations(
 			ctx,
-			MaximalPermissionPolicyAuditDecision, DecisionAllowed,
-			MaximalPermissionPolicyAuditReason, fmt.Sprintf("no maximal local permission policy present in API export %q, path: %q, owning cluster: %q", apiExport.Name, path, logicalcluster.From(apiExport)),
+			MaximalPermissionPolicyAuditDecision, DecisionNoOpinion,
+			MaximalPermissionPolicyAuditReason, fmt.Sprintf("request canceled before RBAC evaluation in API export cluster %q: %v", logicalcluster.From(apiExport), err),
+			MaximalPermissionPolicyAuditCanceled, "rbac",
 		)
-		return a.delegate.Authorize(ctx, attr)
+		return authorizer.DecisionNoOpinion, ReasonRequestCanceled, nil
 	}
 
 	// If bound, create a rbac authorizer filtered to the cluster.
-	clusterAuthorizer := a.newAuthorizer(logicalcluster.From(apiExport))
-	prefixedAttr := deepCopyAttributes(attr)
-	userInfo := prefixedAttr.User.(*user.DefaultInfo)
-	userInfo.Name = apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + userInfo.Name
-	userInfo.Groups = make([]string, 0, len(attr.GetUser().GetGroups()))
-	for _, g := range attr.GetUser().GetGroups() {
-		userInfo.Groups = append(userInfo.Groups, apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix+g)
-	}
-	dec, reason, err := clusterAuthorizer.Authorize(ctx, prefixedAttr)
-	if err != nil {
-		kaudit.AddAuditAnnotations(
-			ctx,
-			MaximalPermissionPolicyAuditDecision, DecisionNoOpinion,
-			MaximalPermissionPolicyAuditReason, fmt.Sprintf("error authorizing RBAC in API export cluster %q: %v", logicalcluster.From(apiExport), err),
+	if !a.adminClusterRBACMerge(ctx, apiExport) {
+		addAuditAnnotations(ctx, MaximalPermissionPolicyAuditAdminClusterMerge, "disabled")
+	}
+	mergeClusters := a.rbacMergeClusters(ctx, lcluster, apiExport)
+
+	if a.strictEmptyPolicy && prefix != "" && a.identityRewriter == nil {
+		found, err := a.prefixedBindingsGrantResource(logicalcluster.From(apiExport), mergeClusters, prefix, group, resourceWithSubresource(attr), attr.GetSubresource())
+		if err != nil {
+			dec := a.failureDecision()
+			addAuditAnnotations(
+				ctx,
+				MaximalPermissionPolicyAuditDecision, DecisionString(dec),
+				MaximalPermissionPolicyAuditReason, fmt.Sprintf("error listing RBAC bindings in API export cluster %q: %v", logicalcluster.From(apiExport), err),
+			)
+			return dec, ReasonRBACEvaluationFailed, err
+		}
+		if !found {
+			reason := fmt.Sprintf("local maximal permission policy of API export %q, path: %q grants nothing on %q", exportName, path, resourceWithSubresource(attr))
+			addAuditAnnotations(
+				ctx,
+				MaximalPermissionPolicyAuditDecision, DecisionDenied,
+				MaximalPermissionPolicyAuditReason, reason,
+			)
+			a.recordDenial(ctx, attr, details, lcluster, exportName, path, reason)
+			return authorizer.DecisionDeny, ReasonPolicyEmpty, nil
+		}
+	}
+
+	prefixedAttr := rewrittenAttributes(attr, group, rewriter)
+	if a.collectionGetAsList {
+		prefixedAttr = collectionGetAsList(prefixedAttr)
+	}
+
+	var dec authorizer.Decision
+	var reason string
+	var cached bool
+	var cacheKey [sha256.Size]byte
+	if a.decisionCache != nil && !details.dryRun {
+		cacheKey = decisionCacheKey(prefixedAttr, logicalcluster.From(apiExport), mergeClusters)
+		dec, reason, cached = a.decisionCache.get(cacheKey)
+	}
+	if cached {
+		addAuditAnnotations(ctx, MaximalPermissionPolicyAuditDecisionCache, "hit")
+	} else {
+		release := func() {}
+		var err error
+		if a.rbacSemaphores != nil {
+			release, err = a.rbacSemaphores.acquire(ctx, logicalcluster.From(apiExport))
+			if err != nil {
+				addAuditAnnotations(
+					ctx,
+					MaximalPermissionPolicyAuditDecision, DecisionNoOpinion,
+					MaximalPermissionPolicyAuditReason, fmt.Sprintf("RBAC evaluation in API export cluster %q not started: %v", logicalcluster.From(apiExport), err),
+				)
+				return authorizer.DecisionNoOpinion, ReasonRBACEvaluationFailed, nil
+			}
+		}
+		if a.circuitBreakers != nil && !a.circuitBreakers.allow(logicalcluster.From(apiExport)) {
+			release()
+			addAuditAnnotations(
+				ctx,
+				MaximalPermissionPolicyAuditDecision, DecisionString(a.circuitBreakers.openDecision),
+				MaximalPermissionPolicyAuditReason, fmt.Sprintf("circuit breaker of API export cluster %q open after consecutive RBAC evaluation errors", logicalcluster.From(apiExport)),
+			)
+			return a.circuitBreakers.openDecision, ReasonCircuitBreakerOpen, nil
+		}
+		clusterAuthorizer := a.clusterAuthorizer(ctx, logicalcluster.From(apiExport), mergeClusters)
+		start := time.Now()
+		spanCtx, span := a.startSpan(ctx, spanAuthorizeRBAC,
+			attribute.String(spanAttributeExport, exportName),
+			attribute.String(spanAttributeExportWS, logicalcluster.From(apiExport).String()),
 		)
-		return authorizer.DecisionNoOpinion, reason, err
+		dec, reason, err = a.authorizeRBAC(spanCtx, clusterAuthorizer, prefixedAttr)
+		span.End()
+		if a.metrics {
+			rbacDurations.WithLabelValues(exportName).Observe(time.Since(start).Seconds())
+		}
+		release()
+		if a.circuitBreakers != nil && a.circuitBreakers.done(logicalcluster.From(apiExport), err) {
+			if a.metrics {
+				circuitBreakerOpens.Inc()
+			}
+		}
+		if err != nil {
+			dec := a.failureDecision()
+			addAuditAnnotations(
+				ctx,
+				MaximalPermissionPolicyAuditDecision, DecisionString(dec),
+				MaximalPermissionPolicyAuditReason, fmt.Sprintf("error authorizing RBAC in API export cluster %q: %v", logicalcluster.From(apiExport), err),
+			)
+			return dec, ReasonRBACEvaluationFailed, err
+		}
+		if a.decisionCache != nil && !details.dryRun {
+			a.decisionCache.add(cacheKey, dec, reason)
+		}
+	}
+	if details.trace != nil {
+		details.trace.PrefixedUser, details.trace.RBACDecision, details.trace.RBACReason = prefixedAttr.GetUser(), dec, reason
 	}
 
-	kaudit.AddAuditAnnotations(
+	addAuditAnnotations(
 		ctx,
 		MaximalPermissionPolicyAuditDecision, DecisionString(dec),
-		MaximalPermissionPolicyAuditReason, fmt.Sprintf("API export cluster %q reason: %v", logicalcluster.From(apiExport), reason),
+		MaximalPermissionPolicyAuditReason, fmt.Sprintf("API export cluster %q, user and group prefix: %q reason: %v", logicalcluster.From(apiExport), prefix, reason),
+		MaximalPermissionPolicyAuditRBACDecision, DecisionString(dec),
 	)
 
 	if dec == authorizer.DecisionAllow {
-		return a.delegate.Authorize(ctx, attr)
+		return a.policyAllowed(ctx, attr, exportName, path, details)
+	}
+
+	addAuditAnnotations(
+		ctx,
+		MaximalPermissionPolicyAuditRBACUser, prefixedAttr.GetUser().GetName(),
+		MaximalPermissionPolicyAuditRBACGroupCount, strconv.Itoa(len(prefixedAttr.GetUser().GetGroups())),
+	)
+	a.recordDenial(ctx, attr, details, lcluster, exportName, path, reason)
+
+	// an explicit deny of the RBAC authorizer terminates the authorizer chain, anything else is no opinion
+	if dec == authorizer.DecisionDeny {
+		return authorizer.DecisionDeny, ReasonPolicyDenied, nil
 	}
+	return authorizer.DecisionNoOpinion, ReasonPolicyDenied, nil
+}
 
-	return authorizer.DecisionNoOpinion, reason, nil
+// failureDecision returns the decision of a request whose evaluation failed,
+// i.e. DecisionDeny with FailClosed, and DecisionNoOpinion otherwise.
+func (a *MaximalPermissionPolicyAuthorizer) failureDecision() authorizer.Decision {
+	if a.failurePolicy != FailClosed {
+		return authorizer.DecisionNoOpinion
+	}
+	return authorizer.DecisionDeny
 }
 
-func getAPIBindingReferenceForAttributes(apiBindingIndexer cache.Indexer, attr authorizer.Attributes, clusterName logicalcluster.Name) (*apisv1alpha1.ExportReference, bool, error) {
-	objs, err := apiBindingIndexer.ByIndex(indexers.ByLogicalCluster, clusterName.String())
-	if err != nil {
+// exemptGroup returns the first exempt group of the user, if any.
+func (a *MaximalPermissionPolicyAuthorizer) exemptGroup(userInfo user.Info) (string, bool) {
+	for _, group := range userInfo.GetGroups() {
+		if a.exemptGroups.Has(group) {
+			return group, true
+		}
+	}
+	return "", false
+}
+
+// policyAllowed returns the decision of a request allowed by the maximal permission policy of the given API export,
+// i.e. allows it with WithTerminalDecision, and delegates it otherwise.
+func (a *MaximalPermissionPolicyAuthorizer) policyAllowed(ctx context.Context, attr authorizer.Attributes, exportName, path string, details *MaximalPermissionPolicyDecisionDetails) (authorizer.Decision, string, error) {
+	addAuditAnnotations(ctx, MaximalPermissionPolicyAuditReasonCode, ReasonPolicyAllowed)
+	if !a.terminalDecision {
+		return a.authorizeDelegate(ctx, attr, details)
+	}
+	return authorizer.DecisionAllow, ReasonPolicyAllowed, nil
+}
+
+// allow records the decision, the reason code and the human readable reason of a request allowed by the maximal
+// permission policy in the audit annotations, along with the given further key value pairs, and delegates it.
+func (a *MaximalPermissionPolicyAuthorizer) allow(ctx context.Context, attr authorizer.Attributes, details *MaximalPermissionPolicyDecisionDetails, reasonCode, reason string, keysAndValues ...string) (authorizer.Decision, string, error) {
+	addAuditAnnotations(ctx, append([]string{
+		MaximalPermissionPolicyAuditDecision, DecisionAllowed,
+		MaximalPermissionPolicyAuditReason, reason,
+		MaximalPermissionPolicyAuditReasonCode, reasonCode,
+	}, keysAndValues...)...)
+	return a.authorizeDelegate(ctx, attr, details)
+}
+
+// authorizeDelegate authorizes with the delegate, unless the context is done already.
+// Without delegate, no request is allowed.
+func (a *MaximalPermissionPolicyAuthorizer) authorizeDelegate(ctx context.Context, attr authorizer.Attributes, details *MaximalPermissionPolicyDecisionDetails) (authorizer.Decision, string, error) {
+	if err := ctx.Err(); err != nil {
+		addAuditAnnotations(
+			ctx,
+			MaximalPermissionPolicyAuditDecision, DecisionNoOpinion,
+			MaximalPermissionPolicyAuditReason, fmt.Sprintf("request canceled before delegating: %v", err),
+			MaximalPermissionPolicyAuditReasonCode, ReasonRequestCanceled,
+			MaximalPermissionPolicyAuditCanceled, "delegate",
+		)
+		return authorizer.DecisionNoOpinion, ReasonRequestCanceled, nil
+	}
+	if isWithoutDelegation(ctx) {
+		return authorizer.DecisionAllow, ReasonPolicyAllowed, nil
+	}
+	if a.delegate == nil {
+		return authorizer.DecisionNoOpinion, ReasonNoDelegate, nil
+	}
+	details.Delegated = true
+	return a.delegate.Authorize(ctx, attr)
+}
+
+// enforce returns the given decision, or without enforcement, see WithEnforcement, the decision of the delegate,
+// marking the given decision as shadow decision in the audit annotations.
+func (a *MaximalPermissionPolicyAuthorizer) enforce(ctx context.Context, attr authorizer.Attributes, details *MaximalPermissionPolicyDecisionDetails, dec authorizer.Decision, reason string, err error) (authorizer.Decision, string, error) {
+	if !a.withoutEnforcement {
+		return dec, reason, err
+	}
+	addAuditAnnotations(ctx, MaximalPermissionPolicyAuditEnforcement, "shadow")
+	if details.Delegated {
+		return dec, reason, err
+	}
+	return a.authorizeDelegate(ctx, attr, details)
+}
+
+// authorizeRBAC authorizes the attributes with the given RBAC authorizer, retrying transient errors if enabled with WithRBACRetry.
+func (a *MaximalPermissionPolicyAuthorizer) authorizeRBAC(ctx context.Context, clusterAuthorizer authorizer.Authorizer, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+	if a.rbacRetryAttempts <= 1 {
+		return clusterAuthorizer.Authorize(ctx, attr)
+	}
+
+	var dec authorizer.Decision
+	var reason string
+	attempts := 0
+	backoff := wait.Backoff{Steps: a.rbacRetryAttempts, Duration: a.rbacRetryBackoff, Factor: 2}
+	err := retry.OnError(backoff, isTransientRBACError, func() error {
+		attempts++
+		var err error
+		dec, reason, err = clusterAuthorizer.Authorize(ctx, attr)
+		return err
+	})
+	if err != nil && isTransientRBACError(err) {
+		return authorizer.DecisionNoOpinion, reason, fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+	}
+	return dec, reason, err
+}
+
+// isTransientRBACError returns whether the RBAC evaluation failed with an error that is likely to go away when retried.
+func isTransientRBACError(err error) bool {
+	return apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err) || apierrors.IsTooManyRequests(err) || apierrors.IsServiceUnavailable(err)
+}
+
+// rbacUserGroupPrefix returns the prefix of the user and group names when evaluating a local policy.
+func (a *MaximalPermissionPolicyAuthorizer) rbacUserGroupPrefix() string {
+	if a.userGroupPrefix != "" {
+		return a.userGroupPrefix
+	}
+	return apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix
+}
+
+// prefixedAttributes returns a copy of the attributes for the given API group, with user and groups normalized,
+// unless normalize is nil, and prefixed. The user is copied through the user.Info getters, i.e. it need not be a *user.DefaultInfo.
+func prefixedAttributes(attr authorizer.Attributes, group, prefix string, normalize func(name string) string) authorizer.AttributesRecord {
+	return rewrittenAttributes(attr, group, PrefixIdentityRewriter(prefix, normalize))
+}
+
+// rewrittenAttributes returns a copy of the attributes for the given API group, with the user rewritten by the given rewriter.
+func rewrittenAttributes(attr authorizer.Attributes, group string, rewriter IdentityRewriter) authorizer.AttributesRecord {
+	rewrittenAttr := deepCopyAttributes(attr)
+	rewrittenAttr.APIGroup = group
+	rewrittenAttr.User = rewriter.Rewrite(rewrittenAttr.User)
+	return rewrittenAttr
+}
+
+// collectionGetAsList returns the attributes with a get on a collection, i.e. without name and subresource,
+// turned into a list, which is what Kubernetes derives from a GET request on a collection.
+func collectionGetAsList(attr authorizer.AttributesRecord) authorizer.AttributesRecord {
+	if attr.ResourceRequest && attr.Verb == "get" && attr.Name == "" && attr.Subresource == "" {
+		attr.Verb = "list"
+	}
+	return attr
+}
+
+// SetMaintenanceExemptExports replaces the set of API exports whose maximal permission policy is not enforced,
+// e.g. during a maintenance window. Requests for resources bound to these exports are delegated directly.
+// It is safe to call concurrently with Authorize.
+func (a *MaximalPermissionPolicyAuthorizer) SetMaintenanceExemptExports(exports ...apisv1alpha1.WorkspaceExportReference) {
+	exempt := make(map[apisv1alpha1.WorkspaceExportReference]bool, len(exports))
+	for _, export := range exports {
+		exempt[export] = true
+	}
+
+	a.maintenanceExemptExportsLock.Lock()
+	defer a.maintenanceExemptExportsLock.Unlock()
+	a.maintenanceExemptExports = exempt
+}
+
+func (a *MaximalPermissionPolicyAuthorizer) isMaintenanceExempt(ref apisv1alpha1.WorkspaceExportReference) bool {
+	a.maintenanceExemptExportsLock.RLock()
+	defer a.maintenanceExemptExportsLock.RUnlock()
+	return a.maintenanceExemptExports[ref]
+}
+
+// adminClusterRBACMerge returns whether the RBAC of the local admin cluster is merged
+// when evaluating the maximal permission policy of the given API export.
+func (a *MaximalPermissionPolicyAuthorizer) adminClusterRBACMerge(ctx context.Context, apiExport *apisv1alpha1.APIExport) bool {
+	if isWithoutAdminClusterRBACMerge(ctx) {
+		return false
+	}
+	switch apiExport.Annotations[MaximalPermissionPolicyAdminClusterMergeAnnotationKey] {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return !a.withoutAdminClusterRBACMergeByDefault
+}
+
+// adminClusters returns the clusters merged by adminClusterRBACMerge, see WithFallbackClusters.
+func (a *MaximalPermissionPolicyAuthorizer) adminClusters() []logicalcluster.Name {
+	if len(a.fallbackClusters) == 0 {
+		return []logicalcluster.Name{genericcontrolplane.LocalAdminCluster}
+	}
+	return a.fallbackClusters
+}
+
+// rbacMergeClusters returns the clusters whose RBAC is merged with the RBAC of the API export cluster
+// when evaluating the maximal permission policy of the API export for a request to the given cluster.
+func (a *MaximalPermissionPolicyAuthorizer) rbacMergeClusters(ctx context.Context, requestCluster logicalcluster.Name, apiExport *apisv1alpha1.APIExport) []logicalcluster.Name {
+	var mergeClusters []logicalcluster.Name
+	if a.adminClusterRBACMerge(ctx, apiExport) {
+		mergeClusters = append(mergeClusters, a.adminClusters()...)
+	}
+	if a.consumerParentRBAC {
+		if parent, hasParent := requestCluster.Parent(); hasParent {
+			mergeClusters = append(mergeClusters, parent)
+		}
+	}
+	return mergeClusters
+}
+
+// recordDenial counts a request to the given cluster denied by the maximal permission policy if enabled with
+// WithMetrics and warns about it,
+// unless it is a duplicate suppressed by WithDenialDeduplication or a dry run of Explain.
+func (a *MaximalPermissionPolicyAuthorizer) recordDenial(ctx context.Context, attr authorizer.Attributes, details *MaximalPermissionPolicyDecisionDetails, lcluster logicalcluster.Name, exportName, path, reason string) {
+	if details.dryRun {
+		return
+	}
+	if a.metrics {
+		denials.WithLabelValues(consumerTenant(lcluster)).Inc()
+	}
+	if a.denialDeduplicator != nil && !a.denialDeduplicator.first(attr, exportName, path) {
+		if a.metrics {
+			suppressedDenials.Inc()
+		}
+		return
+	}
+	a.warnDenied(ctx, attr, exportName, path, reason)
+}
+
+// warnDenied attaches a warning to the response of a write request denied by the maximal permission policy.
+func (a *MaximalPermissionPolicyAuthorizer) warnDenied(ctx context.Context, attr authorizer.Attributes, exportName, path, reason string) {
+	if !a.denialWarnings || !writeVerbs.Has(attr.GetVerb()) {
+		return
+	}
+	warning.AddWarning(ctx, "", fmt.Sprintf("%s of API export %q, path: %q: %s", MaximalPermissionPolicyAccessNotPermittedReason, exportName, path, reason))
+}
+
+// warnDeprecated calls the warning handler if the given API export is deprecated, unless it is a dry run of Explain.
+func (a *MaximalPermissionPolicyAuthorizer) warnDeprecated(ctx context.Context, apiExport *apisv1alpha1.APIExport, exportName, path string, details *MaximalPermissionPolicyDecisionDetails) {
+	if details.dryRun || a.warningHandler == nil || !conditions.IsTrue(apiExport, apisv1alpha1.APIExportDeprecated) {
+		return
+	}
+	message := fmt.Sprintf("API export %q, path: %q is deprecated", exportName, path)
+	if reason := conditions.GetMessage(apiExport, apisv1alpha1.APIExportDeprecated); reason != "" {
+		message += ": " + reason
+	}
+	a.warningHandler(ctx, message)
+}
+
+// matchInheritedAPIBinding matches the requested resource in the ancestors of the given cluster by the inheritance lookup,
+// returning the match of the closest ancestor binding it and that ancestor.
+func (a *MaximalPermissionPolicyAuthorizer) matchInheritedAPIBinding(ctx context.Context, attr authorizer.Attributes, clusterName logicalcluster.Name) (*APIBindingMatch, bool, logicalcluster.Name, error) {
+	visited := map[logicalcluster.Name]bool{clusterName: true}
+	for parent := a.inheritanceLookup(clusterName); !parent.Empty() && !visited[parent]; parent = a.inheritanceLookup(parent) {
+		visited[parent] = true
+		match, bound, err := a.matchAPIBinding(ctx, attr, parent)
+		if err != nil || bound {
+			return match, bound, parent, err
+		}
+	}
+	return nil, false, logicalcluster.Name{}, nil
+}
+
+// getAPIBindingReferenceForAttributes returns the reference of the API binding binding the requested resource in the given cluster.
+// If multiple API bindings bind the resource, the first one by name is returned, listing all of them in MatchingAPIBindingNames.
+// See getAPIBindingMatchesForAttributes for the matching rules.
+func getAPIBindingReferenceForAttributes(apiBindingIndexer cache.Indexer, attr authorizer.Attributes, clusterName logicalcluster.Name, scanLimit int) (*APIBindingMatch, bool, error) {
+	matches, err := getAPIBindingMatchesForAttributes(apiBindingIndexer, attr, clusterName, scanLimit)
+	if err != nil || len(matches) == 0 {
 		return nil, false, err
 	}
+
+	match := *matches[0]
+	for _, m := range matches {
+		match.MatchingAPIBindingNames = append(match.MatchingAPIBindingNames, m.APIBindingName)
+	}
+	return &match, true, nil
+}
+
+// getAPIBindingMatchesForAttributes returns a match for each API binding binding the requested resource in the given cluster, sorted by name.
+// Subresource requests match bound resources of the form "<resource>/<subresource>", e.g. "widgets/scale", if any,
+// and the bound resource of the parent resource otherwise. The subresource is evaluated by RBAC in either case.
+// A bound resource "*" matches any resource and subresource of its group, unless another bound resource of any of
+// the API bindings matches the requested resource exactly, i.e. exact bound resources take precedence over wildcards.
+// If the indexer has the indexers.APIBindingByClusterAndBoundGroupResource index, only the API bindings binding
+// the requested resource are considered, and if scanLimit is positive, errAPIBindingScanLimitExceeded is returned if
+// there are more than scanLimit of them. Otherwise all API bindings of the cluster are scanned, and if scanLimit is
+// positive, at most scanLimit of them and errAPIBindingScanLimitExceeded is returned if none of them matches but there are more.
+func getAPIBindingMatchesForAttributes(apiBindingIndexer cache.Indexer, attr authorizer.Attributes, clusterName logicalcluster.Name, scanLimit int) ([]*APIBindingMatch, error) {
+	var objs []interface{}
+	var err error
+	if _, ok := apiBindingIndexer.GetIndexers()[indexers.APIBindingByClusterAndBoundGroupResource]; ok {
+		objs, err = boundAPIBindingsForAttributes(apiBindingIndexer, attr, clusterName)
+		if err != nil {
+			return nil, err
+		}
+		if scanLimit > 0 && len(objs) > scanLimit {
+			return nil, fmt.Errorf("%w: %d API bindings in cluster %q bind %q, more than %d", errAPIBindingScanLimitExceeded, len(objs), clusterName, resourceWithSubresource(attr), scanLimit)
+		}
+		// the candidates of the index are bound already, i.e. there is nothing to scan
+		scanLimit = 0
+	} else {
+		objs, err = apiBindingIndexer.ByIndex(indexers.ByLogicalCluster, clusterName.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the indexer returns the bindings in random order, sort them to match deterministically if multiple bind the resource
+	apiBindings := make([]*apisv1alpha1.APIBinding, 0, len(objs))
 	for _, obj := range objs {
-		apiBinding := obj.(*apisv1alpha1.APIBinding)
-		for _, br := range apiBinding.Status.BoundResources {
-			if br.Group == attr.GetAPIGroup() && br.Resource == attr.GetResource() {
-				return &apiBinding.Spec.Reference, true, nil
+		apiBindings = append(apiBindings, obj.(*apisv1alpha1.APIBinding))
+	}
+	sort.Slice(apiBindings, func(i, j int) bool {
+		return apiBindings[i].Name < apiBindings[j].Name
+	})
+
+	// a subresource request matches a subresource-specific bound resource "<resource>/<subresource>" first,
+	// falling back to the bound resource of the parent resource, and to a wildcard bound resource last
+	var matches, parentMatches, wildcardMatches []*APIBindingMatch
+	for i, apiBinding := range apiBindings {
+		if scanLimit > 0 && i >= scanLimit {
+			if len(matches) > 0 || len(parentMatches) > 0 || len(wildcardMatches) > 0 {
+				break
 			}
+			return nil, fmt.Errorf("%w: no match in %d of %d API bindings in cluster %q", errAPIBindingScanLimitExceeded, scanLimit, len(objs), clusterName)
+		}
+
+		group := mappedGroup(apiBinding.Annotations, MaximalPermissionPolicyGroupAliasesAnnotationKey, attr.GetAPIGroup())
+		if attr.GetSubresource() != "" {
+			matches = appendBindingMatch(matches, apiBinding, group, resourceWithSubresource(attr))
 		}
+		parentMatches = appendBindingMatch(parentMatches, apiBinding, group, attr.GetResource())
+		wildcardMatches = appendBindingMatch(wildcardMatches, apiBinding, group, boundResourceWildcard)
 	}
-	return nil, false, nil
+	if len(matches) == 0 {
+		matches = parentMatches
+	}
+	if len(matches) == 0 {
+		matches = wildcardMatches
+	}
+	return matches, nil
+}
+
+// boundAPIBindingsForAttributes returns the API bindings binding the requested resource, the subresource-specific
+// bound resource or all resources of the group by the indexers.APIBindingByClusterAndBoundGroupResource index.
+func boundAPIBindingsForAttributes(apiBindingIndexer cache.Indexer, attr authorizer.Attributes, clusterName logicalcluster.Name) ([]interface{}, error) {
+	resources := []string{attr.GetResource(), boundResourceWildcard}
+	if attr.GetSubresource() != "" {
+		resources = append(resources, resourceWithSubresource(attr))
+	}
+
+	var ret []interface{}
+	seen := map[interface{}]bool{}
+	for _, resource := range resources {
+		objs, err := apiBindingIndexer.ByIndex(indexers.APIBindingByClusterAndBoundGroupResource, indexers.ClusterAndBoundGroupResourceValue(clusterName, attr.GetAPIGroup(), resource))
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			if !seen[obj] {
+				seen[obj] = true
+				ret = append(ret, obj)
+			}
+		}
+	}
+	return ret, nil
+}
+
+// appendBindingMatch appends the match of the API binding if it binds the resource of the given group.
+// Empty bound resources never match.
+func appendBindingMatch(matches []*APIBindingMatch, apiBinding *apisv1alpha1.APIBinding, group, resource string) []*APIBindingMatch {
+	for i := range apiBinding.Status.BoundResources {
+		br := &apiBinding.Status.BoundResources[i]
+		if isEmptyBoundResource(br) || br.Group != group || br.Resource != resource {
+			continue
+		}
+		return append(matches, &APIBindingMatch{
+			ExportReference:   &apiBinding.Spec.Reference,
+			ExportClusterName: logicalcluster.New(apiBinding.Status.APIExportClusterName),
+			APIBindingName:    apiBinding.Name,
+			BoundResource:     br,
+			Group:             group,
+		})
+	}
+	return matches
+}
+
+// isEmptyBoundResource returns whether the bound resource has neither group nor resource. It is invalid, and must not
+// match e.g. core requests lacking the resource like a wildcard.
+func isEmptyBoundResource(br *apisv1alpha1.BoundAPIResource) bool {
+	return br.Group == "" && br.Resource == ""
+}
+
+// isIncompleteRequestInfo returns whether the attributes lack the resource of a resource request
+// or the path of a non-resource request, e.g. because the request info was not fully populated.
+// Resource requests with a subresource but without resource are rejected before.
+func isIncompleteRequestInfo(attr authorizer.Attributes) bool {
+	if attr.IsResourceRequest() {
+		return attr.GetResource() == ""
+	}
+	return attr.GetPath() == ""
+}
+
+// resourceWithSubresource returns the requested resource, or "<resource>/<subresource>" for subresource requests.
+func resourceWithSubresource(attr authorizer.Attributes) string {
+	if attr.GetSubresource() != "" {
+		return attr.GetResource() + "/" + attr.GetSubresource()
+	}
+	return attr.GetResource()
+}
+
+// verbsPolicyAllows returns whether the verbs policy allows the verb on the resource of the given group.
+func verbsPolicyAllows(policies []apisv1alpha1.ResourceVerbsPolicy, group, resource, verb string) bool {
+	for _, p := range policies {
+		if p.Group == group && p.Resource == resource && verbsAllow(p.Verbs, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+// verbsAllow returns whether the verbs contain the verb or "*".
+func verbsAllow(verbs []string, verb string) bool {
+	for _, v := range verbs {
+		if v == verb || v == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// mappedGroup returns the API group the given group is mapped to by the comma separated "from=to" pairs
+// of the annotation with the given key, or the group itself if it is not mapped.
+func mappedGroup(annotations map[string]string, key, group string) string {
+	mappings, ok := annotations[key]
+	if !ok {
+		return group
+	}
+	for _, mapping := range strings.Split(mappings, ",") {
+		parts := strings.SplitN(strings.TrimSpace(mapping), "=", 2)
+		if len(parts) == 2 && parts[0] == group {
+			return parts[1]
+		}
+	}
+	return group
 }
 
-func getAPIExportByReference(apiExportIndexer cache.Indexer, exportRef *apisv1alpha1.ExportReference) (*apisv1alpha1.APIExport, bool, error) {
-	objs, err := apiExportIndexer.ByIndex(indexers.ByLogicalCluster, exportRef.Workspace.Path)
+// ResolveExportCluster returns the logical cluster of the API export the given reference resolves to,
+// and false if the reference does not point to an existing API export of the indexer.
+// It returns ErrUnsupportedExportReference if the reference is not a Workspace reference.
+// The indexer must have the indexers.APIExportByClusterAndName or the indexers.ByLogicalCluster index.
+func ResolveExportCluster(apiExportIndexer cache.Indexer, exportRef *apisv1alpha1.ExportReference) (logicalcluster.Name, bool, error) {
+	apiExport, found, err := getAPIExportByReference(apiExportIndexer, exportRef, logicalcluster.Name{})
+	if err != nil || !found {
+		return logicalcluster.Name{}, false, err
+	}
+	return logicalcluster.From(apiExport), true, nil
+}
+
+// getAPIExportByReference returns the API export of the given reference. If the resolved logical cluster of the
+// API export is given, e.g. from the status of the API binding, it is looked up in that cluster by the
+// indexers.ByLogicalCluster index, and by the path of the reference otherwise.
+func getAPIExportByReference(apiExportIndexer cache.Indexer, exportRef *apisv1alpha1.ExportReference, exportClusterName logicalcluster.Name) (*apisv1alpha1.APIExport, bool, error) {
+	apiExport, found, err := getAPIExportByName(apiExportIndexer, exportRef, exportClusterName)
+	if err != nil || !found {
+		return apiExport, found, err
+	}
+
+	if !matchesExportReferenceUID(apiExport, exportRef) {
+		return nil, false, nil
+	}
+	return apiExport, true, nil
+}
+
+// matchesExportReferenceUID returns whether the API export has the UID of the reference, if any. A stale reference
+// must not resolve to another API export recreated with the same name.
+func matchesExportReferenceUID(apiExport *apisv1alpha1.APIExport, exportRef *apisv1alpha1.ExportReference) bool {
+	uid := exportRef.Workspace.UID
+	return uid == "" || apiExport.UID == uid
+}
+
+// getAPIExportByName returns the API export of the name of the reference, ignoring its UID.
+func getAPIExportByName(apiExportIndexer cache.Indexer, exportRef *apisv1alpha1.ExportReference, exportClusterName logicalcluster.Name) (*apisv1alpha1.APIExport, bool, error) {
+	if exportRef.Workspace == nil {
+		return nil, false, ErrUnsupportedExportReference
+	}
+
+	if !exportClusterName.Empty() {
+		return getAPIExportByClusterName(apiExportIndexer, exportClusterName, exportRef.Workspace.ExportName)
+	}
+
+	if _, ok := apiExportIndexer.GetIndexers()[indexers.APIExportByClusterAndName]; ok {
+		objs, err := apiExportIndexer.ByIndex(indexers.APIExportByClusterAndName, indexers.ClusterPathAndAPIExportName(exportRef.Workspace.Path, exportRef.Workspace.ExportName))
+		if err != nil {
+			return nil, false, err
+		}
+		// the index key joins path and name with "|", which both may contain
+		for _, obj := range objs {
+			apiExport := obj.(*apisv1alpha1.APIExport)
+			if logicalcluster.From(apiExport).String() == exportRef.Workspace.Path && apiExport.Name == exportRef.Workspace.ExportName {
+				return apiExport, true, nil
+			}
+		}
+		return nil, false, nil
+	}
+
+	// fall back to scanning the exports of the workspace
+	return getAPIExportByClusterName(apiExportIndexer, logicalcluster.New(exportRef.Workspace.Path), exportRef.Workspace.ExportName)
+}
+
+// getAPIExportByClusterName returns the API export of the given name in the given logical cluster
+// by the indexers.ByLogicalCluster index.
+func getAPIExportByClusterName(apiExportIndexer cache.Indexer, clusterName logicalcluster.Name, name string) (*apisv1alpha1.APIExport, bool, error) {
+	objs, err := apiExportIndexer.ByIndex(indexers.ByLogicalCluster, clusterName.String())
 	if err != nil {
 		return nil, false, err
 	}
 	for _, obj := range objs {
 		apiExport := obj.(*apisv1alpha1.APIExport)
-		if apiExport.Name == exportRef.Workspace.ExportName {
-			return apiExport, true, err
+		if apiExport.Name == name {
+			return apiExport, true, nil
 		}
 	}
-	return nil, false, err
-
+	return nil, false, nil
 }
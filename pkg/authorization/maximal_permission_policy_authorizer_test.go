@@ -0,0 +1,3261 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
+	kcpfakeclient "github.com/kcp-dev/client-go/clients/clientset/versioned/fake"
+	kcpkubernetesinformers "github.com/kcp-dev/client-go/clients/informers"
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	auditinternal "k8s.io/apiserver/pkg/apis/audit"
+	kaudit "k8s.io/apiserver/pkg/audit"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/endpoints/filters"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/testutil"
+	authorizationapi "k8s.io/kubernetes/pkg/apis/authorization"
+	"k8s.io/kubernetes/pkg/controller"
+	"k8s.io/kubernetes/pkg/genericcontrolplane"
+	"k8s.io/kubernetes/pkg/registry/authorization/subjectaccessreview"
+	"k8s.io/kubernetes/plugin/pkg/auth/authorizer/rbac"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	kcpfakeinformerclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/fake"
+	kcpinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+func newAPIBinding(clusterName, name, exportPath, exportName string, boundResources ...apisv1alpha1.BoundAPIResource) *apisv1alpha1.APIBinding {
+	return &apisv1alpha1.APIBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Annotations: map[string]string{
+				logicalcluster.AnnotationKey: clusterName,
+			},
+		},
+		Spec: apisv1alpha1.APIBindingSpec{
+			Reference: apisv1alpha1.ExportReference{
+				Workspace: &apisv1alpha1.WorkspaceExportReference{
+					Path:       exportPath,
+					ExportName: exportName,
+				},
+			},
+		},
+		Status: apisv1alpha1.APIBindingStatus{
+			BoundResources: boundResources,
+		},
+	}
+}
+
+func newAPIExport(clusterName, name string, policy *apisv1alpha1.MaximalPermissionPolicy) *apisv1alpha1.APIExport {
+	return &apisv1alpha1.APIExport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Annotations: map[string]string{
+				logicalcluster.AnnotationKey: clusterName,
+			},
+		},
+		Spec: apisv1alpha1.APIExportSpec{
+			MaximalPermissionPolicy: policy,
+		},
+	}
+}
+
+func newIndexer(t testing.TB, objs ...interface{}) cache.Indexer {
+	t.Helper()
+
+	indexer := cache.NewIndexer(kcpcache.MetaClusterNamespaceKeyFunc, cache.Indexers{indexers.ByLogicalCluster: indexers.IndexByLogicalCluster})
+	for _, obj := range objs {
+		require.NoError(t, indexer.Add(obj))
+	}
+	return indexer
+}
+
+func withLocalPolicy() *apisv1alpha1.MaximalPermissionPolicy {
+	return &apisv1alpha1.MaximalPermissionPolicy{Local: &apisv1alpha1.LocalAPIExportPolicy{}}
+}
+
+// newTestMaximalPermissionPolicyAuthorizer returns an authorizer constructed with the given options, serving the given
+// API bindings and exports, and evaluating the maximal permission policies of all API export clusters with inner.
+func newTestMaximalPermissionPolicyAuthorizer(t testing.TB, bindings []*apisv1alpha1.APIBinding, exports []*apisv1alpha1.APIExport, inner, delegate authorizer.Authorizer, opts ...MaximalPermissionPolicyAuthorizerOption) *MaximalPermissionPolicyAuthorizer {
+	t.Helper()
+
+	kubeInformers := kcpkubernetesinformers.NewSharedInformerFactory(kcpfakeclient.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+	kcpInformers := kcpinformers.NewSharedInformerFactory(kcpfakeinformerclient.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+	opts = append([]MaximalPermissionPolicyAuthorizerOption{
+		WithClusterAuthorizerFactory(func(clusterName logicalcluster.Name) authorizer.Authorizer {
+			return inner
+		}),
+	}, opts...)
+	a, err := NewMaximalPermissionPolicyAuthorizer(kubeInformers, kcpInformers, delegate, opts...)
+	require.NoError(t, err)
+
+	for _, b := range bindings {
+		require.NoError(t, kcpInformers.Apis().V1alpha1().APIBindings().Informer().GetIndexer().Add(b))
+	}
+	for _, e := range exports {
+		require.NoError(t, kcpInformers.Apis().V1alpha1().APIExports().Informer().GetIndexer().Add(e))
+	}
+	return a.(*MaximalPermissionPolicyAuthorizer)
+}
+
+// newKubeInformers returns synced RBAC informers serving the given objects, indexing the bindings of users and groups
+// bearing the default prefix for WithStrictEmptyPolicy.
+func newKubeInformers(t *testing.T, objs ...runtime.Object) kcpkubernetesinformers.SharedInformerFactory {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	kubeInformers := kcpkubernetesinformers.NewSharedInformerFactory(kcpfakeclient.NewSimpleClientset(objs...), controller.NoResyncPeriodFunc())
+	addPrefixedBindingsIndexers(kubeInformers, apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix)
+	informers := []cache.SharedIndexInformer{
+		kubeInformers.Rbac().V1().Roles().Informer(),
+		kubeInformers.Rbac().V1().RoleBindings().Informer(),
+		kubeInformers.Rbac().V1().ClusterRoles().Informer(),
+		kubeInformers.Rbac().V1().ClusterRoleBindings().Informer(),
+	}
+	var syncs []cache.InformerSynced
+	for i := range informers {
+		go informers[i].Run(ctx.Done())
+		syncs = append(syncs, informers[i].HasSynced)
+	}
+	require.True(t, cache.WaitForCacheSync(ctx.Done(), syncs...))
+
+	return kubeInformers
+}
+
+func inCluster[T metav1.Object](clusterName string, obj T) T {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[logicalcluster.AnnotationKey] = clusterName
+	obj.SetAnnotations(annotations)
+	return obj
+}
+
+func withCluster(clusterName string) context.Context {
+	return request.WithCluster(context.Background(), request.Cluster{Name: logicalcluster.New(clusterName)})
+}
+
+func withAuditEvent(ctx context.Context) (context.Context, *auditinternal.Event) {
+	ev := &auditinternal.Event{Level: auditinternal.LevelMetadata}
+	return kaudit.WithAuditContext(ctx, &kaudit.AuditContext{Event: ev}), ev
+}
+
+func TestGetAPIBindingReferenceForAttributes(t *testing.T) {
+	aliasedBinding := newAPIBinding("root:consumer", "aliased", "root:provider", "widgets",
+		apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+	)
+	aliasedBinding.Annotations[MaximalPermissionPolicyGroupAliasesAnnotationKey] = "widgets.alias.io=widgets.example.io, other.alias.io=other.example.io"
+
+	bindings := []interface{}{
+		newAPIBinding("root:consumer", "plain", "root:provider", "gadgets",
+			apisv1alpha1.BoundAPIResource{Group: "gadgets.example.io", Resource: "gadgets"},
+		),
+		newAPIBinding("root:consumer", "plain-v2", "root:provider", "gadgets-v2",
+			apisv1alpha1.BoundAPIResource{Group: "gadgets.example.io", Resource: "gadgets"},
+		),
+		newAPIBinding("root:consumer", "gadgets-scale", "root:provider", "gadgets-scale",
+			apisv1alpha1.BoundAPIResource{Group: "gadgets.example.io", Resource: "gadgets/scale"},
+		),
+		aliasedBinding,
+		newAPIBinding("root:consumer", "all-gadgets", "root:provider", "all-gadgets",
+			apisv1alpha1.BoundAPIResource{Group: "gadgets.example.io", Resource: "*"},
+		),
+		newAPIBinding("root:consumer", "all-doodads", "root:provider", "all-doodads",
+			apisv1alpha1.BoundAPIResource{Group: "doodads.example.io", Resource: "*"},
+		),
+		newAPIBinding("root:consumer", "sprockets", "root:provider", "sprockets",
+			apisv1alpha1.BoundAPIResource{},
+			apisv1alpha1.BoundAPIResource{Group: "sprockets.example.io", Resource: "sprockets"},
+		),
+	}
+	scanIndexer := newIndexer(t, bindings...)
+	boundResourceIndexer := cache.NewIndexer(kcpcache.MetaClusterNamespaceKeyFunc, cache.Indexers{
+		indexers.APIBindingByClusterAndBoundGroupResource: indexers.IndexAPIBindingByClusterAndBoundGroupResource(MaximalPermissionPolicyGroupAliasesAnnotationKey),
+	})
+	for _, obj := range bindings {
+		require.NoError(t, boundResourceIndexer.Add(obj))
+	}
+
+	for _, tt := range []struct {
+		name             string
+		cluster          string
+		group            string
+		resource         string
+		subresource      string
+		wantFound        bool
+		wantExport       string
+		wantGroup        string
+		wantBindingNames []string
+	}{
+		{name: "exact group", cluster: "root:consumer", group: "gadgets.example.io", resource: "gadgets", wantFound: true, wantExport: "gadgets", wantGroup: "gadgets.example.io", wantBindingNames: []string{"plain", "plain-v2"}},
+		{name: "subresource-specific binding", cluster: "root:consumer", group: "gadgets.example.io", resource: "gadgets", subresource: "scale", wantFound: true, wantExport: "gadgets-scale", wantGroup: "gadgets.example.io", wantBindingNames: []string{"gadgets-scale"}},
+		{name: "subresource of parent binding", cluster: "root:consumer", group: "gadgets.example.io", resource: "gadgets", subresource: "status", wantFound: true, wantExport: "gadgets", wantGroup: "gadgets.example.io", wantBindingNames: []string{"plain", "plain-v2"}},
+		{name: "canonical group of aliased binding", cluster: "root:consumer", group: "widgets.example.io", resource: "widgets", wantFound: true, wantExport: "widgets", wantGroup: "widgets.example.io"},
+		{name: "alias group", cluster: "root:consumer", group: "widgets.alias.io", resource: "widgets", wantFound: true, wantExport: "widgets", wantGroup: "widgets.example.io"},
+		{name: "alias of another binding", cluster: "root:consumer", group: "widgets.alias.io", resource: "gadgets"},
+		{name: "wildcard only", cluster: "root:consumer", group: "doodads.example.io", resource: "doodads", wantFound: true, wantExport: "all-doodads", wantGroup: "doodads.example.io", wantBindingNames: []string{"all-doodads"}},
+		{name: "subresource of wildcard", cluster: "root:consumer", group: "doodads.example.io", resource: "doodads", subresource: "status", wantFound: true, wantExport: "all-doodads", wantGroup: "doodads.example.io", wantBindingNames: []string{"all-doodads"}},
+		{name: "exact wins over wildcard", cluster: "root:consumer", group: "gadgets.example.io", resource: "gadgets", wantFound: true, wantExport: "gadgets", wantGroup: "gadgets.example.io", wantBindingNames: []string{"plain", "plain-v2"}},
+		{name: "wildcard next to exact", cluster: "root:consumer", group: "gadgets.example.io", resource: "sprockets", wantFound: true, wantExport: "all-gadgets", wantGroup: "gadgets.example.io", wantBindingNames: []string{"all-gadgets"}},
+		{name: "wildcard of another group", cluster: "root:consumer", group: "widgets.example.io", resource: "sprockets"},
+		{name: "unknown group", cluster: "root:consumer", group: "unknown.example.io", resource: "widgets"},
+		{name: "other cluster", cluster: "root:other", group: "gadgets.example.io", resource: "gadgets"},
+		{name: "empty bound resource", cluster: "root:consumer"},
+		{name: "next to empty bound resource", cluster: "root:consumer", group: "sprockets.example.io", resource: "sprockets", wantFound: true, wantExport: "sprockets", wantGroup: "sprockets.example.io", wantBindingNames: []string{"sprockets"}},
+	} {
+		for indexerName, indexer := range map[string]cache.Indexer{"scan": scanIndexer, "bound resource index": boundResourceIndexer} {
+			indexer := indexer
+			t.Run(tt.name+" with "+indexerName, func(t *testing.T) {
+				attr := &authorizer.AttributesRecord{APIGroup: tt.group, Resource: tt.resource, Subresource: tt.subresource}
+				match, found, err := getAPIBindingReferenceForAttributes(indexer, attr, logicalcluster.New(tt.cluster), 0)
+				require.NoError(t, err)
+				require.Equal(t, tt.wantFound, found)
+				if !tt.wantFound {
+					return
+				}
+				require.Equal(t, tt.wantExport, match.ExportReference.Workspace.ExportName)
+				require.Equal(t, tt.wantGroup, match.Group)
+				if tt.wantBindingNames != nil {
+					require.Equal(t, tt.wantBindingNames, match.MatchingAPIBindingNames)
+				}
+			})
+		}
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerMultipleMatchingAPIBindings(t *testing.T) {
+	var bindings []*apisv1alpha1.APIBinding
+	for _, name := range []string{"widgets-v2", "widgets-v1", "gadgets"} {
+		resource := "widgets"
+		if name == "gadgets" {
+			resource = "gadgets"
+		}
+		bindings = append(bindings, newAPIBinding("root:consumer", name, "root:"+name, name,
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: resource},
+		))
+	}
+	exports := []*apisv1alpha1.APIExport{
+		newAPIExport("root:widgets-v1", "widgets-v1", withLocalPolicy()),
+		newAPIExport("root:widgets-v2", "widgets-v2", withLocalPolicy()),
+	}
+
+	for _, tt := range []struct {
+		name         string
+		resource     string
+		wantExport   string
+		wantBinding  string
+		wantMatching string
+	}{
+		{name: "multiple matching bindings", resource: "widgets", wantExport: "widgets-v1", wantBinding: "widgets-v1", wantMatching: "widgets-v1,widgets-v2"},
+		{name: "single matching binding", resource: "gadgets", wantExport: "gadgets", wantBinding: "gadgets"},
+		{name: "no matching binding", resource: "others"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// repeat with the bindings in different orders to catch nondeterminism
+			for i := 0; i < len(bindings); i++ {
+				rotated := append(append([]*apisv1alpha1.APIBinding{}, bindings[i:]...), bindings[:i]...)
+				a := newTestMaximalPermissionPolicyAuthorizer(t, rotated, exports,
+					&recordingAuthorizer{decision: authorizer.DecisionAllow}, &recordingAuthorizer{decision: authorizer.DecisionAllow},
+				)
+
+				ctx, ev := withAuditEvent(withCluster("root:consumer"))
+				_, _, details, err := a.AuthorizeWithDetails(ctx, &authorizer.AttributesRecord{
+					User:            &user.DefaultInfo{Name: "user-1"},
+					Verb:            "get",
+					APIGroup:        "widgets.example.io",
+					Resource:        tt.resource,
+					ResourceRequest: true,
+				})
+				require.NoError(t, err)
+				require.Equal(t, tt.wantBinding, ev.Annotations[MaximalPermissionPolicyAuditBinding])
+				require.Equal(t, tt.wantMatching, ev.Annotations[MaximalPermissionPolicyAuditMatchingAPIBindings])
+				if tt.wantExport == "" {
+					require.False(t, details.Bound)
+					continue
+				}
+				require.Equal(t, tt.wantExport, details.ExportReference.Workspace.ExportName)
+			}
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerSubresourceBinding(t *testing.T) {
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{
+			newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+				apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+			),
+			newAPIBinding("root:consumer", "widgets-scale", "root:provider", "widgets-scale",
+				apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets/scale"},
+			),
+		},
+		[]*apisv1alpha1.APIExport{
+			newAPIExport("root:provider", "widgets", withLocalPolicy()),
+			newAPIExport("root:provider", "widgets-scale", &apisv1alpha1.MaximalPermissionPolicy{Verbs: []apisv1alpha1.ResourceVerbsPolicy{
+				{Group: "widgets.example.io", Resource: "widgets/scale", Verbs: []string{"get"}},
+			}}),
+		},
+		&recordingAuthorizer{decision: authorizer.DecisionAllow},
+		&recordingAuthorizer{decision: authorizer.DecisionAllow},
+	)
+
+	for _, tt := range []struct {
+		name              string
+		verb              string
+		subresource       string
+		wantDecision      authorizer.Decision
+		wantExport        string
+		wantBoundResource string
+	}{
+		{name: "parent resource", verb: "update", wantDecision: authorizer.DecisionAllow, wantExport: "widgets", wantBoundResource: "widgets"},
+		{name: "scale allowed by the stricter policy", verb: "get", subresource: "scale", wantDecision: authorizer.DecisionAllow, wantExport: "widgets-scale", wantBoundResource: "widgets/scale"},
+		{name: "scale denied by the stricter policy", verb: "update", subresource: "scale", wantDecision: authorizer.DecisionNoOpinion, wantExport: "widgets-scale", wantBoundResource: "widgets/scale"},
+		{name: "subresource without specific binding falls back", verb: "update", subresource: "status", wantDecision: authorizer.DecisionAllow, wantExport: "widgets", wantBoundResource: "widgets"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dec, _, details, err := a.AuthorizeWithDetails(withCluster("root:consumer"), &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            tt.verb,
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				Subresource:     tt.subresource,
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, tt.wantExport, details.ExportReference.Workspace.ExportName)
+			require.Equal(t, tt.wantBoundResource, details.BoundResource.Resource)
+		})
+	}
+}
+
+func TestResolveExportCluster(t *testing.T) {
+	exports := []interface{}{
+		newAPIExport("root:provider", "widgets", withLocalPolicy()),
+		newAPIExport("root:other", "gadgets", nil),
+	}
+	scanIndexer := newIndexer(t, exports...)
+	compositeIndexer := cache.NewIndexer(kcpcache.MetaClusterNamespaceKeyFunc, cache.Indexers{indexers.APIExportByClusterAndName: indexers.IndexAPIExportByClusterAndName})
+	for _, obj := range exports {
+		require.NoError(t, compositeIndexer.Add(obj))
+	}
+
+	for _, tt := range []struct {
+		name        string
+		ref         apisv1alpha1.ExportReference
+		wantCluster logicalcluster.Name
+		wantFound   bool
+		wantErr     error
+	}{
+		{
+			name:        "export found",
+			ref:         apisv1alpha1.ExportReference{Workspace: &apisv1alpha1.WorkspaceExportReference{Path: "root:provider", ExportName: "widgets"}},
+			wantCluster: logicalcluster.New("root:provider"),
+			wantFound:   true,
+		},
+		{
+			name: "export not found in the referenced workspace",
+			ref:  apisv1alpha1.ExportReference{Workspace: &apisv1alpha1.WorkspaceExportReference{Path: "root:provider", ExportName: "gadgets"}},
+		},
+		{
+			name: "workspace without exports",
+			ref:  apisv1alpha1.ExportReference{Workspace: &apisv1alpha1.WorkspaceExportReference{Path: "root:consumer", ExportName: "widgets"}},
+		},
+		{
+			name:    "reference without workspace",
+			ref:     apisv1alpha1.ExportReference{},
+			wantErr: ErrUnsupportedExportReference,
+		},
+	} {
+		for indexerName, indexer := range map[string]cache.Indexer{"scan": scanIndexer, "composite index": compositeIndexer} {
+			indexer := indexer
+			t.Run(tt.name+" with "+indexerName, func(t *testing.T) {
+				cluster, found, err := ResolveExportCluster(indexer, &tt.ref)
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Equal(t, tt.wantFound, found)
+				require.Equal(t, tt.wantCluster, cluster)
+			})
+		}
+	}
+}
+
+func TestGetAPIExportByReference(t *testing.T) {
+	apiExport := newAPIExport("root:provider", "widgets", withLocalPolicy())
+	apiExport.UID = "uid-1"
+	exports := []interface{}{
+		apiExport,
+		newAPIExport("2x7kd", "widgets", nil),
+	}
+	scanIndexer := newIndexer(t, exports...)
+	compositeIndexer := cache.NewIndexer(kcpcache.MetaClusterNamespaceKeyFunc, cache.Indexers{
+		indexers.APIExportByClusterAndName: indexers.IndexAPIExportByClusterAndName,
+		indexers.ByLogicalCluster:          indexers.IndexByLogicalCluster,
+	})
+	for _, obj := range exports {
+		require.NoError(t, compositeIndexer.Add(obj))
+	}
+
+	for _, tt := range []struct {
+		name              string
+		path              string
+		exportClusterName string
+		uid               types.UID
+		wantCluster       string
+	}{
+		{name: "by path", path: "root:provider", wantCluster: "root:provider"},
+		{name: "by path and UID", path: "root:provider", uid: "uid-1", wantCluster: "root:provider"},
+		{name: "by path with UID mismatch", path: "root:provider", uid: "uid-0"},
+		{name: "by path without export", path: "root:org:ws"},
+		{name: "by resolved cluster name", path: "root:org:ws", exportClusterName: "2x7kd", wantCluster: "2x7kd"},
+		{name: "resolved cluster name preferred over path", path: "root:provider", exportClusterName: "2x7kd", wantCluster: "2x7kd"},
+		{name: "resolved cluster name without export", path: "root:provider", exportClusterName: "root:other"},
+		{name: "by resolved cluster name with UID mismatch", path: "root:provider", exportClusterName: "root:provider", uid: "uid-0"},
+	} {
+		for indexerName, indexer := range map[string]cache.Indexer{"scan": scanIndexer, "composite index": compositeIndexer} {
+			indexer := indexer
+			t.Run(tt.name+" with "+indexerName, func(t *testing.T) {
+				ref := &apisv1alpha1.ExportReference{Workspace: &apisv1alpha1.WorkspaceExportReference{Path: tt.path, ExportName: "widgets", UID: tt.uid}}
+				apiExport, found, err := getAPIExportByReference(indexer, ref, logicalcluster.New(tt.exportClusterName))
+				require.NoError(t, err)
+				require.Equal(t, tt.wantCluster != "", found)
+				if found {
+					require.Equal(t, tt.wantCluster, logicalcluster.From(apiExport).String())
+				}
+			})
+		}
+	}
+}
+
+func FuzzGetAPIExportByReference(f *testing.F) {
+	for _, seed := range [][5]string{
+		{"root:provider", "widgets", "", "root:provider", "widgets"},
+		{"", "widgets", "", "", "widgets"},
+		{"", "", "", "root:provider", "widgets"},
+		{"//root///provider//", "widgets", "", "//root///provider//", "widgets"},
+		{"root:provider|widgets", "", "", "root:provider", "widgets|"},
+		{"root:provider", "widgets", "2x7kd", "2x7kd", "widgets"},
+		{"root:anbieter", "gerät-☃", "", "root:anbieter", "gerät-☃"},
+		{"root:提供者", "widgets", "", "root:提供者", "widgets"},
+	} {
+		f.Add(seed[0], seed[1], seed[2], seed[3], seed[4])
+	}
+
+	f.Fuzz(func(t *testing.T, path, exportName, exportClusterName, cluster, name string) {
+		apiExport := newAPIExport(cluster, name, nil)
+		scanIndexer := newIndexer(t, apiExport)
+		compositeIndexer := cache.NewIndexer(kcpcache.MetaClusterNamespaceKeyFunc, cache.Indexers{
+			indexers.APIExportByClusterAndName: indexers.IndexAPIExportByClusterAndName,
+			indexers.ByLogicalCluster:          indexers.IndexByLogicalCluster,
+		})
+		require.NoError(t, compositeIndexer.Add(apiExport))
+
+		lookupCluster := path
+		if exportClusterName != "" {
+			lookupCluster = exportClusterName
+		}
+		wantFound := lookupCluster == cluster && exportName == name
+
+		ref := &apisv1alpha1.ExportReference{Workspace: &apisv1alpha1.WorkspaceExportReference{Path: path, ExportName: exportName}}
+		for indexerName, indexer := range map[string]cache.Indexer{"scan": scanIndexer, "composite index": compositeIndexer} {
+			got, found, err := getAPIExportByReference(indexer, ref, logicalcluster.New(exportClusterName))
+			require.NoError(t, err, indexerName)
+			require.Equal(t, wantFound, found, "unexpected result with %s", indexerName)
+			if found {
+				require.Same(t, apiExport, got, indexerName)
+			}
+		}
+	})
+}
+
+func TestMaximalPermissionPolicyAuthorizerResolvedExportClusterName(t *testing.T) {
+	binding := newAPIBinding("root:consumer", "widgets", "root:org:provider", "widgets",
+		apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+	)
+	binding.Status.APIExportClusterName = "2x7kd"
+	inner := &recordingAuthorizer{decision: authorizer.DecisionNoOpinion}
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{binding},
+		[]*apisv1alpha1.APIExport{newAPIExport("2x7kd", "widgets", withLocalPolicy())},
+		inner, &recordingAuthorizer{decision: authorizer.DecisionAllow},
+	)
+
+	dec, reason, err := a.Authorize(withCluster("root:consumer"), &authorizer.AttributesRecord{
+		User:            &user.DefaultInfo{Name: "user-1"},
+		Verb:            "get",
+		APIGroup:        "widgets.example.io",
+		Resource:        "widgets",
+		ResourceRequest: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, authorizer.DecisionNoOpinion, dec)
+	require.Equal(t, ReasonPolicyDenied, reason, "expected the API export to be found by the resolved cluster name")
+	require.NotNil(t, inner.recordedAttributes)
+}
+
+func TestMaximalPermissionPolicyAuthorizerUnsupportedExportReference(t *testing.T) {
+	binding := newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+		apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+	)
+	binding.Spec.Reference = apisv1alpha1.ExportReference{}
+	delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{binding},
+		[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+		&recordingAuthorizer{decision: authorizer.DecisionAllow}, delegate,
+	)
+
+	ctx, ev := withAuditEvent(withCluster("root:consumer"))
+	dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+		User:            &user.DefaultInfo{Name: "user-1"},
+		Verb:            "get",
+		APIGroup:        "widgets.example.io",
+		Resource:        "widgets",
+		ResourceRequest: true,
+	})
+	require.ErrorIs(t, err, ErrUnsupportedExportReference)
+	require.Equal(t, authorizer.DecisionNoOpinion, dec)
+	require.Equal(t, DecisionNoOpinion, ev.Annotations[MaximalPermissionPolicyAuditDecision])
+	require.Contains(t, ev.Annotations[MaximalPermissionPolicyAuditReason], "unsupported export reference")
+	require.Nil(t, delegate.recordedAttributes, "expected the delegate not to be called")
+}
+
+func TestMaximalPermissionPolicyAuthorizerGroupAlias(t *testing.T) {
+	binding := newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+		apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+	)
+	binding.Annotations[MaximalPermissionPolicyGroupAliasesAnnotationKey] = "widgets.alias.io=widgets.example.io"
+
+	inner := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+	delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{binding},
+		[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+		inner, delegate,
+	)
+
+	attr := &authorizer.AttributesRecord{
+		User:            &user.DefaultInfo{Name: "user-1", Groups: []string{"group-1"}},
+		Verb:            "get",
+		APIGroup:        "widgets.alias.io",
+		Resource:        "widgets",
+		ResourceRequest: true,
+	}
+	dec, _, err := a.Authorize(withCluster("root:consumer"), attr)
+	require.NoError(t, err)
+	require.Equal(t, authorizer.DecisionAllow, dec)
+
+	require.NotNil(t, inner.recordedAttributes, "expected the maximal permission policy to be evaluated")
+	requireOnlyUserDiffers(t, attr, "widgets.example.io", inner.recordedAttributes)
+	require.Equal(t, apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix+"user-1", inner.recordedAttributes.GetUser().GetName())
+	require.Equal(t, []string{apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "group-1"}, inner.recordedAttributes.GetUser().GetGroups())
+
+	require.Equal(t, "widgets.alias.io", delegate.recordedAttributes.GetAPIGroup(), "expected the delegate to see the requested group")
+}
+
+// requireOnlyUserDiffers fails if got differs from want in anything but the user,
+// and the API group which is expected to be the canonical group of the bound resource.
+func requireOnlyUserDiffers(t *testing.T, want authorizer.Attributes, wantGroup string, got authorizer.Attributes) {
+	t.Helper()
+	require.NoError(t, onlyUserDiffers(want, wantGroup, got))
+}
+
+func onlyUserDiffers(want authorizer.Attributes, wantGroup string, got authorizer.Attributes) error {
+	for _, f := range []struct {
+		field     string
+		want, got interface{}
+	}{
+		{"verb", want.GetVerb(), got.GetVerb()},
+		{"namespace", want.GetNamespace(), got.GetNamespace()},
+		{"apiGroup", wantGroup, got.GetAPIGroup()},
+		{"apiVersion", want.GetAPIVersion(), got.GetAPIVersion()},
+		{"resource", want.GetResource(), got.GetResource()},
+		{"subresource", want.GetSubresource(), got.GetSubresource()},
+		{"name", want.GetName(), got.GetName()},
+		{"resourceRequest", want.IsResourceRequest(), got.IsResourceRequest()},
+		{"path", want.GetPath(), got.GetPath()},
+	} {
+		if f.want != f.got {
+			return fmt.Errorf("attribute %s differs: want %v, got %v", f.field, f.want, f.got)
+		}
+	}
+	return nil
+}
+
+func TestOnlyUserDiffers(t *testing.T) {
+	base := authorizer.AttributesRecord{
+		User:            &user.DefaultInfo{Name: "user-1"},
+		Verb:            "update",
+		Namespace:       "default",
+		APIGroup:        "widgets.example.io",
+		APIVersion:      "v1",
+		Resource:        "widgets",
+		Subresource:     "status",
+		Name:            "foo",
+		ResourceRequest: true,
+		Path:            "/apis/widgets.example.io/v1/namespaces/default/widgets/foo/status",
+	}
+
+	prefixed := base
+	prefixed.User = &user.DefaultInfo{Name: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "user-1"}
+	require.NoError(t, onlyUserDiffers(base, base.APIGroup, prefixed))
+
+	for name, mutate := range map[string]func(r *authorizer.AttributesRecord){
+		"verb":            func(r *authorizer.AttributesRecord) { r.Verb = "get" },
+		"namespace":       func(r *authorizer.AttributesRecord) { r.Namespace = "other" },
+		"apiGroup":        func(r *authorizer.AttributesRecord) { r.APIGroup = "other.example.io" },
+		"apiVersion":      func(r *authorizer.AttributesRecord) { r.APIVersion = "v2" },
+		"resource":        func(r *authorizer.AttributesRecord) { r.Resource = "gadgets" },
+		"subresource":     func(r *authorizer.AttributesRecord) { r.Subresource = "" },
+		"name":            func(r *authorizer.AttributesRecord) { r.Name = "bar" },
+		"resourceRequest": func(r *authorizer.AttributesRecord) { r.ResourceRequest = false },
+		"path":            func(r *authorizer.AttributesRecord) { r.Path = "/" },
+	} {
+		t.Run(name, func(t *testing.T) {
+			mutated := prefixed
+			mutate(&mutated)
+			require.Error(t, onlyUserDiffers(base, base.APIGroup, mutated))
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerOnlyPrefixesUser(t *testing.T) {
+	inner := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+	delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		)},
+		[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+		inner, delegate,
+	)
+
+	attr := &authorizer.AttributesRecord{
+		User:            &user.DefaultInfo{Name: "user-1", Groups: []string{"group-1"}},
+		Verb:            "update",
+		Namespace:       "default",
+		APIGroup:        "widgets.example.io",
+		APIVersion:      "v1",
+		Resource:        "widgets",
+		Subresource:     "status",
+		Name:            "foo",
+		ResourceRequest: true,
+		Path:            "/apis/widgets.example.io/v1/namespaces/default/widgets/foo/status",
+	}
+	_, _, err := a.Authorize(withCluster("root:consumer"), attr)
+	require.NoError(t, err)
+
+	require.NotNil(t, inner.recordedAttributes, "expected the maximal permission policy to be evaluated")
+	requireOnlyUserDiffers(t, attr, attr.APIGroup, inner.recordedAttributes)
+	require.Equal(t, apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix+"user-1", inner.recordedAttributes.GetUser().GetName())
+
+	require.Equal(t, attr, delegate.recordedAttributes, "expected the delegate to see the unmodified attributes")
+}
+
+// customUserInfo is a user.Info implementation other than *user.DefaultInfo, as some authenticators return.
+type customUserInfo struct {
+	name   string
+	groups []string
+}
+
+func (u customUserInfo) GetName() string     { return u.name }
+func (u customUserInfo) GetUID() string      { return "uid-1" }
+func (u customUserInfo) GetGroups() []string { return u.groups }
+func (u customUserInfo) GetExtra() map[string][]string {
+	return map[string][]string{"scopes": {"scope-1"}}
+}
+
+func TestMaximalPermissionPolicyAuthorizerCustomUserInfo(t *testing.T) {
+	inner := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+	delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		)},
+		[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+		inner, delegate,
+	)
+
+	userInfo := customUserInfo{name: "user-1", groups: []string{"group-1", "group-2"}}
+	dec, _, err := a.Authorize(withCluster("root:consumer"), &authorizer.AttributesRecord{
+		User:            userInfo,
+		Verb:            "get",
+		APIGroup:        "widgets.example.io",
+		Resource:        "widgets",
+		ResourceRequest: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, authorizer.DecisionAllow, dec)
+
+	require.NotNil(t, inner.recordedAttributes, "expected the maximal permission policy to be evaluated")
+	prefix := apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix
+	require.Equal(t, &user.DefaultInfo{
+		Name:   prefix + "user-1",
+		UID:    "uid-1",
+		Groups: []string{prefix + "group-1", prefix + "group-2"},
+		Extra:  map[string][]string{"scopes": {"scope-1"}},
+	}, inner.recordedAttributes.GetUser())
+	require.Equal(t, userInfo, delegate.recordedAttributes.GetUser(), "expected the delegate to see the unmodified user")
+}
+
+type recordingWarnings []string
+
+func (r *recordingWarnings) AddWarning(agent, text string) {
+	*r = append(*r, text)
+}
+
+func TestMaximalPermissionPolicyAuthorizerDenialWarnings(t *testing.T) {
+	for _, tt := range []struct {
+		verb        string
+		enabled     bool
+		wantWarning bool
+	}{
+		{verb: "create", enabled: true, wantWarning: true},
+		{verb: "update", enabled: true, wantWarning: true},
+		{verb: "delete", enabled: true, wantWarning: true},
+		{verb: "get", enabled: true},
+		{verb: "list", enabled: true},
+		{verb: "watch", enabled: true},
+		{verb: "create"},
+	} {
+		t.Run(fmt.Sprintf("%s enabled=%v", tt.verb, tt.enabled), func(t *testing.T) {
+			var opts []MaximalPermissionPolicyAuthorizerOption
+			if tt.enabled {
+				opts = append(opts, WithDenialWarnings())
+			}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				&recordingAuthorizer{decision: authorizer.DecisionNoOpinion, reason: "no RBAC policy matched"},
+				&recordingAuthorizer{decision: authorizer.DecisionAllow},
+				opts...,
+			)
+
+			var warnings recordingWarnings
+			ctx := warning.WithWarningRecorder(withCluster("root:consumer"), &warnings)
+			dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            tt.verb,
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, authorizer.DecisionNoOpinion, dec)
+
+			if !tt.wantWarning {
+				require.Empty(t, warnings)
+				return
+			}
+			require.Equal(t, recordingWarnings{`access not permitted by maximal permission policy of API export "widgets", path: "root:provider": no RBAC policy matched`}, warnings)
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerDenialDeduplication(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		)},
+		[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+		&recordingAuthorizer{decision: authorizer.DecisionNoOpinion, reason: "no RBAC policy matched"},
+		&recordingAuthorizer{decision: authorizer.DecisionAllow},
+		WithDenialWarnings(),
+		WithMetrics(metrics.NewKubeRegistry()),
+	)
+	a.denialDeduplicator = newDenialDeduplicator(time.Minute, fakeClock)
+
+	for _, step := range []struct {
+		name           string
+		user           string
+		verb           string
+		advance        time.Duration
+		wantWarning    bool
+		wantSuppressed bool
+	}{
+		{name: "first denial", user: "user-1", verb: "create", wantWarning: true},
+		{name: "duplicate within window", user: "user-1", verb: "create", advance: 30 * time.Second, wantSuppressed: true},
+		{name: "other verb", user: "user-1", verb: "update", wantWarning: true},
+		{name: "other user", user: "user-2", verb: "create", wantWarning: true},
+		{name: "duplicate after window", user: "user-1", verb: "create", advance: 31 * time.Second, wantWarning: true},
+		{name: "duplicate within new window", user: "user-1", verb: "create", wantSuppressed: true},
+	} {
+		fakeClock.Step(step.advance)
+
+		suppressedBefore, err := testutil.GetCounterMetricValue(suppressedDenials)
+		require.NoError(t, err)
+		deniedBefore, err := testutil.GetCounterMetricValue(denials.WithLabelValues("root:consumer"))
+		require.NoError(t, err)
+
+		var warnings recordingWarnings
+		ctx := warning.WithWarningRecorder(withCluster("root:consumer"), &warnings)
+		dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+			User:            &user.DefaultInfo{Name: step.user},
+			Verb:            step.verb,
+			APIGroup:        "widgets.example.io",
+			Resource:        "widgets",
+			ResourceRequest: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, authorizer.DecisionNoOpinion, dec, step.name)
+
+		if step.wantWarning {
+			require.Len(t, warnings, 1, step.name)
+		} else {
+			require.Empty(t, warnings, step.name)
+		}
+
+		suppressedAfter, err := testutil.GetCounterMetricValue(suppressedDenials)
+		require.NoError(t, err)
+		deniedAfter, err := testutil.GetCounterMetricValue(denials.WithLabelValues("root:consumer"))
+		require.NoError(t, err)
+		require.Equal(t, deniedBefore+1, deniedAfter, "expected every denial to be counted: %s", step.name)
+		if step.wantSuppressed {
+			require.Equal(t, suppressedBefore+1, suppressedAfter, step.name)
+		} else {
+			require.Equal(t, suppressedBefore, suppressedAfter, step.name)
+		}
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerMigratedGroup(t *testing.T) {
+	migratedExport := newAPIExport("root:provider", "widgets", withLocalPolicy())
+	migratedExport.Annotations[MaximalPermissionPolicyOldGroupsAnnotationKey] = "widgets.old.io=widgets.example.io"
+
+	for _, tt := range []struct {
+		name      string
+		export    *apisv1alpha1.APIExport
+		wantGroup string
+	}{
+		{name: "migrated export", export: migratedExport, wantGroup: "widgets.example.io"},
+		{name: "export without migration", export: newAPIExport("root:provider", "widgets", withLocalPolicy()), wantGroup: "widgets.old.io"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.old.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{tt.export},
+				inner, delegate,
+			)
+
+			attr := &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        "widgets.old.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			}
+			dec, _, err := a.Authorize(withCluster("root:consumer"), attr)
+			require.NoError(t, err)
+			require.Equal(t, authorizer.DecisionAllow, dec)
+
+			require.NotNil(t, inner.recordedAttributes, "expected the maximal permission policy to be evaluated")
+			requireOnlyUserDiffers(t, attr, tt.wantGroup, inner.recordedAttributes)
+			require.Equal(t, attr, delegate.recordedAttributes)
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerRBACDecision(t *testing.T) {
+	for _, tt := range []struct {
+		name             string
+		innerDecision    authorizer.Decision
+		wantDecision     authorizer.Decision
+		wantReason       string
+		wantAudit        string
+		wantRBACDecision string
+	}{
+		{name: "inner allow", innerDecision: authorizer.DecisionAllow, wantDecision: authorizer.DecisionAllow, wantReason: "delegate", wantAudit: DecisionAllowed, wantRBACDecision: DecisionAllowed},
+		{name: "inner deny", innerDecision: authorizer.DecisionDeny, wantDecision: authorizer.DecisionDeny, wantReason: ReasonPolicyDenied, wantAudit: DecisionDenied, wantRBACDecision: DecisionDenied},
+		{name: "inner no opinion", innerDecision: authorizer.DecisionNoOpinion, wantDecision: authorizer.DecisionNoOpinion, wantReason: ReasonPolicyDenied, wantAudit: DecisionNoOpinion, wantRBACDecision: DecisionNoOpinion},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				&recordingAuthorizer{decision: tt.innerDecision},
+				&recordingAuthorizer{decision: authorizer.DecisionAllow, reason: "delegate"},
+			)
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, reason, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, tt.wantReason, reason)
+			require.Equal(t, tt.wantAudit, ev.Annotations[MaximalPermissionPolicyAuditDecision])
+			require.Equal(t, tt.wantRBACDecision, ev.Annotations[MaximalPermissionPolicyAuditRBACDecision])
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerAllowReasonCode(t *testing.T) {
+	for _, tt := range []struct {
+		name           string
+		group          string
+		terminal       bool
+		wantDecision   authorizer.Decision
+		wantReasonCode string
+	}{
+		{name: "unbound", group: "other.example.io", wantDecision: authorizer.DecisionNoOpinion, wantReasonCode: ReasonNotBound},
+		{name: "no policy", group: "gadgets.example.io", wantDecision: authorizer.DecisionNoOpinion, wantReasonCode: ReasonPolicyNotPresent},
+		{name: "policy allowed", group: "widgets.example.io", wantDecision: authorizer.DecisionNoOpinion, wantReasonCode: ReasonPolicyAllowed},
+		{name: "policy allowed with terminal decision", group: "widgets.example.io", terminal: true, wantDecision: authorizer.DecisionAllow, wantReasonCode: ReasonPolicyAllowed},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{
+					newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+						apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+					),
+					newAPIBinding("root:consumer", "gadgets", "root:provider", "gadgets",
+						apisv1alpha1.BoundAPIResource{Group: "gadgets.example.io", Resource: "widgets"},
+					),
+				},
+				[]*apisv1alpha1.APIExport{
+					newAPIExport("root:provider", "widgets", withLocalPolicy()),
+					newAPIExport("root:provider", "gadgets", nil),
+				},
+				&recordingAuthorizer{decision: authorizer.DecisionAllow},
+				// the maximal permission policy allows regardless of the decision of the delegate
+				&recordingAuthorizer{decision: authorizer.DecisionNoOpinion},
+				WithTerminalDecision(tt.terminal),
+			)
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        tt.group,
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, DecisionAllowed, ev.Annotations[MaximalPermissionPolicyAuditDecision])
+			require.Equal(t, tt.wantReasonCode, ev.Annotations[MaximalPermissionPolicyAuditReasonCode])
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerAPIBindingScanLimit(t *testing.T) {
+	var bindings []*apisv1alpha1.APIBinding
+	for _, name := range []string{"gadgets", "gizmos", "doodads"} {
+		bindings = append(bindings, newAPIBinding("root:consumer", name, "root:provider", name,
+			apisv1alpha1.BoundAPIResource{Group: name + ".example.io", Resource: name},
+		))
+	}
+
+	for _, tt := range []struct {
+		name             string
+		limit            int
+		overflowDecision authorizer.Decision
+		wantDecision     authorizer.Decision
+		wantOverflow     bool
+	}{
+		{name: "no limit", wantDecision: authorizer.DecisionAllow},
+		{name: "limit not exceeded", limit: 3, overflowDecision: authorizer.DecisionDeny, wantDecision: authorizer.DecisionAllow},
+		{name: "limit exceeded with deny", limit: 2, overflowDecision: authorizer.DecisionDeny, wantDecision: authorizer.DecisionDeny, wantOverflow: true},
+		{name: "limit exceeded with no opinion", limit: 2, overflowDecision: authorizer.DecisionNoOpinion, wantDecision: authorizer.DecisionNoOpinion, wantOverflow: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// an indexer without the bound resource index makes the binding matcher scan the API bindings
+			apiBindingIndexer := newIndexer(t, bindings[0], bindings[1], bindings[2])
+			delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			a := newTestMaximalPermissionPolicyAuthorizer(t, nil, nil, nil, delegate,
+				WithAPIBindingScanLimit(tt.limit, tt.overflowDecision),
+				WithMetrics(metrics.NewKubeRegistry()),
+				WithBindingMatcher(BindingMatcherFunc(func(attr authorizer.Attributes, clusterName logicalcluster.Name) (*APIBindingMatch, bool, error) {
+					return getAPIBindingReferenceForAttributes(apiBindingIndexer, attr, clusterName, tt.limit)
+				})),
+			)
+
+			overflowsBefore, err := testutil.GetCounterMetricValue(apiBindingScanOverflows)
+			require.NoError(t, err)
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+
+			overflowsAfter, err := testutil.GetCounterMetricValue(apiBindingScanOverflows)
+			require.NoError(t, err)
+			if !tt.wantOverflow {
+				require.Equal(t, overflowsBefore, overflowsAfter)
+				require.NotNil(t, delegate.recordedAttributes, "expected the delegate to be called")
+				return
+			}
+			require.Equal(t, overflowsBefore+1, overflowsAfter)
+			require.Nil(t, delegate.recordedAttributes, "expected the delegate not to be called")
+			require.Equal(t, DecisionString(tt.overflowDecision), ev.Annotations[MaximalPermissionPolicyAuditDecision])
+			require.Contains(t, ev.Annotations[MaximalPermissionPolicyAuditReason], "API binding scan limit exceeded")
+		})
+	}
+}
+
+func TestGetAPIBindingMatchesForAttributesIndexedScanLimit(t *testing.T) {
+	apiBindingIndexer := cache.NewIndexer(kcpcache.MetaClusterNamespaceKeyFunc, cache.Indexers{
+		indexers.APIBindingByClusterAndBoundGroupResource: indexers.IndexAPIBindingByClusterAndBoundGroupResource(MaximalPermissionPolicyGroupAliasesAnnotationKey),
+	})
+	for _, name := range []string{"widgets-v1", "widgets-v2", "widgets-v3"} {
+		require.NoError(t, apiBindingIndexer.Add(newAPIBinding("root:consumer", name, "root:provider", name,
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		)))
+	}
+	for _, name := range []string{"gadgets", "gizmos", "doodads"} {
+		require.NoError(t, apiBindingIndexer.Add(newAPIBinding("root:consumer", name, "root:provider", name,
+			apisv1alpha1.BoundAPIResource{Group: name + ".example.io", Resource: name},
+		)))
+	}
+
+	for _, tt := range []struct {
+		name         string
+		resource     string
+		limit        int
+		wantMatches  int
+		wantOverflow bool
+	}{
+		{name: "no limit", resource: "widgets", wantMatches: 3},
+		{name: "limit not exceeded", resource: "widgets", limit: 3, wantMatches: 3},
+		{name: "limit exceeded", resource: "widgets", limit: 2, wantOverflow: true},
+		{name: "bindings of other resources not counted", resource: "gadgets", limit: 2, wantMatches: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			group := "widgets.example.io"
+			if tt.resource != "widgets" {
+				group = tt.resource + ".example.io"
+			}
+			attr := &authorizer.AttributesRecord{APIGroup: group, Resource: tt.resource}
+			matches, err := getAPIBindingMatchesForAttributes(apiBindingIndexer, attr, logicalcluster.New("root:consumer"), tt.limit)
+			if tt.wantOverflow {
+				require.ErrorIs(t, err, errAPIBindingScanLimitExceeded)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, matches, tt.wantMatches)
+		})
+	}
+}
+
+// TestMaximalPermissionPolicyAuthorizerWatchResourceVersion verifies that the resourceVersion of a watch
+// request, e.g. the initial list-then-watch with resourceVersion=0, does not influence the decision.
+func TestMaximalPermissionPolicyAuthorizerWatchResourceVersion(t *testing.T) {
+	requestInfoFactory := &request.RequestInfoFactory{
+		APIPrefixes:          sets.NewString("api", "apis"),
+		GrouplessAPIPrefixes: sets.NewString("api"),
+	}
+
+	for _, decision := range []authorizer.Decision{authorizer.DecisionAllow, authorizer.DecisionNoOpinion} {
+		t.Run(DecisionString(decision), func(t *testing.T) {
+			var prefixedAttributes []authorizer.Attributes
+			for _, rv := range []string{"0", "", "4711"} {
+				inner := &recordingAuthorizer{decision: decision}
+				delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+				a := newTestMaximalPermissionPolicyAuthorizer(t,
+					[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+						apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+					)},
+					[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+					inner, delegate,
+				)
+
+				req := httptest.NewRequest("GET", "/apis/widgets.example.io/v1/namespaces/default/widgets?watch=true&resourceVersion="+rv, nil)
+				requestInfo, err := requestInfoFactory.NewRequestInfo(req)
+				require.NoError(t, err)
+				ctx := request.WithRequestInfo(request.WithUser(withCluster("root:consumer"), &user.DefaultInfo{Name: "user-1"}), requestInfo)
+				attr, err := filters.GetAuthorizerAttributes(ctx)
+				require.NoError(t, err)
+				require.Equal(t, "watch", attr.GetVerb(), "resourceVersion %q", rv)
+
+				dec, _, err := a.Authorize(ctx, attr)
+				require.NoError(t, err)
+				require.Equal(t, decision, dec, "resourceVersion %q", rv)
+
+				require.NotNil(t, inner.recordedAttributes, "expected the maximal permission policy to be evaluated for resourceVersion %q", rv)
+				requireOnlyUserDiffers(t, attr, "widgets.example.io", inner.recordedAttributes)
+				prefixedAttributes = append(prefixedAttributes, inner.recordedAttributes)
+			}
+
+			for i := 1; i < len(prefixedAttributes); i++ {
+				require.Equal(t, prefixedAttributes[0], prefixedAttributes[i])
+			}
+		})
+	}
+}
+
+// TestMaximalPermissionPolicyAuthorizerDryRun verifies that a dry-run create is governed exactly like a real create,
+// i.e. the dryRun query parameter does not influence the attributes nor the decision.
+func TestMaximalPermissionPolicyAuthorizerDryRun(t *testing.T) {
+	requestInfoFactory := &request.RequestInfoFactory{
+		APIPrefixes:          sets.NewString("api", "apis"),
+		GrouplessAPIPrefixes: sets.NewString("api"),
+	}
+
+	for _, decision := range []authorizer.Decision{authorizer.DecisionAllow, authorizer.DecisionNoOpinion} {
+		t.Run(DecisionString(decision), func(t *testing.T) {
+			var prefixedAttributes []authorizer.Attributes
+			for _, query := range []string{"", "?dryRun=All"} {
+				inner := &recordingAuthorizer{decision: decision}
+				delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+				a := newTestMaximalPermissionPolicyAuthorizer(t,
+					[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+						apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+					)},
+					[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+					inner, delegate,
+				)
+
+				req := httptest.NewRequest("POST", "/apis/widgets.example.io/v1/namespaces/default/widgets"+query, nil)
+				requestInfo, err := requestInfoFactory.NewRequestInfo(req)
+				require.NoError(t, err)
+				ctx := request.WithRequestInfo(request.WithUser(withCluster("root:consumer"), &user.DefaultInfo{Name: "user-1"}), requestInfo)
+				attr, err := filters.GetAuthorizerAttributes(ctx)
+				require.NoError(t, err)
+				require.Equal(t, "create", attr.GetVerb(), "query %q", query)
+
+				dec, _, err := a.Authorize(ctx, attr)
+				require.NoError(t, err)
+				require.Equal(t, decision, dec, "query %q", query)
+
+				require.NotNil(t, inner.recordedAttributes, "expected the maximal permission policy to be evaluated for query %q", query)
+				requireOnlyUserDiffers(t, attr, "widgets.example.io", inner.recordedAttributes)
+				prefixedAttributes = append(prefixedAttributes, inner.recordedAttributes)
+			}
+
+			require.Equal(t, prefixedAttributes[0], prefixedAttributes[1])
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerConsumerParentRBAC(t *testing.T) {
+	kubeInformers := newKubeInformers(t,
+		inCluster("root:org", newClusterRole("widgets", rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}})),
+		inCluster("root:org", newClusterRoleBinding("widgets", "widgets", rbacv1.Subject{Kind: rbacv1.UserKind, Name: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "user-1"})),
+	)
+
+	for _, tt := range []struct {
+		name         string
+		opts         []MaximalPermissionPolicyAuthorizerOption
+		wantDecision authorizer.Decision
+	}{
+		{name: "parent RBAC ignored by default", wantDecision: authorizer.DecisionNoOpinion},
+		{name: "parent RBAC merged", opts: []MaximalPermissionPolicyAuthorizerOption{WithConsumerParentRBAC()}, wantDecision: authorizer.DecisionAllow},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:org:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				nil,
+				&recordingAuthorizer{decision: authorizer.DecisionAllow},
+				tt.opts...,
+			)
+			a.newAuthorizer = func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+				return rbac.New(newMergedRBACGetters(kubeInformers, clusterName, mergeClusters...))
+			}
+
+			dec, _, err := a.Authorize(withCluster("root:org:consumer"), &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerVirtualResourceMatcher(t *testing.T) {
+	matcher := func(attr authorizer.Attributes) (*apisv1alpha1.ExportReference, bool) {
+		if attr.GetAPIGroup() != "widgets.example.io" || attr.GetResource() != "widgetviews" {
+			return nil, false
+		}
+		return &apisv1alpha1.ExportReference{Workspace: &apisv1alpha1.WorkspaceExportReference{Path: "root:provider", ExportName: "widgets"}}, true
+	}
+
+	for _, tt := range []struct {
+		name          string
+		opts          []MaximalPermissionPolicyAuthorizerOption
+		resource      string
+		inner         authorizer.Decision
+		wantDecision  authorizer.Decision
+		wantReason    string
+		wantEvaluated bool
+		wantVirtual   string
+	}{
+		{name: "virtual resource without matcher", resource: "widgetviews", wantDecision: authorizer.DecisionAllow, wantReason: "delegate"},
+		{name: "virtual resource permitted", opts: []MaximalPermissionPolicyAuthorizerOption{WithVirtualResourceMatcher(matcher)}, resource: "widgetviews", inner: authorizer.DecisionAllow, wantDecision: authorizer.DecisionAllow, wantReason: "delegate", wantEvaluated: true, wantVirtual: "true"},
+		{name: "virtual resource not permitted", opts: []MaximalPermissionPolicyAuthorizerOption{WithVirtualResourceMatcher(matcher)}, resource: "widgetviews", inner: authorizer.DecisionNoOpinion, wantDecision: authorizer.DecisionNoOpinion, wantReason: ReasonPolicyDenied, wantEvaluated: true, wantVirtual: "true"},
+		{name: "unmatched resource", opts: []MaximalPermissionPolicyAuthorizerOption{WithVirtualResourceMatcher(matcher)}, resource: "gadgets", wantDecision: authorizer.DecisionAllow, wantReason: "delegate"},
+		{name: "bound resource takes precedence", opts: []MaximalPermissionPolicyAuthorizerOption{WithVirtualResourceMatcher(matcher)}, resource: "widgets", inner: authorizer.DecisionAllow, wantDecision: authorizer.DecisionAllow, wantReason: "delegate", wantEvaluated: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingAuthorizer{decision: tt.inner}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				inner,
+				&recordingAuthorizer{decision: authorizer.DecisionAllow, reason: "delegate"},
+				tt.opts...,
+			)
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, reason, details, err := a.AuthorizeWithDetails(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        tt.resource,
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, tt.wantReason, reason)
+			require.Equal(t, tt.wantEvaluated, inner.recordedAttributes != nil, "expected the policy evaluated: %v", tt.wantEvaluated)
+			require.Equal(t, tt.wantEvaluated, details.Bound)
+			require.Equal(t, tt.wantVirtual, ev.Annotations[MaximalPermissionPolicyAuditVirtualResource])
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerInheritanceLookup(t *testing.T) {
+	bindings := []*apisv1alpha1.APIBinding{
+		newAPIBinding("root:org", "widgets", "root:provider-1", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		),
+		newAPIBinding("root:org", "gadgets", "root:provider-1", "gadgets",
+			apisv1alpha1.BoundAPIResource{Group: "gadgets.example.io", Resource: "gadgets"},
+		),
+		newAPIBinding("root:org:team", "gadgets", "root:provider-2", "gadgets",
+			apisv1alpha1.BoundAPIResource{Group: "gadgets.example.io", Resource: "gadgets"},
+		),
+	}
+	exports := []*apisv1alpha1.APIExport{
+		newAPIExport("root:provider-1", "widgets", withLocalPolicy()),
+		newAPIExport("root:provider-1", "gadgets", withLocalPolicy()),
+		newAPIExport("root:provider-2", "gadgets", withLocalPolicy()),
+	}
+	parent := func(clusterName logicalcluster.Name) logicalcluster.Name {
+		parent, _ := clusterName.Parent()
+		return parent
+	}
+
+	for _, tt := range []struct {
+		name              string
+		opts              []MaximalPermissionPolicyAuthorizerOption
+		cluster           string
+		group, resource   string
+		wantReason        string
+		wantExportCluster string
+		wantInheritedFrom string
+	}{
+		{name: "parent binding ignored by default", cluster: "root:org:team", group: "widgets.example.io", resource: "widgets", wantReason: "delegate"},
+		{name: "parent binding", opts: []MaximalPermissionPolicyAuthorizerOption{WithInheritanceLookup(parent)}, cluster: "root:org:team", group: "widgets.example.io", resource: "widgets", wantReason: ReasonPolicyDenied, wantExportCluster: "root:provider-1", wantInheritedFrom: "root:org"},
+		{name: "grandparent binding", opts: []MaximalPermissionPolicyAuthorizerOption{WithInheritanceLookup(parent)}, cluster: "root:org:team:dev", group: "widgets.example.io", resource: "widgets", wantReason: ReasonPolicyDenied, wantExportCluster: "root:provider-1", wantInheritedFrom: "root:org"},
+		{name: "parent binding before grandparent binding", opts: []MaximalPermissionPolicyAuthorizerOption{WithInheritanceLookup(parent)}, cluster: "root:org:team:dev", group: "gadgets.example.io", resource: "gadgets", wantReason: ReasonPolicyDenied, wantExportCluster: "root:provider-2", wantInheritedFrom: "root:org:team"},
+		{name: "own binding before parent binding", opts: []MaximalPermissionPolicyAuthorizerOption{WithInheritanceLookup(parent)}, cluster: "root:org:team", group: "gadgets.example.io", resource: "gadgets", wantReason: ReasonPolicyDenied, wantExportCluster: "root:provider-2"},
+		{name: "no binding in any ancestor", opts: []MaximalPermissionPolicyAuthorizerOption{WithInheritanceLookup(parent)}, cluster: "root:org:team:dev", group: "sprockets.example.io", resource: "sprockets", wantReason: "delegate"},
+		{name: "cyclic lookup", opts: []MaximalPermissionPolicyAuthorizerOption{WithInheritanceLookup(func(clusterName logicalcluster.Name) logicalcluster.Name {
+			return logicalcluster.New("root:other")
+		})}, cluster: "root:org:team", group: "widgets.example.io", resource: "widgets", wantReason: "delegate"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestMaximalPermissionPolicyAuthorizer(t, bindings, exports, nil, &recordingAuthorizer{decision: authorizer.DecisionAllow, reason: "delegate"}, tt.opts...)
+			var exportCluster logicalcluster.Name
+			a.newAuthorizer = func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+				exportCluster = clusterName
+				return &recordingAuthorizer{decision: authorizer.DecisionNoOpinion}
+			}
+
+			ctx, ev := withAuditEvent(withCluster(tt.cluster))
+			_, reason, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        tt.group,
+				Resource:        tt.resource,
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantReason, reason)
+			require.Equal(t, tt.wantExportCluster, exportCluster.String())
+			require.Equal(t, tt.wantInheritedFrom, ev.Annotations[MaximalPermissionPolicyAuditInheritedFrom])
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerFallbackClusters(t *testing.T) {
+	kubeInformers := newKubeInformers(t,
+		inCluster("root:bootstrap-1", newClusterRole("widgets-get", rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}})),
+		inCluster("root:bootstrap-2", newClusterRole("widgets-list", rbacv1.PolicyRule{Verbs: []string{"list"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}})),
+		inCluster("root:provider", newClusterRoleBinding("widgets-get", "widgets-get", rbacv1.Subject{Kind: rbacv1.UserKind, Name: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "user-1"})),
+		inCluster("root:provider", newClusterRoleBinding("widgets-list", "widgets-list", rbacv1.Subject{Kind: rbacv1.UserKind, Name: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "user-1"})),
+	)
+
+	for _, tt := range []struct {
+		name         string
+		opts         []MaximalPermissionPolicyAuthorizerOption
+		verb         string
+		wantDecision authorizer.Decision
+	}{
+		{name: "local admin cluster by default", verb: "get", wantDecision: authorizer.DecisionNoOpinion},
+		{name: "role of first fallback cluster", opts: []MaximalPermissionPolicyAuthorizerOption{WithFallbackClusters(logicalcluster.New("root:bootstrap-1"), logicalcluster.New("root:bootstrap-2"))}, verb: "get", wantDecision: authorizer.DecisionAllow},
+		{name: "role of second fallback cluster", opts: []MaximalPermissionPolicyAuthorizerOption{WithFallbackClusters(logicalcluster.New("root:bootstrap-1"), logicalcluster.New("root:bootstrap-2"))}, verb: "list", wantDecision: authorizer.DecisionAllow},
+		{name: "verb granted by no fallback cluster", opts: []MaximalPermissionPolicyAuthorizerOption{WithFallbackClusters(logicalcluster.New("root:bootstrap-1"), logicalcluster.New("root:bootstrap-2"))}, verb: "delete", wantDecision: authorizer.DecisionNoOpinion},
+		{name: "fallback clusters not merged", opts: []MaximalPermissionPolicyAuthorizerOption{WithFallbackClusters(logicalcluster.New("root:bootstrap-1"), logicalcluster.New("root:bootstrap-2")), WithoutAdminClusterRBACMergeByDefault()}, verb: "get", wantDecision: authorizer.DecisionNoOpinion},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				nil,
+				&recordingAuthorizer{decision: authorizer.DecisionAllow},
+				tt.opts...,
+			)
+			a.newAuthorizer = func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+				return rbac.New(newMergedRBACGetters(kubeInformers, clusterName, mergeClusters...))
+			}
+
+			dec, _, err := a.Authorize(withCluster("root:consumer"), &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            tt.verb,
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerVerbFilter(t *testing.T) {
+	for _, tt := range []struct {
+		name           string
+		verb           string
+		wantResolved   bool
+		wantReasonCode string
+	}{
+		{name: "read verb skips the policy", verb: "get", wantReasonCode: ReasonVerbNotEvaluated},
+		{name: "watch skips the policy", verb: "watch", wantReasonCode: ReasonVerbNotEvaluated},
+		{name: "write verb is evaluated", verb: "update", wantResolved: true, wantReasonCode: ReasonPolicyAllowed},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				inner, delegate,
+				WithVerbFilter(func(verb string) bool {
+					return !sets.NewString("get", "list", "watch").Has(verb)
+				}),
+			)
+			resolved := false
+			getAPIExportByReference := a.getAPIExportByReference
+			a.getAPIExportByReference = func(exportRef *apisv1alpha1.ExportReference, exportClusterName logicalcluster.Name) (*apisv1alpha1.APIExport, bool, error) {
+				resolved = true
+				return getAPIExportByReference(exportRef, exportClusterName)
+			}
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            tt.verb,
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, authorizer.DecisionAllow, dec)
+			require.Equal(t, tt.wantResolved, resolved)
+			require.Equal(t, tt.wantResolved, inner.recordedAttributes != nil, "unexpected RBAC evaluation")
+			require.NotNil(t, delegate.recordedAttributes, "expected the request to be delegated")
+			require.Equal(t, tt.wantReasonCode, ev.Annotations[MaximalPermissionPolicyAuditReasonCode])
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerWithoutAdminClusterRBACMerge(t *testing.T) {
+	kubeInformers := newKubeInformers(t,
+		inCluster(genericcontrolplane.LocalAdminCluster.String(), newClusterRole("widgets", rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}})),
+		inCluster(genericcontrolplane.LocalAdminCluster.String(), newClusterRoleBinding("widgets", "widgets", rbacv1.Subject{Kind: rbacv1.UserKind, Name: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "user-1"})),
+	)
+
+	for _, tt := range []struct {
+		name                string
+		override            bool
+		withoutMergeDefault bool
+		exportMerge         string
+		wantDecision        authorizer.Decision
+		wantAnnotated       bool
+	}{
+		{name: "admin cluster RBAC merged by default", wantDecision: authorizer.DecisionAllow},
+		{name: "admin cluster RBAC merge disabled by context", override: true, wantDecision: authorizer.DecisionNoOpinion, wantAnnotated: true},
+		{name: "admin cluster RBAC merge disabled by API export", exportMerge: "false", wantDecision: authorizer.DecisionNoOpinion, wantAnnotated: true},
+		{name: "admin cluster RBAC merge disabled by default", withoutMergeDefault: true, wantDecision: authorizer.DecisionNoOpinion, wantAnnotated: true},
+		{name: "admin cluster RBAC merge enabled by API export", withoutMergeDefault: true, exportMerge: "true", wantDecision: authorizer.DecisionAllow},
+		{name: "invalid API export override ignored", exportMerge: "no", wantDecision: authorizer.DecisionAllow},
+		{name: "context takes precedence over API export", override: true, exportMerge: "true", wantDecision: authorizer.DecisionNoOpinion, wantAnnotated: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			apiExport := newAPIExport("root:provider", "widgets", withLocalPolicy())
+			if tt.exportMerge != "" {
+				apiExport.Annotations[MaximalPermissionPolicyAdminClusterMergeAnnotationKey] = tt.exportMerge
+			}
+			var opts []MaximalPermissionPolicyAuthorizerOption
+			if tt.withoutMergeDefault {
+				opts = append(opts, WithoutAdminClusterRBACMergeByDefault())
+			}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{apiExport},
+				nil,
+				&recordingAuthorizer{decision: authorizer.DecisionAllow},
+				opts...,
+			)
+			a.newAuthorizer = func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+				return rbac.New(newMergedRBACGetters(kubeInformers, clusterName, mergeClusters...))
+			}
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			if tt.override {
+				ctx = WithoutAdminClusterRBACMerge(ctx)
+			}
+			dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+
+			annotation, found := ev.Annotations[MaximalPermissionPolicyAuditAdminClusterMerge]
+			require.Equal(t, tt.wantAnnotated, found)
+			if tt.wantAnnotated {
+				require.Equal(t, "disabled", annotation)
+			}
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerBindingMatcher(t *testing.T) {
+	// labelMatcher mimics an experimental binding scheme binding all resources of a labeled cluster to one export.
+	labelMatcher := BindingMatcherFunc(func(attr authorizer.Attributes, clusterName logicalcluster.Name) (*APIBindingMatch, bool, error) {
+		switch clusterName {
+		case logicalcluster.New("root:labeled"):
+			return &APIBindingMatch{ExportReference: &apisv1alpha1.ExportReference{
+				Workspace: &apisv1alpha1.WorkspaceExportReference{Path: "root:provider", ExportName: "widgets"},
+			}}, true, nil
+		case logicalcluster.New("root:broken"):
+			return nil, false, fmt.Errorf("label selector unavailable")
+		}
+		return nil, false, nil
+	})
+
+	for _, tt := range []struct {
+		name         string
+		cluster      string
+		wantDecision authorizer.Decision
+		wantEvaluate bool
+		wantDelegate bool
+		wantErr      bool
+	}{
+		{name: "bound by custom matcher", cluster: "root:labeled", wantDecision: authorizer.DecisionNoOpinion, wantEvaluate: true},
+		{name: "not bound by custom matcher", cluster: "root:consumer", wantDecision: authorizer.DecisionAllow, wantDelegate: true},
+		{name: "custom matcher error", cluster: "root:broken", wantDecision: authorizer.DecisionNoOpinion, wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingAuthorizer{decision: authorizer.DecisionNoOpinion}
+			delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				// the default matcher would bind the resource in any of the clusters
+				[]*apisv1alpha1.APIBinding{
+					newAPIBinding("root:consumer", "widgets", "root:provider", "widgets", apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"}),
+					newAPIBinding("root:broken", "widgets", "root:provider", "widgets", apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"}),
+				},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				inner, delegate,
+				WithBindingMatcher(labelMatcher),
+			)
+
+			attr := &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			}
+			dec, _, err := a.Authorize(withCluster(tt.cluster), attr)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantDecision, dec)
+
+			if tt.wantEvaluate {
+				require.NotNil(t, inner.recordedAttributes, "expected the maximal permission policy to be evaluated")
+				requireOnlyUserDiffers(t, attr, "widgets.example.io", inner.recordedAttributes)
+			} else {
+				require.Nil(t, inner.recordedAttributes, "expected the maximal permission policy not to be evaluated")
+			}
+			if tt.wantDelegate {
+				require.Equal(t, attr, delegate.recordedAttributes)
+			} else {
+				require.Nil(t, delegate.recordedAttributes, "expected the delegate not to be called")
+			}
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerWithDetails(t *testing.T) {
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{
+			newAPIBinding("root:consumer", "gadgets", "root:provider", "gadgets", apisv1alpha1.BoundAPIResource{Group: "gadgets.example.io", Resource: "gadgets"}),
+			newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+				apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "sprockets"},
+				apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+			),
+		},
+		[]*apisv1alpha1.APIExport{
+			newAPIExport("root:provider", "widgets", withLocalPolicy()),
+			newAPIExport("root:provider", "gadgets", nil),
+		},
+		&recordingAuthorizer{decision: authorizer.DecisionAllow},
+		&recordingAuthorizer{decision: authorizer.DecisionAllow},
+	)
+
+	for _, tt := range []struct {
+		name        string
+		group       string
+		resource    string
+		wantDetails *MaximalPermissionPolicyDecisionDetails
+	}{
+		{
+			name:     "bound resource",
+			group:    "widgets.example.io",
+			resource: "widgets",
+			wantDetails: &MaximalPermissionPolicyDecisionDetails{
+				Bound:            true,
+				APIBindingName:   "widgets",
+				BoundResource:    &apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				ExportReference:  &apisv1alpha1.ExportReference{Workspace: &apisv1alpha1.WorkspaceExportReference{Path: "root:provider", ExportName: "widgets"}},
+				PolicyApplicable: true,
+				Delegated:        true,
+			},
+		},
+		{
+			name:     "bound resource without policy",
+			group:    "gadgets.example.io",
+			resource: "gadgets",
+			wantDetails: &MaximalPermissionPolicyDecisionDetails{
+				Bound:           true,
+				APIBindingName:  "gadgets",
+				BoundResource:   &apisv1alpha1.BoundAPIResource{Group: "gadgets.example.io", Resource: "gadgets"},
+				ExportReference: &apisv1alpha1.ExportReference{Workspace: &apisv1alpha1.WorkspaceExportReference{Path: "root:provider", ExportName: "gadgets"}},
+				Delegated:       true,
+			},
+		},
+		{name: "unbound resource", group: "widgets.example.io", resource: "doodads", wantDetails: &MaximalPermissionPolicyDecisionDetails{Delegated: true}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dec, _, details, err := a.AuthorizeWithDetails(withCluster("root:consumer"), &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        tt.group,
+				Resource:        tt.resource,
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, authorizer.DecisionAllow, dec)
+			require.Equal(t, tt.wantDetails, details)
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerCertificateGroups(t *testing.T) {
+	// organizational units of client certificates become groups verbatim, including commas, spaces and other special characters.
+	certGroups := []string{"Platform Team", "Dev, Ops", "ou=infra+sre", "Ünïcode/Group:1"}
+
+	for _, group := range certGroups {
+		t.Run(group, func(t *testing.T) {
+			kubeInformers := newKubeInformers(t,
+				inCluster("root:provider", newClusterRole("widgets", rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}})),
+				inCluster("root:provider", newClusterRoleBinding("widgets", "widgets", rbacv1.Subject{Kind: rbacv1.GroupKind, Name: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + group})),
+			)
+
+			var recordedAttributes authorizer.Attributes
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				nil,
+				&recordingAuthorizer{decision: authorizer.DecisionAllow},
+			)
+			a.newAuthorizer = func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+				inner := rbac.New(newMergedRBACGetters(kubeInformers, clusterName, mergeClusters...))
+				return authorizer.AuthorizerFunc(func(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+					recordedAttributes = attr
+					return inner.Authorize(ctx, attr)
+				})
+			}
+
+			for _, tt := range []struct {
+				name         string
+				groups       []string
+				wantDecision authorizer.Decision
+			}{
+				{name: "exact group", groups: []string{group}, wantDecision: authorizer.DecisionAllow},
+				{name: "group among others", groups: append([]string{"other"}, certGroups...), wantDecision: authorizer.DecisionAllow},
+				{name: "group prefix only", groups: []string{group[:len(group)-1]}, wantDecision: authorizer.DecisionNoOpinion},
+			} {
+				t.Run(tt.name, func(t *testing.T) {
+					dec, _, err := a.Authorize(withCluster("root:consumer"), &authorizer.AttributesRecord{
+						User:            &user.DefaultInfo{Name: "user-1", Groups: tt.groups},
+						Verb:            "get",
+						APIGroup:        "widgets.example.io",
+						Resource:        "widgets",
+						ResourceRequest: true,
+					})
+					require.NoError(t, err)
+					require.Equal(t, tt.wantDecision, dec)
+
+					wantGroups := make([]string, 0, len(tt.groups))
+					for _, g := range tt.groups {
+						wantGroups = append(wantGroups, apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix+g)
+					}
+					require.Equal(t, wantGroups, recordedAttributes.GetUser().GetGroups())
+				})
+			}
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerVerbsPolicy(t *testing.T) {
+	policy := &apisv1alpha1.MaximalPermissionPolicy{Verbs: []apisv1alpha1.ResourceVerbsPolicy{
+		{Group: "widgets.example.io", Resource: "widgets", Verbs: []string{"get", "list", "watch"}},
+		{Group: "widgets.example.io", Resource: "widgets/status", Verbs: []string{"*"}},
+	}}
+
+	for _, tt := range []struct {
+		name         string
+		verb         string
+		resource     string
+		subresource  string
+		wantDecision authorizer.Decision
+	}{
+		{name: "allowed verb", verb: "list", resource: "widgets", wantDecision: authorizer.DecisionAllow},
+		{name: "verb not allowed", verb: "delete", resource: "widgets", wantDecision: authorizer.DecisionNoOpinion},
+		{name: "wildcard verb on subresource", verb: "update", resource: "widgets", subresource: "status", wantDecision: authorizer.DecisionAllow},
+		{name: "subresource not listed", verb: "get", resource: "widgets", subresource: "scale", wantDecision: authorizer.DecisionNoOpinion},
+		{name: "resource not listed", verb: "get", resource: "sprockets", wantDecision: authorizer.DecisionNoOpinion},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "sprockets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", policy)},
+				inner, delegate,
+			)
+
+			attr := &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            tt.verb,
+				APIGroup:        "widgets.example.io",
+				Resource:        tt.resource,
+				Subresource:     tt.subresource,
+				ResourceRequest: true,
+			}
+			dec, _, err := a.Authorize(withCluster("root:consumer"), attr)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Nil(t, inner.recordedAttributes, "expected no RBAC evaluation for the verbs policy")
+			if tt.wantDecision == authorizer.DecisionAllow {
+				require.Equal(t, attr, delegate.recordedAttributes)
+			} else {
+				require.Nil(t, delegate.recordedAttributes, "expected the delegate not to be called")
+			}
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerBoundResourceVerbs(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		verbs        []string
+		verb         string
+		wantDecision authorizer.Decision
+		wantReason   string
+		wantRBAC     bool
+	}{
+		{name: "all verbs by default", verb: "delete", wantDecision: authorizer.DecisionAllow, wantReason: "delegate", wantRBAC: true},
+		{name: "allowed verb", verbs: []string{"get", "list"}, verb: "list", wantDecision: authorizer.DecisionAllow, wantReason: "delegate", wantRBAC: true},
+		{name: "verb not allowed", verbs: []string{"get", "list"}, verb: "delete", wantDecision: authorizer.DecisionNoOpinion, wantReason: ReasonVerbNotAllowed},
+		{name: "wildcard verb", verbs: []string{"*"}, verb: "delete", wantDecision: authorizer.DecisionAllow, wantReason: "delegate", wantRBAC: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets", Verbs: tt.verbs},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				inner, &recordingAuthorizer{decision: authorizer.DecisionAllow, reason: "delegate"},
+			)
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, reason, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            tt.verb,
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, tt.wantReason, reason)
+			require.Equal(t, tt.wantRBAC, inner.recordedAttributes != nil, "unexpected RBAC evaluation")
+			if !tt.wantRBAC {
+				require.Equal(t, DecisionNoOpinion, ev.Annotations[MaximalPermissionPolicyAuditDecision])
+				require.Contains(t, ev.Annotations[MaximalPermissionPolicyAuditReason], fmt.Sprintf("verb %q not allowed", tt.verb))
+			}
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerGlobalPolicy(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		policy      *apisv1alpha1.MaximalPermissionPolicy
+		wantUser    string
+		wantGroups  []string
+		wantVariant string
+	}{
+		{
+			name:        "local policy prefixes user and groups",
+			policy:      withLocalPolicy(),
+			wantUser:    "apis.kcp.dev:binding:user-1",
+			wantGroups:  []string{"apis.kcp.dev:binding:team-1"},
+			wantVariant: "local",
+		},
+		{
+			name:        "global policy keeps user and groups",
+			policy:      &apisv1alpha1.MaximalPermissionPolicy{Global: &apisv1alpha1.GlobalAPIExportPolicy{}},
+			wantUser:    "user-1",
+			wantGroups:  []string{"team-1"},
+			wantVariant: "global",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", tt.policy)},
+				inner, delegate,
+			)
+			var authorizerCluster logicalcluster.Name
+			a.newAuthorizer = func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+				authorizerCluster = clusterName
+				return inner
+			}
+
+			attr := &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1", Groups: []string{"team-1"}},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			}
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, _, err := a.Authorize(ctx, attr)
+			require.NoError(t, err)
+			require.Equal(t, authorizer.DecisionAllow, dec)
+			require.Equal(t, logicalcluster.New("root:provider"), authorizerCluster)
+			require.NotNil(t, inner.recordedAttributes, "expected the maximal permission policy to be evaluated")
+			require.Equal(t, tt.wantUser, inner.recordedAttributes.GetUser().GetName())
+			require.Equal(t, tt.wantGroups, inner.recordedAttributes.GetUser().GetGroups())
+			require.Equal(t, attr, delegate.recordedAttributes)
+			require.Equal(t, tt.wantVariant, ev.Annotations[MaximalPermissionPolicyAuditPolicyVariant])
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerUserGroupPrefix(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		opts       []MaximalPermissionPolicyAuthorizerOption
+		user       string
+		group      string
+		wantPrefix string
+		wantUser   string
+		wantGroup  string
+	}{
+		{name: "default prefix", user: "user-1", group: "team-1", wantPrefix: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix, wantUser: "user-1", wantGroup: "team-1"},
+		{name: "custom prefix", opts: []MaximalPermissionPolicyAuthorizerOption{WithUserGroupPrefix("test.apis.kcp.dev:binding:")}, user: "user-1", group: "team-1", wantPrefix: "test.apis.kcp.dev:binding:", wantUser: "user-1", wantGroup: "team-1"},
+		{name: "names as they are by default", user: "User-1", group: "Team-1", wantPrefix: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix, wantUser: "User-1", wantGroup: "Team-1"},
+		{name: "normalized names", opts: []MaximalPermissionPolicyAuthorizerOption{WithUserNameNormalizer(strings.ToLower)}, user: "User-1", group: "TEAM-1", wantPrefix: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix, wantUser: "user-1", wantGroup: "team-1"},
+		{name: "normalized names with custom prefix", opts: []MaximalPermissionPolicyAuthorizerOption{WithUserGroupPrefix("Test:"), WithUserNameNormalizer(strings.ToLower)}, user: "User-1", group: "Team-1", wantPrefix: "Test:", wantUser: "user-1", wantGroup: "team-1"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				inner, &recordingAuthorizer{decision: authorizer.DecisionAllow},
+				tt.opts...,
+			)
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: tt.user, Groups: []string{tt.group}},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, authorizer.DecisionAllow, dec)
+			require.Equal(t, tt.wantPrefix+tt.wantUser, inner.recordedAttributes.GetUser().GetName())
+			require.Equal(t, []string{tt.wantPrefix + tt.wantGroup}, inner.recordedAttributes.GetUser().GetGroups())
+			require.Contains(t, ev.Annotations[MaximalPermissionPolicyAuditReason], fmt.Sprintf("prefix: %q", tt.wantPrefix))
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerIdentityRewriter(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		policy     *apisv1alpha1.MaximalPermissionPolicy
+		wantUser   string
+		wantGroups []string
+	}{
+		{
+			name:       "local policy",
+			policy:     withLocalPolicy(),
+			wantUser:   apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "user-1",
+			wantGroups: []string{apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "internal:admins", apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "team-1"},
+		},
+		{
+			name:       "global policy",
+			policy:     &apisv1alpha1.MaximalPermissionPolicy{Global: &apisv1alpha1.GlobalAPIExportPolicy{}},
+			wantUser:   "user-1",
+			wantGroups: []string{"external:admins", "team-1"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			// map the external group to the internal one the RBAC of the API export cluster binds, and prefix as by default
+			prefix := PrefixIdentityRewriter(apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix, nil)
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", tt.policy)},
+				inner, &recordingAuthorizer{decision: authorizer.DecisionAllow},
+				WithIdentityRewriter(IdentityRewriterFunc(func(u user.Info) user.Info {
+					groups := make([]string, 0, len(u.GetGroups()))
+					for _, g := range u.GetGroups() {
+						if g == "external:admins" {
+							g = "internal:admins"
+						}
+						groups = append(groups, g)
+					}
+					return prefix.Rewrite(&user.DefaultInfo{Name: u.GetName(), UID: u.GetUID(), Groups: groups, Extra: u.GetExtra()})
+				})),
+			)
+
+			attr := &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1", Groups: []string{"external:admins", "team-1"}},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			}
+			dec, _, err := a.Authorize(withCluster("root:consumer"), attr)
+			require.NoError(t, err)
+			require.Equal(t, authorizer.DecisionAllow, dec)
+			require.Equal(t, tt.wantUser, inner.recordedAttributes.GetUser().GetName())
+			require.Equal(t, tt.wantGroups, inner.recordedAttributes.GetUser().GetGroups())
+			require.Equal(t, []string{"external:admins", "team-1"}, attr.User.GetGroups(), "expected the request to be unchanged")
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerCanceledContext(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		resource     string
+		wantCanceled string
+	}{
+		{name: "bound resource", resource: "widgets", wantCanceled: "rbac"},
+		{name: "unbound resource", resource: "configmaps", wantCanceled: "delegate"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				inner, delegate,
+			)
+			var newAuthorizerCalled bool
+			a.newAuthorizer = func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+				newAuthorizerCalled = true
+				return inner
+			}
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			ctx, cancel := context.WithCancel(ctx)
+			cancel()
+
+			dec, reason, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        tt.resource,
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, authorizer.DecisionNoOpinion, dec)
+			require.Equal(t, ReasonRequestCanceled, reason)
+			require.Equal(t, tt.wantCanceled, ev.Annotations[MaximalPermissionPolicyAuditCanceled])
+			require.False(t, newAuthorizerCalled, "expected no RBAC authorizer to be constructed")
+			require.Nil(t, inner.recordedAttributes, "expected no RBAC evaluation")
+			require.Nil(t, delegate.recordedAttributes, "expected the delegate not to be called")
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerDecisionCache(t *testing.T) {
+	inner := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		)},
+		[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+		inner, &recordingAuthorizer{decision: authorizer.DecisionAllow},
+	)
+	newAuthorizerCalls := 0
+	a.newAuthorizer = func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+		newAuthorizerCalls++
+		return inner
+	}
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	a.decisionCache = newDecisionCache(10, time.Minute, fakeClock)
+
+	authorize := func(t *testing.T, userName, verb string) map[string]string {
+		t.Helper()
+		ctx, ev := withAuditEvent(withCluster("root:consumer"))
+		dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+			User:            &user.DefaultInfo{Name: userName, Groups: []string{"team-1"}},
+			Verb:            verb,
+			APIGroup:        "widgets.example.io",
+			Resource:        "widgets",
+			ResourceRequest: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, authorizer.DecisionAllow, dec)
+		return ev.Annotations
+	}
+
+	authorize(t, "user-1", "get")
+	require.Equal(t, 1, newAuthorizerCalls)
+
+	annotations := authorize(t, "user-1", "get")
+	require.Equal(t, 1, newAuthorizerCalls, "expected a cache hit")
+	require.Equal(t, "hit", annotations[MaximalPermissionPolicyAuditDecisionCache])
+	require.Equal(t, DecisionAllowed, annotations[MaximalPermissionPolicyAuditDecision])
+
+	authorize(t, "user-1", "list")
+	require.Equal(t, 2, newAuthorizerCalls, "expected a miss for another verb")
+	authorize(t, "user-2", "get")
+	require.Equal(t, 3, newAuthorizerCalls, "expected a miss for another user")
+
+	fakeClock.Step(2 * time.Minute)
+	authorize(t, "user-1", "get")
+	require.Equal(t, 4, newAuthorizerCalls, "expected the cached decision to expire")
+}
+
+func TestMaximalPermissionPolicyAuthorizerDeprecatedExportWarnings(t *testing.T) {
+	deprecated := newAPIExport("root:provider", "widgets", withLocalPolicy())
+	conditions.MarkTrue(deprecated, apisv1alpha1.APIExportDeprecated)
+	deprecated.Status.Conditions[0].Message = "use gadgets instead"
+	var warnings []string
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{
+			newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+				apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+			),
+			newAPIBinding("root:consumer", "gadgets", "root:provider", "gadgets",
+				apisv1alpha1.BoundAPIResource{Group: "gadgets.example.io", Resource: "gadgets"},
+			),
+		},
+		[]*apisv1alpha1.APIExport{deprecated, newAPIExport("root:provider", "gadgets", withLocalPolicy())},
+		&recordingAuthorizer{decision: authorizer.DecisionNoOpinion}, &recordingAuthorizer{decision: authorizer.DecisionAllow},
+		WithWarningHandler(func(ctx context.Context, message string) {
+			warnings = append(warnings, message)
+		}),
+	)
+
+	for _, tt := range []struct {
+		name         string
+		group        string
+		resource     string
+		wantWarnings []string
+	}{
+		{name: "deprecated export", group: "widgets.example.io", resource: "widgets", wantWarnings: []string{`API export "widgets", path: "root:provider" is deprecated: use gadgets instead`}},
+		{name: "export not deprecated", group: "gadgets.example.io", resource: "gadgets"},
+		{name: "not bound", group: "other.example.io", resource: "others"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings = nil
+			dec, _, err := a.Authorize(withCluster("root:consumer"), &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        tt.group,
+				Resource:        tt.resource,
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			if tt.resource != "others" {
+				require.Equal(t, authorizer.DecisionNoOpinion, dec, "expected the warning not to change the decision")
+			}
+			require.Equal(t, tt.wantWarnings, warnings)
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerTerminalDecision(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		terminal     bool
+		nilDelegate  bool
+		resource     string
+		rbacDecision authorizer.Decision
+		wantDecision authorizer.Decision
+		wantDelegate bool
+	}{
+		{name: "allowed by policy, delegated", resource: "widgets", rbacDecision: authorizer.DecisionAllow, wantDecision: authorizer.DecisionNoOpinion, wantDelegate: true},
+		{name: "allowed by policy, terminal", terminal: true, resource: "widgets", rbacDecision: authorizer.DecisionAllow, wantDecision: authorizer.DecisionAllow},
+		{name: "not allowed by policy, terminal", terminal: true, resource: "widgets", rbacDecision: authorizer.DecisionNoOpinion, wantDecision: authorizer.DecisionNoOpinion},
+		{name: "not bound, terminal", terminal: true, resource: "others", wantDecision: authorizer.DecisionNoOpinion, wantDelegate: true},
+		{name: "allowed by policy, terminal without delegate", terminal: true, nilDelegate: true, resource: "widgets", rbacDecision: authorizer.DecisionAllow, wantDecision: authorizer.DecisionAllow},
+		{name: "not bound, without delegate", nilDelegate: true, resource: "others", wantDecision: authorizer.DecisionNoOpinion},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			delegate := &recordingAuthorizer{decision: authorizer.DecisionNoOpinion}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				&recordingAuthorizer{decision: tt.rbacDecision}, delegate,
+				WithTerminalDecision(tt.terminal),
+			)
+			if tt.nilDelegate {
+				a.delegate = nil
+			}
+
+			dec, reason, err := a.Authorize(withCluster("root:consumer"), &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        tt.resource,
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, tt.wantDelegate, delegate.recordedAttributes != nil)
+			if tt.nilDelegate && dec != authorizer.DecisionAllow {
+				require.Equal(t, ReasonNoDelegate, reason)
+			}
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerAuthorizeForExport(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		policy       *apisv1alpha1.MaximalPermissionPolicy
+		rbacDecision authorizer.Decision
+		wantDecision authorizer.Decision
+		wantRBAC     bool
+		wantReason   string
+	}{
+		{name: "nil policy", wantDecision: authorizer.DecisionAllow},
+		{name: "nil local policy", policy: &apisv1alpha1.MaximalPermissionPolicy{}, wantDecision: authorizer.DecisionAllow},
+		{name: "allowing policy", policy: withLocalPolicy(), rbacDecision: authorizer.DecisionAllow, wantDecision: authorizer.DecisionAllow, wantRBAC: true},
+		{name: "denying policy", policy: withLocalPolicy(), rbacDecision: authorizer.DecisionNoOpinion, wantDecision: authorizer.DecisionNoOpinion, wantRBAC: true,
+			wantReason: ReasonPolicyDenied},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingAuthorizer{decision: tt.rbacDecision}
+			a := newTestMaximalPermissionPolicyAuthorizer(t, nil, nil, inner, &recordingAuthorizer{decision: authorizer.DecisionAllow})
+			a.bindingMatcher = BindingMatcherFunc(func(attr authorizer.Attributes, clusterName logicalcluster.Name) (*APIBindingMatch, bool, error) {
+				t.Fatal("expected no API binding lookup")
+				return nil, false, nil
+			})
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, reason, err := a.AuthorizeForExport(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			}, newAPIExport("root:provider", "widgets", tt.policy))
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, tt.wantRBAC, inner.recordedAttributes != nil)
+			if tt.wantReason != "" {
+				require.Equal(t, tt.wantReason, reason)
+			}
+			if tt.wantRBAC {
+				require.Equal(t, "widgets.example.io", inner.recordedAttributes.GetAPIGroup())
+				require.Equal(t, "local", ev.Annotations[MaximalPermissionPolicyAuditPolicyVariant])
+			}
+		})
+	}
+
+	a := newTestMaximalPermissionPolicyAuthorizer(t, nil, nil, &recordingAuthorizer{}, &recordingAuthorizer{decision: authorizer.DecisionAllow})
+	dec, _, err := a.AuthorizeForExport(context.Background(), &authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "user-1"}}, newAPIExport("root:provider", "widgets", nil))
+	require.Error(t, err, "expected an error without cluster")
+	require.Equal(t, authorizer.DecisionNoOpinion, dec)
+}
+
+func TestMaximalPermissionPolicyAuthorizerFailurePolicy(t *testing.T) {
+	lookupErr := errors.New("indexer unavailable")
+	for _, tt := range []struct {
+		name         string
+		policy       FailurePolicy
+		failing      string
+		wantDecision authorizer.Decision
+	}{
+		{name: "binding lookup fails open by default", failing: "binding", wantDecision: authorizer.DecisionNoOpinion},
+		{name: "binding lookup fails open", policy: FailOpen, failing: "binding", wantDecision: authorizer.DecisionNoOpinion},
+		{name: "binding lookup fails closed", policy: FailClosed, failing: "binding", wantDecision: authorizer.DecisionDeny},
+		{name: "export lookup fails open", policy: FailOpen, failing: "export", wantDecision: authorizer.DecisionNoOpinion},
+		{name: "export lookup fails closed", policy: FailClosed, failing: "export", wantDecision: authorizer.DecisionDeny},
+		{name: "RBAC evaluation fails open", policy: FailOpen, failing: "rbac", wantDecision: authorizer.DecisionNoOpinion},
+		{name: "RBAC evaluation fails closed", policy: FailClosed, failing: "rbac", wantDecision: authorizer.DecisionDeny},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []MaximalPermissionPolicyAuthorizerOption
+			if tt.policy != "" {
+				opts = append(opts, WithFailurePolicy(tt.policy))
+			}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				&recordingAuthorizer{decision: authorizer.DecisionAllow}, &recordingAuthorizer{decision: authorizer.DecisionAllow},
+				opts...,
+			)
+			switch tt.failing {
+			case "binding":
+				a.bindingMatcher = BindingMatcherFunc(func(attr authorizer.Attributes, clusterName logicalcluster.Name) (*APIBindingMatch, bool, error) {
+					return nil, false, lookupErr
+				})
+			case "export":
+				a.getAPIExportByReference = func(exportRef *apisv1alpha1.ExportReference, exportClusterName logicalcluster.Name) (*apisv1alpha1.APIExport, bool, error) {
+					return nil, false, lookupErr
+				}
+			case "rbac":
+				a.newAuthorizer = func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+					return &recordingAuthorizer{decision: authorizer.DecisionNoOpinion, err: lookupErr}
+				}
+			}
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, reason, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.ErrorIs(t, err, lookupErr)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, DecisionString(tt.wantDecision), ev.Annotations[MaximalPermissionPolicyAuditDecision])
+			require.Equal(t, map[string]string{
+				"binding": ReasonBindingLookupFailed,
+				"export":  ReasonExportLookupFailed,
+				"rbac":    ReasonRBACEvaluationFailed,
+			}[tt.failing], reason, "expected the reason code regardless of the failure policy")
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerExportResolutionError(t *testing.T) {
+	lookupErr := errors.New("lookup failed")
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		)},
+		nil,
+		&recordingAuthorizer{decision: authorizer.DecisionAllow}, &recordingAuthorizer{decision: authorizer.DecisionAllow},
+	)
+	a.getAPIExportByReference = func(exportRef *apisv1alpha1.ExportReference, exportClusterName logicalcluster.Name) (*apisv1alpha1.APIExport, bool, error) {
+		return nil, false, lookupErr
+	}
+
+	_, reason, err := a.Authorize(withCluster("root:consumer"), &authorizer.AttributesRecord{
+		User:            &user.DefaultInfo{Name: "user-1"},
+		Verb:            "get",
+		APIGroup:        "widgets.example.io",
+		Resource:        "widgets",
+		ResourceRequest: true,
+	})
+	require.Equal(t, ReasonExportLookupFailed, reason)
+	require.ErrorIs(t, err, lookupErr)
+
+	var resolutionErr *ExportResolutionError
+	require.ErrorAs(t, err, &resolutionErr)
+	require.Equal(t, "widgets", resolutionErr.ExportName)
+	require.Equal(t, "root:provider", resolutionErr.Path)
+	require.EqualError(t, err, `error resolving API export "widgets", path: "root:provider": lookup failed`)
+
+	// the apiserver renders it as internal error naming the API export
+	require.True(t, apierrors.IsInternalError(err))
+	status := apierrors.APIStatus(resolutionErr).Status()
+	require.Equal(t, int32(http.StatusInternalServerError), status.Code)
+	require.Equal(t, "apis.kcp.dev", status.Details.Group)
+	require.Equal(t, "apiexports", status.Details.Kind)
+	require.Equal(t, "widgets", status.Details.Name)
+	require.Contains(t, status.Message, `error resolving API export "widgets"`)
+}
+
+func TestMaximalPermissionPolicyAuthorizerReasonCodes(t *testing.T) {
+	canceledCtx, cancel := context.WithCancel(withCluster("root:consumer"))
+	cancel()
+
+	for _, tt := range []struct {
+		name         string
+		ctx          context.Context
+		resource     string
+		subresource  string
+		verb         string
+		rbacDecision authorizer.Decision
+		opts         []MaximalPermissionPolicyAuthorizerOption
+		wantReason   string
+		wantAudit    string
+	}{
+		{name: "no cluster", ctx: context.Background(), resource: "widgets", wantReason: ReasonClusterUnknown, wantAudit: "error getting cluster from request"},
+		{name: "subresource without resource", subresource: "status", wantReason: ReasonIncompleteRequestInfo, wantAudit: `subresource "status" without resource`},
+		{name: "incomplete request info", wantReason: ReasonIncompleteRequestInfo, wantAudit: "incomplete request info"},
+		{name: "API binding lookup failed", resource: "widgets", opts: []MaximalPermissionPolicyAuthorizerOption{WithBindingMatcher(BindingMatcherFunc(func(attr authorizer.Attributes, clusterName logicalcluster.Name) (*APIBindingMatch, bool, error) {
+			return nil, false, errors.New("indexer unavailable")
+		}))}, wantReason: ReasonBindingLookupFailed, wantAudit: "error getting API binding reference: indexer unavailable"},
+		{name: "API binding scan limit exceeded", resource: "widgets", opts: []MaximalPermissionPolicyAuthorizerOption{WithBindingMatcher(BindingMatcherFunc(func(attr authorizer.Attributes, clusterName logicalcluster.Name) (*APIBindingMatch, bool, error) {
+			return nil, false, errAPIBindingScanLimitExceeded
+		}))}, wantReason: ReasonBindingLookupFailed, wantAudit: "API binding scan limit exceeded"},
+		{name: "API export not found", resource: "gadgets", wantReason: ReasonExportNotFound, wantAudit: `API export "gadgets" not found, path: "root:provider"`},
+		{name: "verbs policy denied", resource: "doodads", verb: "delete", wantReason: ReasonPolicyDenied, wantAudit: `verbs policy of API export "doodads", path: "root:provider" does not allow verb "delete" on "doodads"`},
+		{name: "RBAC denied", resource: "widgets", rbacDecision: authorizer.DecisionNoOpinion, wantReason: ReasonPolicyDenied, wantAudit: `API export cluster "root:provider"`},
+		{name: "allowed terminally", resource: "widgets", rbacDecision: authorizer.DecisionAllow, opts: []MaximalPermissionPolicyAuthorizerOption{WithTerminalDecision(true)}, wantReason: ReasonPolicyAllowed},
+		{name: "canceled", ctx: canceledCtx, resource: "widgets", wantReason: ReasonRequestCanceled, wantAudit: "request canceled before RBAC evaluation"},
+		{name: "delegated", resource: "sprockets", wantReason: "delegate reason"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{
+					newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+						apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+					),
+					newAPIBinding("root:consumer", "gadgets", "root:provider", "gadgets",
+						apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "gadgets"},
+					),
+					newAPIBinding("root:consumer", "doodads", "root:provider", "doodads",
+						apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "doodads"},
+					),
+				},
+				[]*apisv1alpha1.APIExport{
+					newAPIExport("root:provider", "widgets", withLocalPolicy()),
+					newAPIExport("root:provider", "doodads", &apisv1alpha1.MaximalPermissionPolicy{
+						Verbs: []apisv1alpha1.ResourceVerbsPolicy{{Group: "widgets.example.io", Resource: "doodads", Verbs: []string{"get"}}},
+					}),
+				},
+				&recordingAuthorizer{decision: tt.rbacDecision},
+				authorizer.AuthorizerFunc(func(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+					return authorizer.DecisionAllow, "delegate reason", nil
+				}),
+				tt.opts...,
+			)
+
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = withCluster("root:consumer")
+			}
+			verb := tt.verb
+			if verb == "" {
+				verb = "get"
+			}
+			ctx, ev := withAuditEvent(ctx)
+			_, reason, _ := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            verb,
+				APIGroup:        "widgets.example.io",
+				Resource:        tt.resource,
+				Subresource:     tt.subresource,
+				ResourceRequest: true,
+			})
+			require.Equal(t, tt.wantReason, reason)
+			require.Contains(t, ev.Annotations[MaximalPermissionPolicyAuditReason], tt.wantAudit, "expected the details in the audit annotation")
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerShadowMode(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		enforce           bool
+		rbacDecision      authorizer.Decision
+		wantDecision      authorizer.Decision
+		wantAuditDecision string
+		wantShadow        bool
+	}{
+		{name: "denying policy enforced", enforce: true, rbacDecision: authorizer.DecisionNoOpinion, wantDecision: authorizer.DecisionNoOpinion, wantAuditDecision: DecisionNoOpinion},
+		{name: "denying policy in shadow mode", rbacDecision: authorizer.DecisionNoOpinion, wantDecision: authorizer.DecisionAllow, wantAuditDecision: DecisionNoOpinion, wantShadow: true},
+		{name: "allowing policy in shadow mode", rbacDecision: authorizer.DecisionAllow, wantDecision: authorizer.DecisionAllow, wantAuditDecision: DecisionAllowed, wantShadow: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			delegateCalls := 0
+			delegate := authorizer.AuthorizerFunc(func(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+				delegateCalls++
+				return authorizer.DecisionAllow, "", nil
+			})
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				&recordingAuthorizer{decision: tt.rbacDecision}, delegate,
+				WithEnforcement(tt.enforce),
+				WithMetrics(metrics.NewKubeRegistry()),
+			)
+			before, err := testutil.GetCounterMetricValue(decisions.WithLabelValues(decisionLabel(tt.rbacDecision), "true", "widgets"))
+			require.NoError(t, err)
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "delete",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, tt.wantAuditDecision, ev.Annotations[MaximalPermissionPolicyAuditDecision], "expected the would-be decision to be audited")
+			_, shadow := ev.Annotations[MaximalPermissionPolicyAuditEnforcement]
+			require.Equal(t, tt.wantShadow, shadow)
+			if tt.wantShadow {
+				require.Equal(t, "shadow", ev.Annotations[MaximalPermissionPolicyAuditEnforcement])
+				require.Equal(t, 1, delegateCalls, "expected the delegate to be called exactly once")
+			}
+
+			got, err := testutil.GetCounterMetricValue(decisions.WithLabelValues(decisionLabel(tt.rbacDecision), "true", "widgets"))
+			require.NoError(t, err)
+			require.Equal(t, float64(1), got-before, "expected the would-be decision to be counted")
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerIncompleteRequestInfo(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		opts         []MaximalPermissionPolicyAuthorizerOption
+		wantDecision authorizer.Decision
+		wantDelegate bool
+	}{
+		{name: "no opinion by default", wantDecision: authorizer.DecisionNoOpinion},
+		{name: "configured decision", opts: []MaximalPermissionPolicyAuthorizerOption{WithIncompleteRequestInfoDecision(authorizer.DecisionDeny)}, wantDecision: authorizer.DecisionDeny},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				&recordingAuthorizer{decision: authorizer.DecisionAllow},
+				delegate,
+				tt.opts...,
+			)
+
+			// a resource request whose request info lacks everything but the verb
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			ctx = request.WithRequestInfo(request.WithUser(ctx, &user.DefaultInfo{Name: "user-1"}), &request.RequestInfo{IsResourceRequest: true, Verb: "get"})
+			attr, err := filters.GetAuthorizerAttributes(ctx)
+			require.NoError(t, err)
+
+			dec, _, err := a.Authorize(ctx, attr)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, "incomplete request info", ev.Annotations[MaximalPermissionPolicyAuditReason])
+			if tt.wantDelegate {
+				require.Equal(t, attr, delegate.recordedAttributes)
+			} else {
+				require.Nil(t, delegate.recordedAttributes, "expected the delegate not to be called")
+			}
+		})
+	}
+}
+
+func TestPrefixedAttributes(t *testing.T) {
+	attr := &authorizer.AttributesRecord{
+		User: &user.DefaultInfo{
+			Name:   "user-1",
+			UID:    "uid-1",
+			Groups: []string{"team-1"},
+			Extra:  map[string][]string{"scopes": {"scope-1", "scope-2"}},
+		},
+		Verb:            "get",
+		APIGroup:        "widgets.example.io",
+		Resource:        "widgets",
+		ResourceRequest: true,
+	}
+
+	prefixedAttr := prefixedAttributes(attr, "widgets.example.io", "prefix:", nil)
+	require.Equal(t, &user.DefaultInfo{
+		Name:   "prefix:user-1",
+		UID:    "uid-1",
+		Groups: []string{"prefix:team-1"},
+		Extra:  map[string][]string{"scopes": {"scope-1", "scope-2"}},
+	}, prefixedAttr.User)
+
+	// the extra of the prefixed attributes is a copy
+	prefixedAttr.User.GetExtra()["scopes"][0] = "changed"
+	require.Equal(t, []string{"scope-1", "scope-2"}, attr.GetUser().GetExtra()["scopes"])
+}
+
+func TestMaximalPermissionPolicyAuthorizerImpersonation(t *testing.T) {
+	inner := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		)},
+		[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+		inner, &recordingAuthorizer{decision: authorizer.DecisionAllow},
+	)
+
+	// impersonation is resolved before authorization, i.e. the policy applies to the impersonated user
+	var dec authorizer.Decision
+	handler := filters.WithImpersonation(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		attr, err := filters.GetAuthorizerAttributes(req.Context())
+		require.NoError(t, err)
+		dec, _, err = a.Authorize(req.Context(), attr)
+		require.NoError(t, err)
+	}), authorizer.AuthorizerFunc(func(context.Context, authorizer.Attributes) (authorizer.Decision, string, error) {
+		return authorizer.DecisionAllow, "", nil
+	}), serializer.NewCodecFactory(runtime.NewScheme()))
+
+	req := httptest.NewRequest("GET", "/apis/widgets.example.io/v1/widgets", nil)
+	req.Header.Set(authenticationv1.ImpersonateUserHeader, "impersonated")
+	req.Header.Add(authenticationv1.ImpersonateGroupHeader, "team-1")
+	req.Header.Add(authenticationv1.ImpersonateUserExtraHeaderPrefix+"Scopes", "scope-1")
+	ctx := request.WithUser(withCluster("root:consumer"), &user.DefaultInfo{Name: "admin", Groups: []string{user.AllAuthenticated}})
+	ctx = request.WithRequestInfo(ctx, &request.RequestInfo{IsResourceRequest: true, Verb: "list", APIGroup: "widgets.example.io", APIVersion: "v1", Resource: "widgets"})
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+
+	require.Equal(t, authorizer.DecisionAllow, dec)
+	require.NotNil(t, inner.recordedAttributes, "expected the policy to be evaluated")
+	require.Equal(t, &user.DefaultInfo{
+		Name: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "impersonated",
+		Groups: []string{
+			apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "team-1",
+			apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + user.AllAuthenticated,
+		},
+		Extra: map[string][]string{"scopes": {"scope-1"}},
+	}, inner.recordedAttributes.GetUser())
+}
+
+func TestMaximalPermissionPolicyAuthorizerNonResourceRequest(t *testing.T) {
+	delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow, reason: "delegate"}
+	a := newTestMaximalPermissionPolicyAuthorizer(t, nil, nil, nil, delegate,
+		WithBindingMatcher(BindingMatcherFunc(func(attr authorizer.Attributes, clusterName logicalcluster.Name) (*APIBindingMatch, bool, error) {
+			t.Fatal("expected no API binding lookup for a non-resource request")
+			return nil, false, nil
+		})),
+	)
+
+	ctx, ev := withAuditEvent(withCluster("root:consumer"))
+	attr := &authorizer.AttributesRecord{
+		User: &user.DefaultInfo{Name: "user-1"},
+		Verb: "get",
+		Path: "/healthz",
+	}
+	dec, reason, details, err := a.AuthorizeWithDetails(ctx, attr)
+	require.NoError(t, err)
+	require.Equal(t, authorizer.DecisionAllow, dec)
+	require.Equal(t, "delegate", reason)
+	require.Equal(t, &MaximalPermissionPolicyDecisionDetails{Delegated: true}, details)
+	require.Equal(t, attr, delegate.recordedAttributes)
+	require.Equal(t, DecisionAllowed, ev.Annotations[MaximalPermissionPolicyAuditDecision])
+	require.Equal(t, `non-resource request of path "/healthz"`, ev.Annotations[MaximalPermissionPolicyAuditReason])
+}
+
+func TestMaximalPermissionPolicyAuthorizerMaintenanceExemptExports(t *testing.T) {
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{
+			newAPIBinding("root:consumer", "widgets", "root:provider", "widgets", apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"}),
+			newAPIBinding("root:consumer", "gadgets", "root:provider", "gadgets", apisv1alpha1.BoundAPIResource{Group: "gadgets.example.io", Resource: "gadgets"}),
+		},
+		[]*apisv1alpha1.APIExport{
+			newAPIExport("root:provider", "widgets", withLocalPolicy()),
+			newAPIExport("root:provider", "gadgets", withLocalPolicy()),
+		},
+		// stateless, as opposed to recordingAuthorizer, to be called concurrently
+		authorizer.AuthorizerFunc(func(context.Context, authorizer.Attributes) (authorizer.Decision, string, error) {
+			return authorizer.DecisionNoOpinion, "", nil
+		}),
+		authorizer.AuthorizerFunc(func(context.Context, authorizer.Attributes) (authorizer.Decision, string, error) {
+			return authorizer.DecisionAllow, "", nil
+		}),
+	)
+	authorize := func(group, resource string) (authorizer.Decision, string) {
+		ctx, ev := withAuditEvent(withCluster("root:consumer"))
+		dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+			User:            &user.DefaultInfo{Name: "user-1"},
+			Verb:            "get",
+			APIGroup:        group,
+			Resource:        resource,
+			ResourceRequest: true,
+		})
+		require.NoError(t, err)
+		return dec, ev.Annotations[MaximalPermissionPolicyAuditReason]
+	}
+
+	dec, _ := authorize("widgets.example.io", "widgets")
+	require.Equal(t, authorizer.DecisionNoOpinion, dec, "expected enforcement without exemption")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			a.SetMaintenanceExemptExports(apisv1alpha1.WorkspaceExportReference{Path: "root:provider", ExportName: "widgets"})
+		}()
+		go func() {
+			defer wg.Done()
+			// gadgets are never exempt, independent of concurrent updates
+			dec, _, err := a.Authorize(withCluster("root:consumer"), &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        "gadgets.example.io",
+				Resource:        "gadgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, authorizer.DecisionNoOpinion, dec)
+		}()
+	}
+	wg.Wait()
+
+	dec, reason := authorize("widgets.example.io", "widgets")
+	require.Equal(t, authorizer.DecisionAllow, dec, "expected exempt export to bypass enforcement")
+	require.Equal(t, `maintenance exempt API export "widgets", path: "root:provider"`, reason)
+	dec, _ = authorize("gadgets.example.io", "gadgets")
+	require.Equal(t, authorizer.DecisionNoOpinion, dec, "expected non-exempt export to be enforced")
+
+	a.SetMaintenanceExemptExports()
+	dec, _ = authorize("widgets.example.io", "widgets")
+	require.Equal(t, authorizer.DecisionNoOpinion, dec, "expected enforcement after the maintenance window")
+}
+
+func TestMaximalPermissionPolicyAuthorizerSubjectAccessReview(t *testing.T) {
+	kubeInformers := newKubeInformers(t,
+		inCluster("root:provider", newClusterRole("widgets", rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}})),
+		inCluster("root:provider", newClusterRoleBinding("widgets", "widgets", rbacv1.Subject{Kind: rbacv1.UserKind, Name: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "user-1"})),
+	)
+
+	for _, tt := range []struct {
+		name             string
+		verb             string
+		delegateDecision authorizer.Decision
+		wantAllowed      bool
+		wantCeiling      bool
+	}{
+		{name: "allowed", verb: "get", delegateDecision: authorizer.DecisionAllow, wantAllowed: true},
+		{name: "ceiling exceeded", verb: "delete", delegateDecision: authorizer.DecisionAllow, wantCeiling: true},
+		{name: "ordinary RBAC denial", verb: "get", delegateDecision: authorizer.DecisionNoOpinion},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				nil,
+				&recordingAuthorizer{decision: tt.delegateDecision, reason: "workspace RBAC decision"},
+			)
+			a.newAuthorizer = func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+				return rbac.New(newMergedRBACGetters(kubeInformers, clusterName, mergeClusters...))
+			}
+
+			obj, err := subjectaccessreview.NewREST(a).Create(withCluster("root:consumer"), &authorizationapi.SubjectAccessReview{
+				Spec: authorizationapi.SubjectAccessReviewSpec{
+					User: "user-1",
+					ResourceAttributes: &authorizationapi.ResourceAttributes{
+						Verb:     tt.verb,
+						Group:    "widgets.example.io",
+						Resource: "widgets",
+					},
+				},
+			}, nil, &metav1.CreateOptions{})
+			require.NoError(t, err)
+
+			status := obj.(*authorizationapi.SubjectAccessReview).Status
+			require.Equal(t, tt.wantAllowed, status.Allowed)
+			require.Empty(t, status.EvaluationError)
+			require.Equal(t, tt.wantCeiling, status.Reason == MaximalPermissionPolicyCeilingExceededReasonCode, "unexpected reason %q", status.Reason)
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerExplicitRBACVerbs(t *testing.T) {
+	kubeInformers := newKubeInformers(t,
+		inCluster("root:provider", newClusterRole("widgets",
+			rbacv1.PolicyRule{Verbs: []string{"*"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}},
+			rbacv1.PolicyRule{Verbs: []string{"get", "*"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"sprockets"}},
+		)),
+		inCluster("root:provider", newClusterRoleBinding("widgets", "widgets", rbacv1.Subject{Kind: rbacv1.UserKind, Name: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "user-1"})),
+	)
+
+	for _, tt := range []struct {
+		name         string
+		opts         []MaximalPermissionPolicyAuthorizerOption
+		verb         string
+		resource     string
+		wantDecision authorizer.Decision
+	}{
+		{name: "wildcard verb honored by default", verb: "delete", resource: "widgets", wantDecision: authorizer.DecisionAllow},
+		{name: "wildcard verb ignored", opts: []MaximalPermissionPolicyAuthorizerOption{WithExplicitRBACVerbs()}, verb: "delete", resource: "widgets", wantDecision: authorizer.DecisionNoOpinion},
+		{name: "explicit verb next to wildcard verb", opts: []MaximalPermissionPolicyAuthorizerOption{WithExplicitRBACVerbs()}, verb: "get", resource: "sprockets", wantDecision: authorizer.DecisionAllow},
+		{name: "other verb next to wildcard verb ignored", opts: []MaximalPermissionPolicyAuthorizerOption{WithExplicitRBACVerbs()}, verb: "list", resource: "sprockets", wantDecision: authorizer.DecisionNoOpinion},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "sprockets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				nil,
+				&recordingAuthorizer{decision: authorizer.DecisionAllow},
+				tt.opts...,
+			)
+			a.newAuthorizer = func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+				return newRBACAuthorizer(kubeInformers, clusterName, mergeClusters, a.explicitRBACVerbs)
+			}
+
+			dec, _, err := a.Authorize(withCluster("root:consumer"), &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            tt.verb,
+				APIGroup:        "widgets.example.io",
+				Resource:        tt.resource,
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerAggregatedClusterRole(t *testing.T) {
+	labeled := func(clusterRole *rbacv1.ClusterRole, labels map[string]string) *rbacv1.ClusterRole {
+		clusterRole.Labels = labels
+		return clusterRole
+	}
+	aggregated := newClusterRole("widgets-aggregated")
+	aggregated.AggregationRule = &rbacv1.AggregationRule{ClusterRoleSelectors: []metav1.LabelSelector{
+		{MatchLabels: map[string]string{"widgets.example.io/aggregate-to-widgets": "true"}},
+	}}
+	kubeInformers := newKubeInformers(t,
+		inCluster("root:provider", labeled(newClusterRole("widgets-reader",
+			rbacv1.PolicyRule{Verbs: []string{"get", "list"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}},
+		), map[string]string{"widgets.example.io/aggregate-to-widgets": "true"})),
+		inCluster("root:provider", labeled(newClusterRole("widgets-scaler",
+			rbacv1.PolicyRule{Verbs: []string{"update"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets/scale"}},
+			rbacv1.PolicyRule{Verbs: []string{"get", "list"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}},
+		), map[string]string{"widgets.example.io/aggregate-to-widgets": "true"})),
+		inCluster("root:provider", labeled(newClusterRole("widgets-admin",
+			rbacv1.PolicyRule{Verbs: []string{"delete"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}},
+		), map[string]string{"widgets.example.io/aggregate-to-widgets": "false"})),
+		inCluster("root:other", labeled(newClusterRole("widgets-other",
+			rbacv1.PolicyRule{Verbs: []string{"create"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}},
+		), map[string]string{"widgets.example.io/aggregate-to-widgets": "true"})),
+		inCluster("root:provider", aggregated),
+		inCluster("root:provider", newClusterRoleBinding("widgets", "widgets-aggregated", rbacv1.Subject{Kind: rbacv1.UserKind, Name: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "user-1"})),
+	)
+
+	for _, tt := range []struct {
+		verb         string
+		subresource  string
+		wantDecision authorizer.Decision
+	}{
+		{verb: "get", wantDecision: authorizer.DecisionAllow},
+		{verb: "list", wantDecision: authorizer.DecisionAllow},
+		{verb: "update", subresource: "scale", wantDecision: authorizer.DecisionAllow},
+		{verb: "update", wantDecision: authorizer.DecisionNoOpinion},
+		{verb: "delete", wantDecision: authorizer.DecisionNoOpinion},
+		{verb: "create", wantDecision: authorizer.DecisionNoOpinion},
+	} {
+		t.Run(tt.verb+" "+tt.subresource, func(t *testing.T) {
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				nil,
+				&recordingAuthorizer{decision: authorizer.DecisionAllow},
+			)
+			a.newAuthorizer = func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+				return newRBACAuthorizer(kubeInformers, clusterName, mergeClusters, false)
+			}
+
+			dec, _, err := a.Authorize(withCluster("root:consumer"), &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            tt.verb,
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				Subresource:     tt.subresource,
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+		})
+	}
+
+	_, _, clusterRoleGetter, _ := newMergedRBACGetters(kubeInformers, logicalcluster.New("root:provider"))
+	got, err := clusterRoleGetter.GetClusterRole("widgets-aggregated")
+	require.NoError(t, err)
+	require.Equal(t, []rbacv1.PolicyRule{
+		{Verbs: []string{"get", "list"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}},
+		{Verbs: []string{"update"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets/scale"}},
+	}, got.Rules, "expected the rules of the labeled ClusterRoles by name, without duplicates")
+}
+
+func TestMaximalPermissionPolicyAuthorizerSubresourceWithoutResource(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts []MaximalPermissionPolicyAuthorizerOption
+	}{
+		{name: "default"},
+		{name: "incomplete request info decision configured", opts: []MaximalPermissionPolicyAuthorizerOption{WithIncompleteRequestInfoDecision(authorizer.DecisionDeny)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				// a bound resource without resource name must not be matched either
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: ""},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				inner, delegate,
+				tt.opts...,
+			)
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "update",
+				APIGroup:        "widgets.example.io",
+				Subresource:     "status",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, authorizer.DecisionNoOpinion, dec)
+			require.Equal(t, `subresource "status" without resource`, ev.Annotations[MaximalPermissionPolicyAuditReason])
+			require.Nil(t, inner.recordedAttributes, "expected the maximal permission policy not to be evaluated")
+			require.Nil(t, delegate.recordedAttributes, "expected the delegate not to be called")
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerCollectionGetAsList(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		enabled     bool
+		verb        string
+		objectName  string
+		subresource string
+		wantVerb    string
+	}{
+		{name: "get on collection", enabled: true, verb: "get", wantVerb: "list"},
+		{name: "get on item", enabled: true, verb: "get", objectName: "widget-1", wantVerb: "get"},
+		{name: "get on subresource", enabled: true, verb: "get", objectName: "widget-1", subresource: "status", wantVerb: "get"},
+		{name: "list", enabled: true, verb: "list", wantVerb: "list"},
+		{name: "get on collection disabled", verb: "get", wantVerb: "get"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			var opts []MaximalPermissionPolicyAuthorizerOption
+			if tt.enabled {
+				opts = append(opts, WithCollectionGetAsList())
+			}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				inner, delegate,
+				opts...,
+			)
+
+			attr := &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            tt.verb,
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				Subresource:     tt.subresource,
+				Name:            tt.objectName,
+				ResourceRequest: true,
+			}
+			dec, _, err := a.Authorize(withCluster("root:consumer"), attr)
+			require.NoError(t, err)
+			require.Equal(t, authorizer.DecisionAllow, dec)
+			require.Equal(t, tt.wantVerb, inner.recordedAttributes.GetVerb())
+			require.Equal(t, tt.verb, delegate.recordedAttributes.GetVerb(), "expected the delegate to get the original verb")
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerSystemMastersBypass(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		opts         []MaximalPermissionPolicyAuthorizerOption
+		groups       []string
+		wantDecision authorizer.Decision
+		wantBypass   bool
+	}{
+		{name: "masters bypass by default", groups: []string{user.SystemPrivilegedGroup}, wantDecision: authorizer.DecisionAllow, wantBypass: true},
+		{name: "masters without bypass", opts: []MaximalPermissionPolicyAuthorizerOption{WithExemptGroups(nil)}, groups: []string{user.SystemPrivilegedGroup}, wantDecision: authorizer.DecisionNoOpinion},
+		{name: "masters bypass added back", opts: []MaximalPermissionPolicyAuthorizerOption{WithExemptGroups(nil), WithSystemMastersBypass()}, groups: []string{user.SystemPrivilegedGroup}, wantDecision: authorizer.DecisionAllow, wantBypass: true},
+		{name: "non-masters", groups: []string{"group-1"}, wantDecision: authorizer.DecisionNoOpinion},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingAuthorizer{decision: authorizer.DecisionNoOpinion}
+			delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				inner, delegate,
+				tt.opts...,
+			)
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1", Groups: tt.groups},
+				Verb:            "delete",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+
+			bypass, found := ev.Annotations[MaximalPermissionPolicyAuditSystemMastersBypass]
+			require.Equal(t, tt.wantBypass, found)
+			if tt.wantBypass {
+				require.Equal(t, "true", bypass)
+				require.Equal(t, user.SystemPrivilegedGroup, ev.Annotations[MaximalPermissionPolicyAuditExemptGroup])
+				require.Nil(t, inner.recordedAttributes, "expected the maximal permission policy not to be evaluated")
+			} else {
+				require.NotNil(t, inner.recordedAttributes, "expected the maximal permission policy to be evaluated")
+			}
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerClusterAuthorizerFactory(t *testing.T) {
+	kubeInformers := kcpkubernetesinformers.NewSharedInformerFactory(kcpfakeclient.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+	kcpInformers := kcpinformers.NewSharedInformerFactory(kcpfakeinformerclient.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+
+	var clusterNames []logicalcluster.Name
+	webhook := &recordingAuthorizer{decision: authorizer.DecisionNoOpinion}
+	a, err := NewMaximalPermissionPolicyAuthorizer(kubeInformers, kcpInformers, &recordingAuthorizer{decision: authorizer.DecisionAllow},
+		WithClusterAuthorizerFactory(func(clusterName logicalcluster.Name) authorizer.Authorizer {
+			clusterNames = append(clusterNames, clusterName)
+			return webhook
+		}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, kcpInformers.Apis().V1alpha1().APIBindings().Informer().GetIndexer().Add(newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+		apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+	)))
+	require.NoError(t, kcpInformers.Apis().V1alpha1().APIExports().Informer().GetIndexer().Add(newAPIExport("root:provider", "widgets", withLocalPolicy())))
+
+	for i := 0; i < 2; i++ {
+		dec, reason, err := a.Authorize(withCluster("root:consumer"), &authorizer.AttributesRecord{
+			User:            &user.DefaultInfo{Name: "user-1"},
+			Verb:            "get",
+			APIGroup:        "widgets.example.io",
+			Resource:        "widgets",
+			ResourceRequest: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, authorizer.DecisionNoOpinion, dec)
+		require.Equal(t, ReasonPolicyDenied, reason)
+	}
+	require.Equal(t, []logicalcluster.Name{logicalcluster.New("root:provider")}, clusterNames, "expected the factory to be called once with the API export cluster")
+	require.Equal(t, "apis.kcp.dev:binding:user-1", webhook.recordedAttributes.GetUser().GetName())
+}
+
+func TestMaximalPermissionPolicyAuthorizerHasSynced(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kubeInformers := kcpkubernetesinformers.NewSharedInformerFactory(kcpfakeclient.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+	kcpInformers := kcpinformers.NewSharedInformerFactory(kcpfakeinformerclient.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+	a, err := NewMaximalPermissionPolicyAuthorizer(kubeInformers, kcpInformers, nil)
+	require.NoError(t, err)
+	require.False(t, a.(*MaximalPermissionPolicyAuthorizer).HasSynced(), "expected not to have synced before the informers are started")
+
+	kubeInformers.Start(ctx.Done())
+	kubeInformers.WaitForCacheSync(ctx.Done())
+	require.False(t, a.(*MaximalPermissionPolicyAuthorizer).HasSynced(), "expected not to have synced before the API binding and API export informers")
+
+	kcpInformers.Start(ctx.Done())
+	kcpInformers.WaitForCacheSync(ctx.Done())
+	require.True(t, a.(*MaximalPermissionPolicyAuthorizer).HasSynced())
+}
+
+func TestMaximalPermissionPolicyAuthorizerClose(t *testing.T) {
+	kubeInformers := kcpkubernetesinformers.NewSharedInformerFactory(kcpfakeclient.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+	kcpInformers := kcpinformers.NewSharedInformerFactory(kcpfakeinformerclient.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+	authz, err := NewMaximalPermissionPolicyAuthorizer(kubeInformers, kcpInformers, &recordingAuthorizer{},
+		WithDecisionCache(10, time.Minute),
+		WithDenialDeduplication(time.Minute),
+		WithCircuitBreaker(1, time.Minute, time.Minute, authorizer.DecisionNoOpinion),
+	)
+	require.NoError(t, err)
+	a := authz.(*MaximalPermissionPolicyAuthorizer)
+
+	a.newAuthorizer(logicalcluster.New("root:provider"), nil)
+	a.decisionCache.add([sha256.Size]byte{1}, authorizer.DecisionAllow, "")
+	a.denialDeduplicator.first(&authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "user-1"}}, "widgets", "root:provider")
+	a.circuitBreakers.done(logicalcluster.New("root:provider"), errors.New("boom"))
+	require.False(t, a.circuitBreakers.allow(logicalcluster.New("root:provider")), "expected the circuit breaker to be open")
+
+	require.NoError(t, a.Close())
+	_, _, cached := a.decisionCache.get([sha256.Size]byte{1})
+	require.False(t, cached, "expected the decision cache to be cleared")
+	require.Empty(t, a.denialDeduplicator.cache.Keys())
+	require.True(t, a.circuitBreakers.allow(logicalcluster.New("root:provider")), "expected the circuit breaker to be closed")
+
+	require.NoError(t, a.Close(), "expected Close to be idempotent")
+}
+
+func TestMaximalPermissionPolicyAuthorizerExemptGroups(t *testing.T) {
+	kubeInformers := kcpkubernetesinformers.NewSharedInformerFactory(kcpfakeclient.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+	kcpInformers := kcpinformers.NewSharedInformerFactory(kcpfakeinformerclient.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+	defaults, err := NewMaximalPermissionPolicyAuthorizer(kubeInformers, kcpInformers, &recordingAuthorizer{})
+	require.NoError(t, err)
+	require.Equal(t, []string{user.SystemPrivilegedGroup}, defaults.(*MaximalPermissionPolicyAuthorizer).exemptGroups.List(), "expected system:masters to be exempt by default")
+
+	for _, tt := range []struct {
+		name         string
+		exemptGroups []string
+		groups       []string
+		wantDecision authorizer.Decision
+		wantExempt   string
+	}{
+		{name: "masters exempt", exemptGroups: []string{user.SystemPrivilegedGroup}, groups: []string{user.SystemPrivilegedGroup}, wantDecision: authorizer.DecisionAllow, wantExempt: user.SystemPrivilegedGroup},
+		{name: "custom group exempt", exemptGroups: []string{"system:kcp:internal"}, groups: []string{"group-1", "system:kcp:internal"}, wantDecision: authorizer.DecisionAllow, wantExempt: "system:kcp:internal"},
+		{name: "masters not exempt without default", exemptGroups: []string{"system:kcp:internal"}, groups: []string{user.SystemPrivilegedGroup}, wantDecision: authorizer.DecisionNoOpinion},
+		{name: "no exempt groups", exemptGroups: []string{}, groups: []string{user.SystemPrivilegedGroup}, wantDecision: authorizer.DecisionNoOpinion},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingAuthorizer{decision: authorizer.DecisionNoOpinion}
+			delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				inner, delegate,
+				WithExemptGroups(tt.exemptGroups),
+			)
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1", Groups: tt.groups},
+				Verb:            "delete",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, tt.wantExempt, ev.Annotations[MaximalPermissionPolicyAuditExemptGroup])
+			if tt.wantExempt != "" {
+				require.Nil(t, inner.recordedAttributes, "expected the maximal permission policy not to be evaluated")
+			} else {
+				require.NotNil(t, inner.recordedAttributes, "expected the maximal permission policy to be evaluated")
+			}
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerExemptNamespaces(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		namespace    string
+		resource     string
+		wantDecision authorizer.Decision
+		wantExempt   string
+	}{
+		{name: "exempt namespace", namespace: "kube-system", resource: "widgets", wantDecision: authorizer.DecisionAllow, wantExempt: "kube-system"},
+		{name: "namespace not exempt", namespace: "default", resource: "widgets", wantDecision: authorizer.DecisionNoOpinion},
+		{name: "cluster-scoped request", resource: "widgets", wantDecision: authorizer.DecisionNoOpinion},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingAuthorizer{decision: authorizer.DecisionNoOpinion}
+			delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				inner, delegate,
+				WithExemptNamespaces([]string{"kube-system", ""}),
+			)
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "delete",
+				Namespace:       tt.namespace,
+				APIGroup:        "widgets.example.io",
+				Resource:        tt.resource,
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, tt.wantExempt, ev.Annotations[MaximalPermissionPolicyAuditExemptNamespace])
+			if tt.wantExempt != "" {
+				require.Nil(t, inner.recordedAttributes, "expected the maximal permission policy not to be evaluated")
+			} else {
+				require.NotNil(t, inner.recordedAttributes, "expected the maximal permission policy to be evaluated")
+			}
+		})
+	}
+}
+
+// flakyAuthorizer fails with the given errors before it returns the decision.
+type flakyAuthorizer struct {
+	errs     []error
+	decision authorizer.Decision
+
+	attempts int
+}
+
+func (f *flakyAuthorizer) Authorize(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+	f.attempts++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return authorizer.DecisionNoOpinion, "", err
+	}
+	return f.decision, "", nil
+}
+
+func TestMaximalPermissionPolicyAuthorizerRBACRetry(t *testing.T) {
+	timeout := apierrors.NewTimeoutError("RBAC evaluation timed out", 1)
+	for _, tt := range []struct {
+		name         string
+		opts         []MaximalPermissionPolicyAuthorizerOption
+		errs         []error
+		wantDecision authorizer.Decision
+		wantErr      bool
+		wantAttempts int
+	}{
+		{name: "no retry by default", errs: []error{timeout}, wantDecision: authorizer.DecisionNoOpinion, wantErr: true, wantAttempts: 1},
+		{name: "transient error then success", opts: []MaximalPermissionPolicyAuthorizerOption{WithRBACRetry(3, time.Millisecond)}, errs: []error{timeout, timeout}, wantDecision: authorizer.DecisionAllow, wantAttempts: 3},
+		{name: "transient errors exceeding attempts", opts: []MaximalPermissionPolicyAuthorizerOption{WithRBACRetry(2, time.Millisecond)}, errs: []error{timeout, timeout, timeout}, wantDecision: authorizer.DecisionNoOpinion, wantErr: true, wantAttempts: 2},
+		{name: "non-transient error not retried", opts: []MaximalPermissionPolicyAuthorizerOption{WithRBACRetry(3, time.Millisecond)}, errs: []error{fmt.Errorf("boom")}, wantDecision: authorizer.DecisionNoOpinion, wantErr: true, wantAttempts: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &flakyAuthorizer{errs: tt.errs, decision: authorizer.DecisionAllow}
+			delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				inner, delegate,
+				tt.opts...,
+			)
+
+			dec, _, err := a.Authorize(withCluster("root:consumer"), &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, tt.wantAttempts, inner.attempts)
+		})
+	}
+}
+
+// newBenchmarkAuthorizer returns an authorizer with the given numbers of API bindings in root:consumer and bound
+// resources per API binding, scanning the API bindings of the cluster, and allowing in RBAC and by the delegate.
+func newBenchmarkAuthorizer(b *testing.B, bindings, resources int) *MaximalPermissionPolicyAuthorizer {
+	b.Helper()
+
+	allow := authorizer.AuthorizerFunc(func(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+		return authorizer.DecisionAllow, "", nil
+	})
+	apiBindings := make([]*apisv1alpha1.APIBinding, 0, bindings)
+	apiExports := make([]*apisv1alpha1.APIExport, 0, bindings)
+	for i := 0; i < bindings; i++ {
+		name := fmt.Sprintf("export-%d", i)
+		boundResources := make([]apisv1alpha1.BoundAPIResource, 0, resources)
+		for j := 0; j < resources; j++ {
+			boundResources = append(boundResources, apisv1alpha1.BoundAPIResource{Group: name + ".example.io", Resource: fmt.Sprintf("resource-%d", j)})
+		}
+		apiBindings = append(apiBindings, newAPIBinding("root:consumer", name, "root:provider", name, boundResources...))
+		apiExports = append(apiExports, newAPIExport("root:provider", name, withLocalPolicy()))
+	}
+	return newTestMaximalPermissionPolicyAuthorizer(b, apiBindings, apiExports, allow, allow)
+}
+
+func BenchmarkAuthorize(b *testing.B) {
+	for _, bindings := range []int{10, 100, 1000} {
+		for _, resources := range []int{1, 10} {
+			a := newBenchmarkAuthorizer(b, bindings, resources)
+			ctx := withCluster("root:consumer")
+			bound := &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1", Groups: []string{"team-1"}},
+				Verb:            "get",
+				APIGroup:        fmt.Sprintf("export-%d.example.io", bindings-1),
+				Resource:        fmt.Sprintf("resource-%d", resources-1),
+				ResourceRequest: true,
+			}
+			unbound := &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1", Groups: []string{"team-1"}},
+				Verb:            "get",
+				Resource:        "configmaps",
+				ResourceRequest: true,
+			}
+
+			for _, bb := range []struct {
+				name string
+				attr authorizer.Attributes
+			}{
+				{name: "bound", attr: bound},
+				{name: "unbound", attr: unbound},
+			} {
+				attr := bb.attr
+				b.Run(fmt.Sprintf("%s/bindings=%d/resources=%d", bb.name, bindings, resources), func(b *testing.B) {
+					b.ReportAllocs()
+					for i := 0; i < b.N; i++ {
+						if dec, _, err := a.Authorize(ctx, attr); err != nil || dec != authorizer.DecisionAllow {
+							b.Fatalf("unexpected decision %v: %v", dec, err)
+						}
+					}
+				})
+			}
+		}
+	}
+}
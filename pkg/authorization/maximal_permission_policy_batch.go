@@ -0,0 +1,147 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+// authorizeBatch memoizes the binding matches, the API exports and the RBAC authorizers of the requests
+// of one AuthorizeBatch call. It is only used by a single goroutine.
+type authorizeBatch struct {
+	matches     map[batchMatchKey]batchMatch
+	exports     map[batchExportKey]batchExport
+	authorizers map[string]authorizer.Authorizer
+}
+
+// batchMatchKey is what the default binding matcher matches the requested resource by.
+type batchMatchKey struct {
+	clusterName                  logicalcluster.Name
+	group, resource, subresource string
+}
+
+type batchMatch struct {
+	match *APIBindingMatch
+	bound bool
+	err   error
+}
+
+// batchExportKey is what API exports are looked up by.
+type batchExportKey struct {
+	ref         apisv1alpha1.WorkspaceExportReference
+	clusterName logicalcluster.Name
+}
+
+type batchExport struct {
+	apiExport *apisv1alpha1.APIExport
+	found     bool
+	err       error
+}
+
+func newAuthorizeBatch() *authorizeBatch {
+	return &authorizeBatch{
+		matches:     map[batchMatchKey]batchMatch{},
+		exports:     map[batchExportKey]batchExport{},
+		authorizers: map[string]authorizer.Authorizer{},
+	}
+}
+
+// batchFrom returns the batch of the context, or nil outside of AuthorizeBatch.
+func batchFrom(ctx context.Context) *authorizeBatch {
+	batch, _ := ctx.Value(authorizeBatchKey).(*authorizeBatch)
+	return batch
+}
+
+// AuthorizeBatch authorizes the given requests to the cluster of the context like Authorize, returning the decisions
+// and reasons in the order of the requests, and the aggregated errors. Requests for resources bound to the same
+// API export share the binding match, the API export lookup and the RBAC authorizer of the API export cluster,
+// e.g. for controllers checking many permissions at once.
+func (a *MaximalPermissionPolicyAuthorizer) AuthorizeBatch(ctx context.Context, attrs []authorizer.Attributes) ([]authorizer.Decision, []string, error) {
+	ctx = context.WithValue(ctx, authorizeBatchKey, newAuthorizeBatch())
+
+	decisions := make([]authorizer.Decision, len(attrs))
+	reasons := make([]string, len(attrs))
+	var errs []error
+	for i, attr := range attrs {
+		var err error
+		decisions[i], reasons[i], err = a.authorizeAndRecord(ctx, attr, &MaximalPermissionPolicyDecisionDetails{})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return decisions, reasons, utilerrors.NewAggregate(errs)
+}
+
+// matchAPIBinding matches the requested resource with the binding matcher, once per batch for the default matcher.
+func (a *MaximalPermissionPolicyAuthorizer) matchAPIBinding(ctx context.Context, attr authorizer.Attributes, clusterName logicalcluster.Name) (*APIBindingMatch, bool, error) {
+	batch := batchFrom(ctx)
+	if batch == nil || a.customBindingMatcher {
+		return a.bindingMatcher.MatchAPIBinding(attr, clusterName)
+	}
+
+	key := batchMatchKey{clusterName: clusterName, group: attr.GetAPIGroup(), resource: attr.GetResource(), subresource: attr.GetSubresource()}
+	m, ok := batch.matches[key]
+	if !ok {
+		m.match, m.bound, m.err = a.bindingMatcher.MatchAPIBinding(attr, clusterName)
+		batch.matches[key] = m
+	}
+	return m.match, m.bound, m.err
+}
+
+// resolveAPIExport returns the API export of the reference, looking it up once per batch unless forced fresh.
+func (a *MaximalPermissionPolicyAuthorizer) resolveAPIExport(ctx context.Context, exportRef *apisv1alpha1.ExportReference, exportClusterName logicalcluster.Name) (*apisv1alpha1.APIExport, bool, error) {
+	batch := batchFrom(ctx)
+	if batch == nil || exportRef.Workspace == nil || isForceFresh(ctx) {
+		return a.getAPIExportByReference(exportRef, exportClusterName)
+	}
+
+	key := batchExportKey{ref: *exportRef.Workspace, clusterName: exportClusterName}
+	e, ok := batch.exports[key]
+	if !ok {
+		e.apiExport, e.found, e.err = a.getAPIExportByReference(exportRef, exportClusterName)
+		batch.exports[key] = e
+	}
+	return e.apiExport, e.found, e.err
+}
+
+// clusterAuthorizer returns the RBAC authorizer of the API export cluster, creating it once per batch.
+func (a *MaximalPermissionPolicyAuthorizer) clusterAuthorizer(ctx context.Context, clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+	batch := batchFrom(ctx)
+	if batch == nil {
+		return a.newAuthorizer(clusterName, mergeClusters)
+	}
+
+	names := []string{clusterName.String()}
+	for _, mergeCluster := range mergeClusters {
+		names = append(names, mergeCluster.String())
+	}
+	key := strings.Join(names, ",")
+	clusterAuthorizer, ok := batch.authorizers[key]
+	if !ok {
+		clusterAuthorizer = a.newAuthorizer(clusterName, mergeClusters)
+		batch.authorizers[key] = clusterAuthorizer
+	}
+	return clusterAuthorizer
+}
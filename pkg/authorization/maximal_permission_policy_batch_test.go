@@ -0,0 +1,180 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+// lookupCounts counts the binding matches, API export lookups and RBAC authorizers created by an authorizer.
+type lookupCounts struct {
+	matches, exports, authorizers int
+}
+
+// newBatchTestAuthorizer returns an authorizer for widgets and gadgets of root:provider, allowing only get of widgets.
+func newBatchTestAuthorizer(tb testing.TB) (*MaximalPermissionPolicyAuthorizer, *lookupCounts) {
+	tb.Helper()
+
+	inner := authorizer.AuthorizerFunc(func(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+		if attr.GetResource() == "widgets" && attr.GetVerb() == "get" {
+			return authorizer.DecisionAllow, "", nil
+		}
+		return authorizer.DecisionNoOpinion, "", nil
+	})
+	a := newTestMaximalPermissionPolicyAuthorizer(tb,
+		[]*apisv1alpha1.APIBinding{
+			newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+				apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+			),
+			newAPIBinding("root:consumer", "gadgets", "root:provider", "gadgets",
+				apisv1alpha1.BoundAPIResource{Group: "gadgets.example.io", Resource: "gadgets"},
+			),
+		},
+		[]*apisv1alpha1.APIExport{
+			newAPIExport("root:provider", "widgets", withLocalPolicy()),
+			newAPIExport("root:provider", "gadgets", withLocalPolicy()),
+		},
+		inner, &recordingAuthorizer{decision: authorizer.DecisionAllow},
+	)
+
+	counts := &lookupCounts{}
+	bindingMatcher, getAPIExportByReference, newAuthorizer := a.bindingMatcher, a.getAPIExportByReference, a.newAuthorizer
+	a.bindingMatcher = BindingMatcherFunc(func(attr authorizer.Attributes, clusterName logicalcluster.Name) (*APIBindingMatch, bool, error) {
+		counts.matches++
+		return bindingMatcher.MatchAPIBinding(attr, clusterName)
+	})
+	a.getAPIExportByReference = func(exportRef *apisv1alpha1.ExportReference, exportClusterName logicalcluster.Name) (*apisv1alpha1.APIExport, bool, error) {
+		counts.exports++
+		return getAPIExportByReference(exportRef, exportClusterName)
+	}
+	a.newAuthorizer = func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+		counts.authorizers++
+		return newAuthorizer(clusterName, mergeClusters)
+	}
+	return a, counts
+}
+
+// batchTestAttributes returns n requests alternating between the verbs and resources of newBatchTestAuthorizer.
+func batchTestAttributes(n int) []authorizer.Attributes {
+	attrs := make([]authorizer.Attributes, 0, n)
+	for i := 0; i < n; i++ {
+		group, resource := "widgets.example.io", "widgets"
+		if i%3 == 2 {
+			group, resource = "gadgets.example.io", "gadgets"
+		}
+		verb := "get"
+		if i%2 == 1 {
+			verb = "delete"
+		}
+		attrs = append(attrs, &authorizer.AttributesRecord{
+			User:            &user.DefaultInfo{Name: "user-1"},
+			Verb:            verb,
+			APIGroup:        group,
+			Resource:        resource,
+			Name:            fmt.Sprintf("name-%d", i),
+			ResourceRequest: true,
+		})
+	}
+	return attrs
+}
+
+func TestMaximalPermissionPolicyAuthorizerAuthorizeBatch(t *testing.T) {
+	a, counts := newBatchTestAuthorizer(t)
+	attrs := append(batchTestAttributes(6), &authorizer.AttributesRecord{
+		User:            &user.DefaultInfo{Name: "user-1"},
+		Verb:            "delete",
+		APIGroup:        "other.example.io",
+		Resource:        "others",
+		ResourceRequest: true,
+	})
+
+	var wantDecisions []authorizer.Decision
+	var wantReasons []string
+	for _, attr := range attrs {
+		dec, reason, err := a.Authorize(withCluster("root:consumer"), attr)
+		require.NoError(t, err)
+		wantDecisions = append(wantDecisions, dec)
+		wantReasons = append(wantReasons, reason)
+	}
+	require.Equal(t, []authorizer.Decision{
+		authorizer.DecisionAllow, authorizer.DecisionNoOpinion, authorizer.DecisionNoOpinion,
+		authorizer.DecisionNoOpinion, authorizer.DecisionAllow, authorizer.DecisionNoOpinion,
+		authorizer.DecisionAllow,
+	}, wantDecisions)
+
+	*counts = lookupCounts{}
+	decisions, reasons, err := a.AuthorizeBatch(withCluster("root:consumer"), attrs)
+	require.NoError(t, err)
+	require.Equal(t, wantDecisions, decisions, "expected the decisions of Authorize in the order of the requests")
+	require.Equal(t, wantReasons, reasons)
+	require.Equal(t, lookupCounts{matches: 3, exports: 2, authorizers: 1}, *counts)
+
+	*counts = lookupCounts{}
+	decisions, _, err = a.AuthorizeBatch(WithForceFresh(withCluster("root:consumer")), attrs)
+	require.NoError(t, err)
+	require.Equal(t, wantDecisions, decisions)
+	require.Equal(t, lookupCounts{matches: 3, exports: 6, authorizers: 1}, *counts, "expected an API export lookup per bound request when forced fresh")
+}
+
+func TestMaximalPermissionPolicyAuthorizerAuthorizeBatchErrors(t *testing.T) {
+	a, _ := newBatchTestAuthorizer(t)
+
+	decisions, reasons, err := a.AuthorizeBatch(context.Background(), batchTestAttributes(2))
+	require.Error(t, err, "expected an error without cluster")
+	require.Equal(t, []authorizer.Decision{authorizer.DecisionNoOpinion, authorizer.DecisionNoOpinion}, decisions)
+	require.Len(t, reasons, 2)
+
+	decisions, _, err = a.AuthorizeBatch(withCluster("root:consumer"), nil)
+	require.NoError(t, err)
+	require.Empty(t, decisions)
+}
+
+func BenchmarkMaximalPermissionPolicyAuthorizerAuthorizeBatch(b *testing.B) {
+	attrs := batchTestAttributes(100)
+
+	b.Run("loop", func(b *testing.B) {
+		a, counts := newBatchTestAuthorizer(b)
+		ctx := withCluster("root:consumer")
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, attr := range attrs {
+				a.Authorize(ctx, attr) //nolint:errcheck
+			}
+		}
+		b.ReportMetric(float64(counts.matches+counts.exports)/float64(b.N), "lookups/op")
+	})
+
+	b.Run("batch", func(b *testing.B) {
+		a, counts := newBatchTestAuthorizer(b)
+		ctx := withCluster("root:consumer")
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			a.AuthorizeBatch(ctx, attrs) //nolint:errcheck
+		}
+		b.ReportMetric(float64(counts.matches+counts.exports)/float64(b.N), "lookups/op")
+	})
+}
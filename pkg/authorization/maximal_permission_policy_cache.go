@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"strings"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+const (
+	// rbacAuthorizerCacheSize is the maximum number of RBAC authorizers cached per API export cluster and merge clusters.
+	rbacAuthorizerCacheSize = 1024
+	// rbacAuthorizerCacheTTL is the time RBAC authorizers are cached. They read RBAC through informers, hence never get stale.
+	rbacAuthorizerCacheTTL = time.Hour
+)
+
+// rbacAuthorizerCache caches the RBAC authorizers the maximal permission policy is evaluated with,
+// keyed by API export cluster and merge clusters.
+type rbacAuthorizerCache struct {
+	cache         *utilcache.LRUExpireCache
+	newAuthorizer func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer
+}
+
+func newRBACAuthorizerCache(newAuthorizer func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer) *rbacAuthorizerCache {
+	return &rbacAuthorizerCache{
+		cache:         utilcache.NewLRUExpireCache(rbacAuthorizerCacheSize),
+		newAuthorizer: newAuthorizer,
+	}
+}
+
+// get returns the cached RBAC authorizer of the given cluster and merge clusters, constructing it if missing.
+func (c *rbacAuthorizerCache) get(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+	keys := make([]string, 0, len(mergeClusters)+1)
+	keys = append(keys, clusterName.String())
+	for _, mergeCluster := range mergeClusters {
+		keys = append(keys, mergeCluster.String())
+	}
+	key := strings.Join(keys, "|")
+
+	if a, ok := c.cache.Get(key); ok {
+		return a.(authorizer.Authorizer)
+	}
+	a := c.newAuthorizer(clusterName, mergeClusters)
+	c.cache.Add(key, a, rbacAuthorizerCacheTTL)
+	return a
+}
+
+// clear removes all cached RBAC authorizers.
+func (c *rbacAuthorizerCache) clear() {
+	clearLRUExpireCache(c.cache)
+}
+
+// clearLRUExpireCache removes all entries of the cache.
+func clearLRUExpireCache(cache *utilcache.LRUExpireCache) {
+	for _, key := range cache.Keys() {
+		cache.Remove(key)
+	}
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/kubernetes/pkg/genericcontrolplane"
+)
+
+func TestRBACAuthorizerCache(t *testing.T) {
+	var constructed int
+	c := newRBACAuthorizerCache(func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+		constructed++
+		return &recordingAuthorizer{}
+	})
+
+	provider := logicalcluster.New("root:provider")
+	first := c.get(provider, []logicalcluster.Name{genericcontrolplane.LocalAdminCluster})
+	require.Same(t, first, c.get(provider, []logicalcluster.Name{genericcontrolplane.LocalAdminCluster}))
+	require.Equal(t, 1, constructed)
+
+	require.NotSame(t, first, c.get(provider, nil), "expected different merge clusters not to share an authorizer")
+	require.Equal(t, 2, constructed)
+}
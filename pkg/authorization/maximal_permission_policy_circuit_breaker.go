@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/utils/clock"
+)
+
+// circuitBreakers stop evaluating the RBAC of an API export cluster for a cooldown after consecutive
+// evaluation errors, e.g. because of corrupt RBAC data. After the cooldown, a single evaluation probes
+// whether the errors persist (half-open): it closes the breaker on success and reopens it on error.
+type circuitBreakers struct {
+	threshold    int
+	window       time.Duration
+	cooldown     time.Duration
+	openDecision authorizer.Decision
+	clock        clock.PassiveClock
+
+	lock     sync.Mutex
+	breakers map[logicalcluster.Name]*circuitBreaker
+}
+
+// circuitBreaker is the state of the breaker of an API export cluster with recent errors.
+type circuitBreaker struct {
+	// failures is the number of consecutive errors since firstFailure.
+	failures     int
+	firstFailure time.Time
+	// openedAt is the time the breaker opened, zero if closed.
+	openedAt time.Time
+	// probing is set while the probe of a half-open breaker is in flight.
+	probing bool
+}
+
+func newCircuitBreakers(threshold int, window, cooldown time.Duration, openDecision authorizer.Decision, clock clock.PassiveClock) *circuitBreakers {
+	return &circuitBreakers{
+		threshold:    threshold,
+		window:       window,
+		cooldown:     cooldown,
+		openDecision: openDecision,
+		clock:        clock,
+		breakers:     map[logicalcluster.Name]*circuitBreaker{},
+	}
+}
+
+// allow returns whether the RBAC of the given cluster may be evaluated, i.e. the breaker is closed,
+// or half-open without a probe in flight. The caller must call done with the result of the evaluation.
+func (c *circuitBreakers) allow(clusterName logicalcluster.Name) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	b, ok := c.breakers[clusterName]
+	if !ok || b.openedAt.IsZero() {
+		return true
+	}
+	if c.clock.Since(b.openedAt) < c.cooldown || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// done records the result of an RBAC evaluation of the given cluster. It returns true if the breaker opened.
+func (c *circuitBreakers) done(clusterName logicalcluster.Name, err error) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	b, ok := c.breakers[clusterName]
+	if err == nil {
+		delete(c.breakers, clusterName)
+		return false
+	}
+	if !ok {
+		b = &circuitBreaker{}
+		c.breakers[clusterName] = b
+	}
+
+	now := c.clock.Now()
+	if b.probing {
+		b.probing = false
+		b.openedAt = now
+		return true
+	}
+	if !b.openedAt.IsZero() {
+		// an evaluation started before the breaker opened
+		return false
+	}
+	if b.failures == 0 || now.Sub(b.firstFailure) > c.window {
+		b.failures, b.firstFailure = 0, now
+	}
+	b.failures++
+	if b.failures < c.threshold {
+		return false
+	}
+	b.openedAt = now
+	return true
+}
+
+// reset closes all breakers.
+func (c *circuitBreakers) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.breakers = map[logicalcluster.Name]*circuitBreaker{}
+}
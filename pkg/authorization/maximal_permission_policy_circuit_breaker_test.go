@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/testutil"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestMaximalPermissionPolicyAuthorizerCircuitBreaker(t *testing.T) {
+	inner := &recordingAuthorizer{err: errors.New("corrupt RBAC data")}
+	a := newConcurrencyTestAuthorizer(t, inner, nil,
+		WithCircuitBreaker(3, time.Minute, time.Minute, authorizer.DecisionNoOpinion),
+		WithMetrics(metrics.NewKubeRegistry()),
+	)
+	evaluations := 0
+	newAuthorizer := a.newAuthorizer
+	a.newAuthorizer = func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+		evaluations++
+		return newAuthorizer(clusterName, mergeClusters)
+	}
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	a.circuitBreakers.clock = fakeClock
+
+	opensBefore, err := testutil.GetCounterMetricValue(circuitBreakerOpens)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		dec, reason, err := authorizeConcurrencyTestRequest(a, "widgets.example.io", "widgets")
+		require.Error(t, err)
+		require.Equal(t, authorizer.DecisionNoOpinion, dec)
+		require.Equal(t, ReasonRBACEvaluationFailed, reason)
+	}
+	require.Equal(t, 3, evaluations)
+
+	opensAfter, err := testutil.GetCounterMetricValue(circuitBreakerOpens)
+	require.NoError(t, err)
+	require.Equal(t, 1.0, opensAfter-opensBefore)
+
+	// the breaker is open, the RBAC is not evaluated
+	dec, reason, err := authorizeConcurrencyTestRequest(a, "widgets.example.io", "widgets")
+	require.NoError(t, err)
+	require.Equal(t, authorizer.DecisionNoOpinion, dec)
+	require.Equal(t, ReasonCircuitBreakerOpen, reason)
+	require.Equal(t, 3, evaluations)
+
+	// half-open after the cooldown, the failing probe reopens the breaker
+	fakeClock.Step(time.Minute)
+	dec, reason, err = authorizeConcurrencyTestRequest(a, "widgets.example.io", "widgets")
+	require.Error(t, err)
+	require.Equal(t, authorizer.DecisionNoOpinion, dec)
+	require.Equal(t, ReasonRBACEvaluationFailed, reason)
+	require.Equal(t, 4, evaluations)
+
+	_, reason, err = authorizeConcurrencyTestRequest(a, "widgets.example.io", "widgets")
+	require.NoError(t, err)
+	require.Equal(t, ReasonCircuitBreakerOpen, reason)
+	require.Equal(t, 4, evaluations)
+
+	// the successful probe closes the breaker
+	fakeClock.Step(time.Minute)
+	inner.err = nil
+	inner.decision = authorizer.DecisionAllow
+	for i := 0; i < 2; i++ {
+		dec, _, err = authorizeConcurrencyTestRequest(a, "widgets.example.io", "widgets")
+		require.NoError(t, err)
+		require.Equal(t, authorizer.DecisionAllow, dec)
+	}
+	require.Equal(t, 6, evaluations)
+}
+
+func TestCircuitBreakersWindow(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	c := newCircuitBreakers(2, time.Minute, time.Minute, authorizer.DecisionDeny, fakeClock)
+	cluster := logicalcluster.New("root:provider")
+	evalErr := errors.New("failed")
+
+	require.False(t, c.done(cluster, evalErr))
+	fakeClock.Step(2 * time.Minute)
+	require.False(t, c.done(cluster, evalErr), "expected errors outside of the window to start over")
+	require.True(t, c.allow(cluster))
+
+	require.False(t, c.done(cluster, nil))
+	require.False(t, c.done(cluster, evalErr), "expected a success to reset the errors")
+	require.True(t, c.done(cluster, evalErr))
+	require.False(t, c.allow(cluster))
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+)
+
+// errRBACConcurrencyLimitTimeout is returned when the RBAC evaluation of an API export cluster
+// could not start within the timeout because of the concurrency limit.
+var errRBACConcurrencyLimitTimeout = errors.New("timed out waiting for concurrent RBAC evaluations")
+
+// clusterSemaphores limits the number of concurrent RBAC evaluations per API export cluster.
+// The semaphores of clusters without evaluations, running or waiting, are dropped.
+type clusterSemaphores struct {
+	limit   int
+	timeout time.Duration
+
+	lock       sync.Mutex
+	semaphores map[logicalcluster.Name]*clusterSemaphore
+}
+
+// clusterSemaphore holds the slots of the RBAC evaluations of a cluster, and counts the evaluations holding or
+// waiting for one of them.
+type clusterSemaphore struct {
+	slots chan struct{}
+	users int
+}
+
+// newClusterSemaphores returns semaphores of the given positive limit.
+func newClusterSemaphores(limit int, timeout time.Duration) *clusterSemaphores {
+	return &clusterSemaphores{
+		limit:      limit,
+		timeout:    timeout,
+		semaphores: map[logicalcluster.Name]*clusterSemaphore{},
+	}
+}
+
+// acquire waits for a free slot of the given cluster, at most for the timeout. It returns a function releasing
+// the slot, or errRBACConcurrencyLimitTimeout respectively the context error if no slot was acquired.
+func (s *clusterSemaphores) acquire(ctx context.Context, clusterName logicalcluster.Name) (func(), error) {
+	s.lock.Lock()
+	semaphore, ok := s.semaphores[clusterName]
+	if !ok {
+		semaphore = &clusterSemaphore{slots: make(chan struct{}, s.limit)}
+		s.semaphores[clusterName] = semaphore
+	}
+	semaphore.users++
+	s.lock.Unlock()
+
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+	select {
+	case semaphore.slots <- struct{}{}:
+		return func() {
+			<-semaphore.slots
+			s.done(clusterName, semaphore)
+		}, nil
+	case <-timer.C:
+		s.done(clusterName, semaphore)
+		return nil, errRBACConcurrencyLimitTimeout
+	case <-ctx.Done():
+		s.done(clusterName, semaphore)
+		return nil, ctx.Err()
+	}
+}
+
+// done drops the semaphore of the given cluster after its last evaluation.
+func (s *clusterSemaphores) done(clusterName logicalcluster.Name, semaphore *clusterSemaphore) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	semaphore.users--
+	if semaphore.users == 0 {
+		delete(s.semaphores, clusterName)
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+// blockingAuthorizer allows once unblocked, recording the maximum number of concurrent calls.
+type blockingAuthorizer struct {
+	entered chan struct{}
+	unblock chan struct{}
+
+	lock          sync.Mutex
+	current, peak int
+}
+
+func newBlockingAuthorizer() *blockingAuthorizer {
+	return &blockingAuthorizer{entered: make(chan struct{}, 100), unblock: make(chan struct{})}
+}
+
+func (b *blockingAuthorizer) Authorize(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+	b.lock.Lock()
+	b.current++
+	if b.current > b.peak {
+		b.peak = b.current
+	}
+	b.lock.Unlock()
+	b.entered <- struct{}{}
+
+	<-b.unblock
+
+	b.lock.Lock()
+	b.current--
+	b.lock.Unlock()
+	return authorizer.DecisionAllow, "", nil
+}
+
+// newConcurrencyTestAuthorizer returns an authorizer evaluating the RBAC of root:provider-1 and root:provider-2
+// with the given inner authorizers.
+func newConcurrencyTestAuthorizer(t *testing.T, inner, otherInner authorizer.Authorizer, opts ...MaximalPermissionPolicyAuthorizerOption) *MaximalPermissionPolicyAuthorizer {
+	t.Helper()
+
+	opts = append([]MaximalPermissionPolicyAuthorizerOption{
+		WithClusterAuthorizerFactory(func(clusterName logicalcluster.Name) authorizer.Authorizer {
+			if clusterName.String() == "root:provider-2" {
+				return otherInner
+			}
+			return inner
+		}),
+	}, opts...)
+	return newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{
+			newAPIBinding("root:consumer", "widgets", "root:provider-1", "widgets",
+				apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+			),
+			newAPIBinding("root:consumer", "gadgets", "root:provider-2", "gadgets",
+				apisv1alpha1.BoundAPIResource{Group: "gadgets.example.io", Resource: "gadgets"},
+			),
+		},
+		[]*apisv1alpha1.APIExport{
+			newAPIExport("root:provider-1", "widgets", withLocalPolicy()),
+			newAPIExport("root:provider-2", "gadgets", withLocalPolicy()),
+		},
+		nil,
+		authorizer.AuthorizerFunc(func(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+			return authorizer.DecisionAllow, "", nil
+		}),
+		opts...,
+	)
+}
+
+func authorizeConcurrencyTestRequest(a *MaximalPermissionPolicyAuthorizer, group, resource string) (authorizer.Decision, string, error) {
+	return a.Authorize(withCluster("root:consumer"), &authorizer.AttributesRecord{
+		User:            &user.DefaultInfo{Name: "user-1"},
+		Verb:            "get",
+		APIGroup:        group,
+		Resource:        resource,
+		ResourceRequest: true,
+	})
+}
+
+func TestMaximalPermissionPolicyAuthorizerRBACConcurrencyLimit(t *testing.T) {
+	inner := newBlockingAuthorizer()
+	otherInner := newBlockingAuthorizer()
+	close(otherInner.unblock)
+	a := newConcurrencyTestAuthorizer(t, inner, otherInner, WithRBACConcurrencyLimit(2, wait.ForeverTestTimeout))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dec, _, err := authorizeConcurrencyTestRequest(a, "widgets.example.io", "widgets")
+			require.NoError(t, err)
+			require.Equal(t, authorizer.DecisionAllow, dec)
+		}()
+	}
+
+	// two evaluations start, the others wait for them
+	for i := 0; i < 2; i++ {
+		<-inner.entered
+	}
+	select {
+	case <-inner.entered:
+		t.Fatal("expected at most two concurrent RBAC evaluations")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// another API export cluster has its own limit
+	dec, _, err := authorizeConcurrencyTestRequest(a, "gadgets.example.io", "gadgets")
+	require.NoError(t, err)
+	require.Equal(t, authorizer.DecisionAllow, dec)
+
+	close(inner.unblock)
+	wg.Wait()
+	require.Equal(t, 2, inner.peak)
+	require.Empty(t, a.rbacSemaphores.semaphores, "expected the semaphores of idle clusters to be dropped")
+}
+
+func TestMaximalPermissionPolicyAuthorizerRBACConcurrencyUnlimited(t *testing.T) {
+	for _, limit := range []int{0, -1} {
+		inner := newBlockingAuthorizer()
+		close(inner.unblock)
+		a := newConcurrencyTestAuthorizer(t, inner, nil, WithRBACConcurrencyLimit(limit, time.Millisecond))
+		require.Nil(t, a.rbacSemaphores, "expected limit %d to disable the limit", limit)
+
+		dec, _, err := authorizeConcurrencyTestRequest(a, "widgets.example.io", "widgets")
+		require.NoError(t, err)
+		require.Equal(t, authorizer.DecisionAllow, dec, "expected limit %d not to block RBAC evaluations", limit)
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerRBACConcurrencyTimeout(t *testing.T) {
+	inner := newBlockingAuthorizer()
+	a := newConcurrencyTestAuthorizer(t, inner, nil, WithRBACConcurrencyLimit(1, 10*time.Millisecond))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		authorizeConcurrencyTestRequest(a, "widgets.example.io", "widgets") //nolint:errcheck
+	}()
+	<-inner.entered
+
+	dec, reason, err := authorizeConcurrencyTestRequest(a, "widgets.example.io", "widgets")
+	require.NoError(t, err)
+	require.Equal(t, authorizer.DecisionNoOpinion, dec)
+	require.Equal(t, ReasonRBACEvaluationFailed, reason)
+
+	close(inner.unblock)
+	<-done
+
+	dec, _, err = authorizeConcurrencyTestRequest(a, "widgets.example.io", "widgets")
+	require.NoError(t, err)
+	require.Equal(t, authorizer.DecisionAllow, dec, "expected the slot to be released")
+	require.Empty(t, a.rbacSemaphores.semaphores, "expected the semaphores of idle clusters to be dropped")
+}
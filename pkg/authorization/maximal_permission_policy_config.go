@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"encoding/json"
+)
+
+// maximalPermissionPolicyAuthorizerConfig is the configuration of a MaximalPermissionPolicyAuthorizer
+// after applying defaults and options, as serialized by MarshalConfig. Fields are not omitted when empty,
+// such that a changed default shows up as a changed value.
+type maximalPermissionPolicyAuthorizerConfig struct {
+	DenialWarnings                        bool     `json:"denialWarnings"`
+	APIBindingScanLimit                   int      `json:"apiBindingScanLimit"`
+	APIBindingScanOverflowDecision        string   `json:"apiBindingScanOverflowDecision"`
+	ConsumerParentRBAC                    bool     `json:"consumerParentRBAC"`
+	InheritanceLookup                     bool     `json:"inheritanceLookup"`
+	VirtualResourceMatcher                bool     `json:"virtualResourceMatcher"`
+	PendingAPIBindingsNoOpinion           bool     `json:"pendingAPIBindingsNoOpinion"`
+	StrictEmptyPolicy                     bool     `json:"strictEmptyPolicy"`
+	VerbFilter                            bool     `json:"verbFilter"`
+	MultiExportPolicy                     string   `json:"multiExportPolicy"`
+	DenialRecorder                        bool     `json:"denialRecorder"`
+	FallbackClusters                      []string `json:"fallbackClusters"`
+	Tracing                               bool     `json:"tracing"`
+	CustomBindingMatcher                  bool     `json:"customBindingMatcher"`
+	IncompleteRequestInfoDecision         string   `json:"incompleteRequestInfoDecision"`
+	ExplicitRBACVerbs                     bool     `json:"explicitRBACVerbs"`
+	CrossShardExportCacheTTL              string   `json:"crossShardExportCacheTTL"`
+	CrossShardExportTimeout               string   `json:"crossShardExportTimeout"`
+	RBACRetryAttempts                     int      `json:"rbacRetryAttempts"`
+	RBACRetryBackoff                      string   `json:"rbacRetryBackoff"`
+	DenialDeduplicationWindow             string   `json:"denialDeduplicationWindow"`
+	CollectionGetAsList                   bool     `json:"collectionGetAsList"`
+	WithoutAdminClusterRBACMergeByDefault bool     `json:"withoutAdminClusterRBACMergeByDefault"`
+	ExemptGroups                          []string `json:"exemptGroups"`
+	ExemptNamespaces                      []string `json:"exemptNamespaces"`
+	DecisionLogs                          bool     `json:"decisionLogs"`
+	RBACConcurrencyLimit                  int      `json:"rbacConcurrencyLimit"`
+	RBACConcurrencyTimeout                string   `json:"rbacConcurrencyTimeout"`
+	UserGroupPrefix                       string   `json:"userGroupPrefix"`
+	UserNameNormalizer                    bool     `json:"userNameNormalizer"`
+	IdentityRewriter                      bool     `json:"identityRewriter"`
+	Metrics                               bool     `json:"metrics"`
+	DecisionCacheSize                     int      `json:"decisionCacheSize"`
+	DecisionCacheTTL                      string   `json:"decisionCacheTTL"`
+	WarningHandler                        bool     `json:"warningHandler"`
+	TerminalDecision                      bool     `json:"terminalDecision"`
+	FailurePolicy                         string   `json:"failurePolicy"`
+	WithoutEnforcement                    bool     `json:"withoutEnforcement"`
+	ClusterAuthorizerFactory              bool     `json:"clusterAuthorizerFactory"`
+	CircuitBreakerThreshold               int      `json:"circuitBreakerThreshold"`
+	CircuitBreakerWindow                  string   `json:"circuitBreakerWindow"`
+	CircuitBreakerCooldown                string   `json:"circuitBreakerCooldown"`
+	CircuitBreakerDecision                string   `json:"circuitBreakerDecision"`
+}
+
+// MarshalConfig returns a stable JSON serialization of the configuration of the authorizer
+// after applying defaults and options. It is meant for detecting changes of defaults across releases.
+func (a *MaximalPermissionPolicyAuthorizer) MarshalConfig() ([]byte, error) {
+	config := maximalPermissionPolicyAuthorizerConfig{
+		DenialWarnings:                        a.denialWarnings,
+		APIBindingScanLimit:                   a.apiBindingScanLimit,
+		ConsumerParentRBAC:                    a.consumerParentRBAC,
+		InheritanceLookup:                     a.inheritanceLookup != nil,
+		VirtualResourceMatcher:                a.virtualResourceMatcher != nil,
+		PendingAPIBindingsNoOpinion:           a.pendingAPIBindingsNoOpinion,
+		StrictEmptyPolicy:                     a.strictEmptyPolicy,
+		VerbFilter:                            a.verbFilter != nil,
+		MultiExportPolicy:                     string(FirstMatch),
+		DenialRecorder:                        a.denialRecorder != nil,
+		Tracing:                               a.tracer != nil,
+		CustomBindingMatcher:                  a.customBindingMatcher,
+		IncompleteRequestInfoDecision:         DecisionString(a.incompleteRequestInfoDecision),
+		ExplicitRBACVerbs:                     a.explicitRBACVerbs,
+		CollectionGetAsList:                   a.collectionGetAsList,
+		WithoutAdminClusterRBACMergeByDefault: a.withoutAdminClusterRBACMergeByDefault,
+		ExemptGroups:                          a.exemptGroups.List(),
+		ExemptNamespaces:                      a.exemptNamespaces.List(),
+		DecisionLogs:                          a.decisionLogs,
+		UserGroupPrefix:                       a.rbacUserGroupPrefix(),
+		UserNameNormalizer:                    a.userNameNormalizer != nil,
+		IdentityRewriter:                      a.identityRewriter != nil,
+		Metrics:                               a.metrics,
+		WarningHandler:                        a.warningHandler != nil,
+		TerminalDecision:                      a.terminalDecision,
+		FailurePolicy:                         string(FailOpen),
+		WithoutEnforcement:                    a.withoutEnforcement,
+		ClusterAuthorizerFactory:              a.clusterAuthorizerFactory != nil,
+		FallbackClusters:                      []string{},
+		RBACRetryAttempts:                     1,
+	}
+	if a.multiExportPolicy != "" {
+		config.MultiExportPolicy = string(a.multiExportPolicy)
+	}
+	if a.failurePolicy != "" {
+		config.FailurePolicy = string(a.failurePolicy)
+	}
+	if a.apiBindingScanLimit > 0 {
+		config.APIBindingScanOverflowDecision = DecisionString(a.apiBindingScanOverflowDecision)
+	}
+	if a.crossShardExportResolver != nil {
+		config.CrossShardExportCacheTTL = a.crossShardExportResolver.ttl.String()
+		config.CrossShardExportTimeout = a.crossShardExportResolver.timeout.String()
+	}
+	if a.rbacRetryAttempts > 1 {
+		config.RBACRetryAttempts = a.rbacRetryAttempts
+		config.RBACRetryBackoff = a.rbacRetryBackoff.String()
+	}
+	if a.denialDeduplicator != nil {
+		config.DenialDeduplicationWindow = a.denialDeduplicator.window.String()
+	}
+	if a.rbacSemaphores != nil {
+		config.RBACConcurrencyLimit = a.rbacSemaphores.limit
+		config.RBACConcurrencyTimeout = a.rbacSemaphores.timeout.String()
+	}
+	if a.decisionCache != nil {
+		config.DecisionCacheSize = a.decisionCache.size
+		config.DecisionCacheTTL = a.decisionCache.ttl.String()
+	}
+	if a.circuitBreakers != nil {
+		config.CircuitBreakerThreshold = a.circuitBreakers.threshold
+		config.CircuitBreakerWindow = a.circuitBreakers.window.String()
+		config.CircuitBreakerCooldown = a.circuitBreakers.cooldown.String()
+		config.CircuitBreakerDecision = DecisionString(a.circuitBreakers.openDecision)
+	}
+	for _, cluster := range a.fallbackClusters {
+		config.FallbackClusters = append(config.FallbackClusters, cluster.String())
+	}
+	return json.MarshalIndent(config, "", "  ")
+}
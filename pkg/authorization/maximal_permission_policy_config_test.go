@@ -0,0 +1,142 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	kcpfakeclient "github.com/kcp-dev/client-go/clients/clientset/versioned/fake"
+	kcpkubernetesinformers "github.com/kcp-dev/client-go/clients/informers"
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/component-base/metrics"
+	"k8s.io/kubernetes/pkg/controller"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	kcpfakeinformerclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/fake"
+	kcpinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
+)
+
+func TestNewMaximalPermissionPolicyAuthorizerInvalidOptions(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		opt     MaximalPermissionPolicyAuthorizerOption
+		wantErr string
+	}{
+		{name: "API binding scan overflow allowed", opt: WithAPIBindingScanLimit(10, authorizer.DecisionAllow), wantErr: "API binding scan overflow decision must not be DecisionAllow"},
+		{name: "incomplete request info allowed", opt: WithIncompleteRequestInfoDecision(authorizer.DecisionAllow), wantErr: "incomplete request info decision must not be DecisionAllow"},
+		{name: "open circuit breaker allowed", opt: WithCircuitBreaker(3, time.Minute, time.Minute, authorizer.DecisionAllow), wantErr: "circuit breaker decision must not be DecisionAllow"},
+		{name: "empty decision cache", opt: WithDecisionCache(0, time.Minute), wantErr: "decision cache size and TTL must be positive, got size 0 and TTL 1m0s"},
+		{name: "decision cache without TTL", opt: WithDecisionCache(10, 0), wantErr: "decision cache size and TTL must be positive, got size 10 and TTL 0s"},
+		{name: "circuit breaker without threshold", opt: WithCircuitBreaker(0, time.Minute, time.Minute, authorizer.DecisionNoOpinion), wantErr: "circuit breaker threshold, window and cooldown must be positive, got threshold 0, window 1m0s and cooldown 1m0s"},
+		{name: "circuit breaker with negative threshold", opt: WithCircuitBreaker(-1, time.Minute, time.Minute, authorizer.DecisionNoOpinion), wantErr: "circuit breaker threshold, window and cooldown must be positive, got threshold -1, window 1m0s and cooldown 1m0s"},
+		{name: "circuit breaker without window", opt: WithCircuitBreaker(3, 0, time.Minute, authorizer.DecisionNoOpinion), wantErr: "circuit breaker threshold, window and cooldown must be positive, got threshold 3, window 0s and cooldown 1m0s"},
+		{name: "circuit breaker without cooldown", opt: WithCircuitBreaker(3, time.Minute, 0, authorizer.DecisionNoOpinion), wantErr: "circuit breaker threshold, window and cooldown must be positive, got threshold 3, window 1m0s and cooldown 0s"},
+		{name: "unknown failure policy", opt: WithFailurePolicy("FailSometimes"), wantErr: `failure policy must be FailOpen or FailClosed, got "FailSometimes"`},
+		{name: "empty failure policy", opt: WithFailurePolicy(""), wantErr: `failure policy must be FailOpen or FailClosed, got ""`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeInformers := kcpkubernetesinformers.NewSharedInformerFactory(kcpfakeclient.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+			kcpInformers := kcpinformers.NewSharedInformerFactory(kcpfakeinformerclient.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+			a, err := NewMaximalPermissionPolicyAuthorizer(kubeInformers, kcpInformers, &recordingAuthorizer{}, tt.opt)
+			require.ErrorContains(t, err, tt.wantErr)
+			require.Nil(t, a)
+		})
+	}
+}
+
+// TestMaximalPermissionPolicyAuthorizerConfig guards against unnoticed changes of defaults. If a default
+// or option changes intentionally, update the golden files by running the test with UPDATE_GOLDEN=true.
+func TestMaximalPermissionPolicyAuthorizerConfig(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts []MaximalPermissionPolicyAuthorizerOption
+	}{
+		{name: "defaults"},
+		{name: "all-options", opts: []MaximalPermissionPolicyAuthorizerOption{
+			WithDenialWarnings(),
+			WithAPIBindingScanLimit(100, authorizer.DecisionDeny),
+			WithConsumerParentRBAC(),
+			WithIncompleteRequestInfoDecision(authorizer.DecisionDeny),
+			WithCrossShardExportResolver(&fakeCrossShardExportResolver{}, time.Minute, 5*time.Second),
+			WithExplicitRBACVerbs(),
+			WithRBACRetry(3, 10*time.Millisecond),
+			WithDenialDeduplication(time.Minute),
+			WithCollectionGetAsList(),
+			WithoutAdminClusterRBACMergeByDefault(),
+			WithExemptGroups([]string{"system:kcp:internal"}),
+			WithExemptNamespaces([]string{"kube-system"}),
+			WithPendingAPIBindingsNoOpinion(),
+			WithStrictEmptyPolicy(true),
+			WithVerbFilter(func(string) bool { return true }),
+			WithDenialRecorder(func(DeniedRequest) {}),
+			WithVirtualResourceMatcher(func(authorizer.Attributes) (*apisv1alpha1.ExportReference, bool) { return nil, false }),
+			WithFallbackClusters(logicalcluster.New("root:bootstrap-1"), logicalcluster.New("root:bootstrap-2")),
+			WithTracerProvider(trace.NewNoopTracerProvider()),
+			WithSystemMastersBypass(),
+			WithDecisionLogs(),
+			WithRBACConcurrencyLimit(10, time.Second),
+			WithUserGroupPrefix("test.apis.kcp.dev:binding:"),
+			WithUserNameNormalizer(strings.ToLower),
+			WithIdentityRewriter(PrefixIdentityRewriter("rewritten:", nil)),
+			WithMultiExportPolicy(RequireAll),
+			WithInheritanceLookup(func(clusterName logicalcluster.Name) logicalcluster.Name {
+				parent, _ := clusterName.Parent()
+				return parent
+			}),
+			WithMetrics(metrics.NewKubeRegistry()),
+			WithDecisionCache(1000, 10*time.Second),
+			WithWarningHandler(func(ctx context.Context, message string) {}),
+			WithTerminalDecision(true),
+			WithFailurePolicy(FailClosed),
+			WithEnforcement(false),
+			WithCircuitBreaker(5, time.Minute, 30*time.Second, authorizer.DecisionDeny),
+			WithClusterAuthorizerFactory(func(clusterName logicalcluster.Name) authorizer.Authorizer {
+				return &recordingAuthorizer{}
+			}),
+			WithBindingMatcher(BindingMatcherFunc(func(attr authorizer.Attributes, clusterName logicalcluster.Name) (*APIBindingMatch, bool, error) {
+				return nil, false, nil
+			})),
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeInformers := kcpkubernetesinformers.NewSharedInformerFactory(kcpfakeclient.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+			kcpInformers := kcpinformers.NewSharedInformerFactory(kcpfakeinformerclient.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+			a, err := NewMaximalPermissionPolicyAuthorizer(kubeInformers, kcpInformers, &recordingAuthorizer{}, tt.opts...)
+			require.NoError(t, err)
+
+			got, err := a.(*MaximalPermissionPolicyAuthorizer).MarshalConfig()
+			require.NoError(t, err)
+
+			golden := filepath.Join("testdata", "maximal_permission_policy_authorizer_config_"+tt.name+".json")
+			if os.Getenv("UPDATE_GOLDEN") == "true" {
+				require.NoError(t, os.WriteFile(golden, append(got, '\n'), 0644))
+			}
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			require.Equal(t, string(want), string(got)+"\n")
+		})
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+)
+
+// crossShardExportCacheSize is the maximum number of API export references cached by the cross-shard resolver.
+const crossShardExportCacheSize = 1024
+
+// CrossShardExportResolver resolves references to API exports that are not known to the local shard,
+// e.g. because the owning workspace lives on another shard.
+type CrossShardExportResolver interface {
+	ResolveAPIExport(ctx context.Context, exportRef *apisv1alpha1.ExportReference) (export *apisv1alpha1.APIExport, found bool, err error)
+}
+
+// NewClientCrossShardExportResolver returns a CrossShardExportResolver getting API exports through the given
+// client, which is expected to reach all shards, e.g. through the front-proxy or the cache server.
+func NewClientCrossShardExportResolver(client kcpclient.ClusterInterface) CrossShardExportResolver {
+	return &clientCrossShardExportResolver{client: client}
+}
+
+type clientCrossShardExportResolver struct {
+	client kcpclient.ClusterInterface
+}
+
+func (r *clientCrossShardExportResolver) ResolveAPIExport(ctx context.Context, exportRef *apisv1alpha1.ExportReference) (*apisv1alpha1.APIExport, bool, error) {
+	if exportRef.Workspace == nil {
+		return nil, false, nil
+	}
+
+	apiExport, err := r.client.Cluster(logicalcluster.New(exportRef.Workspace.Path)).ApisV1alpha1().APIExports().Get(ctx, exportRef.Workspace.ExportName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return apiExport, true, nil
+}
+
+// cachingCrossShardExportResolver caches the results of a CrossShardExportResolver, including not found results,
+// and bounds the time of each resolution. Like for local API exports, an API export without the UID of the reference
+// is not found. Errors are not cached. Contexts of WithForceFresh skip the cached
+// results, refreshing them.
+type cachingCrossShardExportResolver struct {
+	delegate CrossShardExportResolver
+	cache    *utilcache.LRUExpireCache
+	ttl      time.Duration
+	timeout  time.Duration
+}
+
+func newCachingCrossShardExportResolver(delegate CrossShardExportResolver, ttl, timeout time.Duration, clock utilcache.Clock) *cachingCrossShardExportResolver {
+	return &cachingCrossShardExportResolver{
+		delegate: delegate,
+		cache:    utilcache.NewLRUExpireCacheWithClock(crossShardExportCacheSize, clock),
+		ttl:      ttl,
+		timeout:  timeout,
+	}
+}
+
+// cachedAPIExport is a cache entry of the cachingCrossShardExportResolver. A nil export means not found.
+type cachedAPIExport struct {
+	export *apisv1alpha1.APIExport
+}
+
+func (r *cachingCrossShardExportResolver) ResolveAPIExport(ctx context.Context, exportRef *apisv1alpha1.ExportReference) (*apisv1alpha1.APIExport, bool, error) {
+	if exportRef.Workspace == nil {
+		return nil, false, nil
+	}
+
+	key := *exportRef.Workspace
+	if entry, ok := r.cache.Get(key); ok && !isForceFresh(ctx) {
+		export := entry.(cachedAPIExport).export
+		return export, export != nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	export, found, err := r.delegate.ResolveAPIExport(ctx, exportRef)
+	if err != nil {
+		return nil, false, err
+	}
+	if !found || !matchesExportReferenceUID(export, exportRef) {
+		export, found = nil, false
+	}
+	r.cache.Add(key, cachedAPIExport{export: export}, r.ttl)
+	return export, found, nil
+}
+
+// clear removes all cached API exports.
+func (r *cachingCrossShardExportResolver) clear() {
+	clearLRUExpireCache(r.cache)
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+type fakeCrossShardExportResolver struct {
+	exports map[apisv1alpha1.WorkspaceExportReference]*apisv1alpha1.APIExport
+	block   bool
+	calls   int
+}
+
+func (r *fakeCrossShardExportResolver) ResolveAPIExport(ctx context.Context, exportRef *apisv1alpha1.ExportReference) (*apisv1alpha1.APIExport, bool, error) {
+	r.calls++
+	if r.block {
+		<-ctx.Done()
+		return nil, false, ctx.Err()
+	}
+	// like a client, look up the export by path and name only
+	export, found := r.exports[apisv1alpha1.WorkspaceExportReference{Path: exportRef.Workspace.Path, ExportName: exportRef.Workspace.ExportName}]
+	return export, found, nil
+}
+
+func TestCachingCrossShardExportResolver(t *testing.T) {
+	remote := &fakeCrossShardExportResolver{exports: map[apisv1alpha1.WorkspaceExportReference]*apisv1alpha1.APIExport{
+		{Path: "root:remote", ExportName: "widgets"}: newAPIExport("root:remote", "widgets", withLocalPolicy()),
+	}}
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	resolver := newCachingCrossShardExportResolver(remote, time.Minute, time.Second, fakeClock)
+
+	ref := &apisv1alpha1.ExportReference{Workspace: &apisv1alpha1.WorkspaceExportReference{Path: "root:remote", ExportName: "widgets"}}
+	export, found, err := resolver.ResolveAPIExport(context.Background(), ref)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "widgets", export.Name)
+
+	_, found, err = resolver.ResolveAPIExport(context.Background(), ref)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, 1, remote.calls, "expected the second resolution to be cached")
+
+	missing := &apisv1alpha1.ExportReference{Workspace: &apisv1alpha1.WorkspaceExportReference{Path: "root:remote", ExportName: "gadgets"}}
+	for i := 0; i < 2; i++ {
+		_, found, err = resolver.ResolveAPIExport(context.Background(), missing)
+		require.NoError(t, err)
+		require.False(t, found)
+	}
+	require.Equal(t, 2, remote.calls, "expected the not found result to be cached")
+
+	fakeClock.Step(2 * time.Minute)
+	_, _, err = resolver.ResolveAPIExport(context.Background(), ref)
+	require.NoError(t, err)
+	require.Equal(t, 3, remote.calls, "expected a resolution after the ttl expired")
+}
+
+func TestCachingCrossShardExportResolverForceFresh(t *testing.T) {
+	remote := &fakeCrossShardExportResolver{exports: map[apisv1alpha1.WorkspaceExportReference]*apisv1alpha1.APIExport{}}
+	resolver := newCachingCrossShardExportResolver(remote, time.Minute, time.Second, clocktesting.NewFakeClock(time.Now()))
+
+	ref := &apisv1alpha1.ExportReference{Workspace: &apisv1alpha1.WorkspaceExportReference{Path: "root:remote", ExportName: "widgets"}}
+	_, found, err := resolver.ResolveAPIExport(context.Background(), ref)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	// the export is created after the not found result was cached
+	remote.exports[*ref.Workspace] = newAPIExport("root:remote", "widgets", withLocalPolicy())
+	_, found, err = resolver.ResolveAPIExport(context.Background(), ref)
+	require.NoError(t, err)
+	require.False(t, found, "expected the cached not found result")
+	require.Equal(t, 1, remote.calls)
+
+	export, found, err := resolver.ResolveAPIExport(WithForceFresh(context.Background()), ref)
+	require.NoError(t, err)
+	require.True(t, found, "expected the cache to be skipped")
+	require.Equal(t, "widgets", export.Name)
+	require.Equal(t, 2, remote.calls)
+
+	_, found, err = resolver.ResolveAPIExport(context.Background(), ref)
+	require.NoError(t, err)
+	require.True(t, found, "expected the fresh result to be cached")
+	require.Equal(t, 2, remote.calls)
+}
+
+func TestCachingCrossShardExportResolverTimeout(t *testing.T) {
+	remote := &fakeCrossShardExportResolver{block: true}
+	resolver := newCachingCrossShardExportResolver(remote, time.Minute, 10*time.Millisecond, clocktesting.NewFakeClock(time.Now()))
+
+	ref := &apisv1alpha1.ExportReference{Workspace: &apisv1alpha1.WorkspaceExportReference{Path: "root:remote", ExportName: "widgets"}}
+	for i := 0; i < 2; i++ {
+		_, _, err := resolver.ResolveAPIExport(context.Background(), ref)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	}
+	require.Equal(t, 2, remote.calls, "expected errors not to be cached")
+}
+
+func TestCachingCrossShardExportResolverUID(t *testing.T) {
+	recreated := newAPIExport("root:remote", "widgets", withLocalPolicy())
+	recreated.UID = "uid-2"
+	remote := &fakeCrossShardExportResolver{exports: map[apisv1alpha1.WorkspaceExportReference]*apisv1alpha1.APIExport{
+		{Path: "root:remote", ExportName: "widgets"}: recreated,
+	}}
+	resolver := newCachingCrossShardExportResolver(remote, time.Minute, time.Second, clocktesting.NewFakeClock(time.Now()))
+
+	for _, tt := range []struct {
+		name      string
+		uid       types.UID
+		wantFound bool
+	}{
+		{name: "without UID", wantFound: true},
+		{name: "matching UID", uid: "uid-2", wantFound: true},
+		{name: "stale UID", uid: "uid-1"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ref := &apisv1alpha1.ExportReference{Workspace: &apisv1alpha1.WorkspaceExportReference{Path: "root:remote", ExportName: "widgets", UID: tt.uid}}
+			for i := 0; i < 2; i++ {
+				export, found, err := resolver.ResolveAPIExport(context.Background(), ref)
+				require.NoError(t, err)
+				require.Equal(t, tt.wantFound, found)
+				if !tt.wantFound {
+					require.Nil(t, export)
+				}
+			}
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerCrossShardExport(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		uid          types.UID
+		remote       *fakeCrossShardExportResolver
+		wantDecision authorizer.Decision
+		wantEvaluate bool
+		wantErr      bool
+	}{
+		{
+			name: "remote export",
+			remote: &fakeCrossShardExportResolver{exports: map[apisv1alpha1.WorkspaceExportReference]*apisv1alpha1.APIExport{
+				{Path: "root:remote", ExportName: "widgets"}: newAPIExport("root:remote", "widgets", withLocalPolicy()),
+			}},
+			wantDecision: authorizer.DecisionAllow,
+			wantEvaluate: true,
+		},
+		{
+			name: "stale reference to a recreated remote export",
+			uid:  "uid-1",
+			remote: &fakeCrossShardExportResolver{exports: map[apisv1alpha1.WorkspaceExportReference]*apisv1alpha1.APIExport{
+				{Path: "root:remote", ExportName: "widgets"}: func() *apisv1alpha1.APIExport {
+					export := newAPIExport("root:remote", "widgets", withLocalPolicy())
+					export.UID = "uid-2"
+					return export
+				}(),
+			}},
+			wantDecision: authorizer.DecisionNoOpinion,
+		},
+		{name: "export neither local nor remote", remote: &fakeCrossShardExportResolver{}, wantDecision: authorizer.DecisionNoOpinion},
+		{name: "remote timeout", remote: &fakeCrossShardExportResolver{block: true}, wantDecision: authorizer.DecisionNoOpinion, wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			binding := newAPIBinding("root:consumer", "widgets", "root:remote", "widgets",
+				apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+			)
+			binding.Spec.Reference.Workspace.UID = tt.uid
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{binding},
+				nil,
+				inner,
+				&recordingAuthorizer{decision: authorizer.DecisionAllow},
+				WithCrossShardExportResolver(tt.remote, time.Minute, 10*time.Millisecond),
+			)
+
+			dec, _, err := a.Authorize(withCluster("root:consumer"), &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, tt.wantEvaluate, inner.recordedAttributes != nil)
+			require.Equal(t, 1, tt.remote.calls, "expected the remote resolver to be asked after the local miss")
+		})
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+// decisionCache caches the RBAC decisions of maximal permission policies for a TTL. Entries are not invalidated
+// when RBAC changes, i.e. a decision may be stale for up to the TTL.
+type decisionCache struct {
+	cache *utilcache.LRUExpireCache
+	size  int
+	ttl   time.Duration
+}
+
+// cachedDecision is an entry of the decisionCache.
+type cachedDecision struct {
+	decision authorizer.Decision
+	reason   string
+}
+
+func newDecisionCache(size int, ttl time.Duration, clock utilcache.Clock) *decisionCache {
+	return &decisionCache{
+		cache: utilcache.NewLRUExpireCacheWithClock(size, clock),
+		size:  size,
+		ttl:   ttl,
+	}
+}
+
+// decisionCacheKey returns the key of the RBAC decision of the given prefixed attributes in the API export cluster,
+// merged with the RBAC of the merge clusters. It hashes everything RBAC evaluates, i.e. the user name and groups,
+// the verb, the API group, the resource and subresource, the namespace and the name, respectively the path of
+// non-resource requests. Every element is length-prefixed and lists are prefixed with their length, such that
+// elements containing separators, e.g. groups derived from client certificates, cannot collide.
+func decisionCacheKey(attr authorizer.Attributes, clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) [sha256.Size]byte {
+	groups := append([]string(nil), attr.GetUser().GetGroups()...)
+	sort.Strings(groups)
+	merged := make([]string, 0, len(mergeClusters))
+	for _, mergeCluster := range mergeClusters {
+		merged = append(merged, mergeCluster.String())
+	}
+
+	h := sha256.New()
+	write := func(fields ...string) {
+		var length [binary.MaxVarintLen64]byte
+		h.Write(length[:binary.PutUvarint(length[:], uint64(len(fields)))])
+		for _, field := range fields {
+			h.Write(length[:binary.PutUvarint(length[:], uint64(len(field)))])
+			h.Write([]byte(field))
+		}
+	}
+	write(clusterName.String())
+	write(merged...)
+	write(attr.GetUser().GetName())
+	write(groups...)
+	write(attr.GetVerb())
+	if attr.IsResourceRequest() {
+		write(attr.GetAPIGroup(), attr.GetResource(), attr.GetSubresource(), attr.GetNamespace(), attr.GetName())
+	} else {
+		write(attr.GetPath())
+	}
+
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key
+}
+
+func (c *decisionCache) get(key [sha256.Size]byte) (authorizer.Decision, string, bool) {
+	entry, ok := c.cache.Get(key)
+	if !ok {
+		return authorizer.DecisionNoOpinion, "", false
+	}
+	cached := entry.(cachedDecision)
+	return cached.decision, cached.reason, true
+}
+
+func (c *decisionCache) add(key [sha256.Size]byte, dec authorizer.Decision, reason string) {
+	c.cache.Add(key, cachedDecision{decision: dec, reason: reason}, c.ttl)
+}
+
+// clear removes all cached decisions.
+func (c *decisionCache) clear() {
+	clearLRUExpireCache(c.cache)
+}
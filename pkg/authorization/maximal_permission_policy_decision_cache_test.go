@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+func TestDecisionCacheKey(t *testing.T) {
+	attr := func(name string, groups ...string) authorizer.AttributesRecord {
+		return authorizer.AttributesRecord{
+			User:            &user.DefaultInfo{Name: name, Groups: groups},
+			Verb:            "get",
+			APIGroup:        "widgets.example.io",
+			Resource:        "widgets",
+			ResourceRequest: true,
+		}
+	}
+	provider := logicalcluster.New("root:provider")
+
+	for _, tt := range []struct {
+		name                           string
+		a, b                           authorizer.AttributesRecord
+		aMergeClusters, bMergeClusters []logicalcluster.Name
+		wantEqual                      bool
+	}{
+		{
+			name:      "group order ignored",
+			a:         attr("user-1", "apis.kcp.dev:binding:a", "apis.kcp.dev:binding:b"),
+			b:         attr("user-1", "apis.kcp.dev:binding:b", "apis.kcp.dev:binding:a"),
+			wantEqual: true,
+		},
+		{
+			name: "group with comma",
+			a:    attr("user-1", "a,b"),
+			b:    attr("user-1", "a", "b"),
+		},
+		{
+			name: "prefixed group with comma",
+			a:    attr("user-1", "apis.kcp.dev:binding:a,apis.kcp.dev:binding:b"),
+			b:    attr("user-1", "apis.kcp.dev:binding:a", "apis.kcp.dev:binding:b"),
+		},
+		{
+			name: "group with separator",
+			a:    attr("user-1", "a\x00b"),
+			b:    attr("user-1", "a", "b"),
+		},
+		{
+			name: "user name shifted into groups",
+			a:    attr("user-1\x00a"),
+			b:    attr("user-1", "a"),
+		},
+		{
+			name:           "merge cluster with comma",
+			a:              attr("user-1"),
+			b:              attr("user-1"),
+			aMergeClusters: []logicalcluster.Name{logicalcluster.New("system:admin,root:other")},
+			bMergeClusters: []logicalcluster.Name{logicalcluster.New("system:admin"), logicalcluster.New("root:other")},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := decisionCacheKey(tt.a, provider, tt.aMergeClusters)
+			b := decisionCacheKey(tt.b, provider, tt.bMergeClusters)
+			if tt.wantEqual {
+				require.Equal(t, a, b)
+			} else {
+				require.NotEqual(t, a, b)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"time"
+
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+// denialDeduplicationCacheSize is the maximum number of denials remembered by the denial deduplicator.
+const denialDeduplicationCacheSize = 4096
+
+// denialKey identifies identical denials of the maximal permission policy.
+type denialKey struct {
+	user       string
+	exportPath string
+	exportName string
+	group      string
+	resource   string
+	verb       string
+}
+
+// denialDeduplicator remembers denials of the maximal permission policy for a window
+// to tell the first denial apart from identical ones, e.g. of a retrying client.
+type denialDeduplicator struct {
+	cache  *utilcache.LRUExpireCache
+	window time.Duration
+}
+
+func newDenialDeduplicator(window time.Duration, clock utilcache.Clock) *denialDeduplicator {
+	return &denialDeduplicator{
+		cache:  utilcache.NewLRUExpireCacheWithClock(denialDeduplicationCacheSize, clock),
+		window: window,
+	}
+}
+
+// first returns whether the denial of the attributes for the given API export is the first within the window.
+// The window starts with the first denial and is not extended by duplicates.
+func (d *denialDeduplicator) first(attr authorizer.Attributes, exportName, path string) bool {
+	key := denialKey{
+		user:       attr.GetUser().GetName(),
+		exportPath: path,
+		exportName: exportName,
+		group:      attr.GetAPIGroup(),
+		resource:   resourceWithSubresource(attr),
+		verb:       attr.GetVerb(),
+	}
+	if _, ok := d.cache.Get(key); ok {
+		return false
+	}
+	d.cache.Add(key, struct{}{}, d.window)
+	return true
+}
+
+// clear forgets all denials.
+func (d *denialDeduplicator) clear() {
+	clearLRUExpireCache(d.cache)
+}
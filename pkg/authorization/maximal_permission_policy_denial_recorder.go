@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+)
+
+// DeniedRequest is a request for a bound resource the authorizer did not allow, passed to the recorder of WithDenialRecorder.
+type DeniedRequest struct {
+	Timestamp time.Time
+	Cluster   logicalcluster.Name
+	User      string
+	Verb      string
+	APIGroup  string
+	// Resource is the requested resource, or "<resource>/<subresource>" for subresource requests.
+	Resource   string
+	ExportPath string
+	ExportName string
+	Decision   authorizer.Decision
+	// ReasonCode is the reason of the decision if it is one of the Reason constants, e.g. ReasonPolicyDenied,
+	// and empty otherwise, e.g. for the reasons of the delegate.
+	ReasonCode string
+}
+
+// WithDenialRecorder sets a recorder called with every request for a bound resource the authorizer does not allow,
+// e.g. to keep the recent denials for a debug view. It is called synchronously on the request path, outside of any
+// lock of the authorizer, hence it must be fast and safe for concurrent use. Denials of requests for resources not bound
+// by an API binding, or that are exempt, are not recorded.
+func WithDenialRecorder(recorder func(DeniedRequest)) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.denialRecorder = recorder
+	}
+}
+
+// recordDeniedRequest passes a decision other than allow on a request for a bound resource to the denial recorder, if any.
+func (a *MaximalPermissionPolicyAuthorizer) recordDeniedRequest(ctx context.Context, attr authorizer.Attributes, details *MaximalPermissionPolicyDecisionDetails, dec authorizer.Decision, reason string) {
+	if a.denialRecorder == nil || !details.Bound || dec == authorizer.DecisionAllow {
+		return
+	}
+
+	denied := DeniedRequest{
+		Timestamp: time.Now(),
+		User:      attr.GetUser().GetName(),
+		Verb:      attr.GetVerb(),
+		APIGroup:  attr.GetAPIGroup(),
+		Resource:  resourceWithSubresource(attr),
+		Decision:  dec,
+	}
+	if lcluster, err := genericapirequest.ClusterNameFrom(ctx); err == nil {
+		denied.Cluster = lcluster
+	}
+	if details.ExportReference != nil && details.ExportReference.Workspace != nil {
+		denied.ExportPath = details.ExportReference.Workspace.Path
+		denied.ExportName = details.ExportReference.Workspace.ExportName
+	}
+	if reasonCodes.Has(reason) {
+		denied.ReasonCode = reason
+	}
+	a.denialRecorder(denied)
+}
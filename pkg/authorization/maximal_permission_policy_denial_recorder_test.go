@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+func TestMaximalPermissionPolicyAuthorizerDenialRecorder(t *testing.T) {
+	var denied []DeniedRequest
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		)},
+		[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+		authorizer.AuthorizerFunc(func(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+			if attr.GetVerb() == "get" {
+				return authorizer.DecisionAllow, "", nil
+			}
+			return authorizer.DecisionNoOpinion, "", nil
+		}),
+		&recordingAuthorizer{decision: authorizer.DecisionNoOpinion, reason: "delegate"},
+		WithDenialRecorder(func(d DeniedRequest) {
+			denied = append(denied, d)
+		}),
+	)
+
+	authorize := func(verb, group, resource string) authorizer.Decision {
+		dec, _, err := a.Authorize(withCluster("root:consumer"), &authorizer.AttributesRecord{
+			User:            &user.DefaultInfo{Name: "user-1"},
+			Verb:            verb,
+			APIGroup:        group,
+			Resource:        resource,
+			Subresource:     "status",
+			ResourceRequest: true,
+		})
+		require.NoError(t, err)
+		return dec
+	}
+
+	// not permitted by the policy
+	require.Equal(t, authorizer.DecisionNoOpinion, authorize("update", "widgets.example.io", "widgets"))
+	require.Len(t, denied, 1)
+	require.False(t, denied[0].Timestamp.IsZero())
+	denied[0].Timestamp = time.Time{}
+	require.Equal(t, DeniedRequest{
+		Cluster:    logicalcluster.New("root:consumer"),
+		User:       "user-1",
+		Verb:       "update",
+		APIGroup:   "widgets.example.io",
+		Resource:   "widgets/status",
+		ExportPath: "root:provider",
+		ExportName: "widgets",
+		Decision:   authorizer.DecisionNoOpinion,
+		ReasonCode: ReasonPolicyDenied,
+	}, denied[0])
+
+	// permitted by the policy, but not by the delegate
+	require.Equal(t, authorizer.DecisionNoOpinion, authorize("get", "widgets.example.io", "widgets"))
+	require.Len(t, denied, 2)
+	require.Empty(t, denied[1].ReasonCode, "expected the reason of the delegate not to be a reason code")
+
+	// not bound
+	require.Equal(t, authorizer.DecisionNoOpinion, authorize("update", "gadgets.example.io", "gadgets"))
+	require.Len(t, denied, 2, "expected no denial recorded for an unbound resource")
+
+	// allowed
+	a.delegate = &recordingAuthorizer{decision: authorizer.DecisionAllow}
+	require.Equal(t, authorizer.DecisionAllow, authorize("get", "widgets.example.io", "widgets"))
+	require.Len(t, denied, 2, "expected no denial recorded for an allowed request")
+
+	// safe without recorder
+	WithDenialRecorder(nil)(a)
+	require.Equal(t, authorizer.DecisionNoOpinion, authorize("update", "widgets.example.io", "widgets"))
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+// DecisionTrace explains a decision of the MaximalPermissionPolicyAuthorizer, e.g. for command line tooling
+// rendering why a request is or is not permitted.
+type DecisionTrace struct {
+	// Bound is true if the requested resource is bound by the API binding APIBindingName, by BoundResource.
+	Bound          bool
+	APIBindingName string
+	BoundResource  *apisv1alpha1.BoundAPIResource
+
+	// APIExportName and APIExportPath reference the API export of the API binding.
+	APIExportName string
+	APIExportPath string
+	// APIExportCluster is the logical cluster of the API export, empty if it was not resolved.
+	APIExportCluster logicalcluster.Name
+
+	// PolicyPresent is true if the resolved API export has a maximal permission policy.
+	PolicyPresent bool
+	// PolicyApplicable is true if the maximal permission policy was enforced, see MaximalPermissionPolicyDecisionDetails.
+	PolicyApplicable bool
+
+	// PrefixedUser is the user the RBAC of the API export cluster was evaluated with, nil if RBAC was not evaluated.
+	PrefixedUser user.Info
+	// RBACDecision and RBACReason are the outcome of the RBAC evaluation in the API export cluster, if PrefixedUser is set.
+	RBACDecision authorizer.Decision
+	RBACReason   string
+
+	// Delegated is true if the decision was made by the delegate.
+	Delegated bool
+	// Decision and Reason are the decision of the authorizer, before applying WithoutEnforcement.
+	Decision authorizer.Decision
+	Reason   string
+
+	// AuditAnnotations are the audit annotations the decision is recorded with.
+	AuditAnnotations map[string]string
+}
+
+// Explain evaluates the request like Authorize, and returns a trace of the decision. The audit annotations are returned
+// in the trace instead of being recorded, and the decision is neither logged nor counted. Denials are neither counted,
+// deduplicated nor warned about, deprecation warnings are not emitted, and the decision cache is neither read nor
+// filled. The trace is returned along with any error of the evaluation.
+func (a *MaximalPermissionPolicyAuthorizer) Explain(ctx context.Context, attr authorizer.Attributes) (*DecisionTrace, error) {
+	ctx, annotations := withAuditAnnotations(ctx)
+
+	decisionTrace := &DecisionTrace{}
+	details := &MaximalPermissionPolicyDecisionDetails{trace: decisionTrace, dryRun: true}
+	dec, reason, err := a.authorize(ctx, attr, details)
+
+	decisionTrace.Bound = details.Bound
+	decisionTrace.APIBindingName = details.APIBindingName
+	decisionTrace.BoundResource = details.BoundResource
+	if details.ExportReference != nil && details.ExportReference.Workspace != nil {
+		decisionTrace.APIExportName = details.ExportReference.Workspace.ExportName
+		decisionTrace.APIExportPath = details.ExportReference.Workspace.Path
+	}
+	decisionTrace.PolicyApplicable = details.PolicyApplicable
+	decisionTrace.Delegated = details.Delegated
+	decisionTrace.Decision, decisionTrace.Reason = dec, reason
+	decisionTrace.AuditAnnotations = annotations.values
+	return decisionTrace, err
+}
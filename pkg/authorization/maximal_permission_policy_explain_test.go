@@ -0,0 +1,216 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/warning"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/testutil"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+)
+
+func TestMaximalPermissionPolicyAuthorizerExplain(t *testing.T) {
+	widgets := apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"}
+	prefixedUser := &user.DefaultInfo{
+		Name:   apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "user-1",
+		Groups: []string{},
+	}
+
+	for _, tt := range []struct {
+		name    string
+		exports []*apisv1alpha1.APIExport
+		group   string
+		inner   authorizer.Decision
+		want    DecisionTrace
+	}{
+		{
+			name:  "unbound",
+			group: "other.example.io",
+			want: DecisionTrace{
+				Delegated: true,
+				Decision:  authorizer.DecisionAllow,
+				Reason:    "delegate",
+			},
+		},
+		{
+			name:  "API export not found",
+			group: "widgets.example.io",
+			want: DecisionTrace{
+				Bound:            true,
+				APIBindingName:   "widgets",
+				BoundResource:    &widgets,
+				APIExportName:    "widgets",
+				APIExportPath:    "root:provider",
+				PolicyApplicable: true,
+				Decision:         authorizer.DecisionNoOpinion,
+				Reason:           ReasonExportNotFound,
+			},
+		},
+		{
+			name:    "no policy",
+			exports: []*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", nil)},
+			group:   "widgets.example.io",
+			want: DecisionTrace{
+				Bound:            true,
+				APIBindingName:   "widgets",
+				BoundResource:    &widgets,
+				APIExportName:    "widgets",
+				APIExportPath:    "root:provider",
+				APIExportCluster: logicalcluster.New("root:provider"),
+				Delegated:        true,
+				Decision:         authorizer.DecisionAllow,
+				Reason:           "delegate",
+			},
+		},
+		{
+			name:    "permitted by policy",
+			exports: []*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+			group:   "widgets.example.io",
+			inner:   authorizer.DecisionAllow,
+			want: DecisionTrace{
+				Bound:            true,
+				APIBindingName:   "widgets",
+				BoundResource:    &widgets,
+				APIExportName:    "widgets",
+				APIExportPath:    "root:provider",
+				APIExportCluster: logicalcluster.New("root:provider"),
+				PolicyPresent:    true,
+				PolicyApplicable: true,
+				PrefixedUser:     prefixedUser,
+				RBACDecision:     authorizer.DecisionAllow,
+				RBACReason:       "inner",
+				Delegated:        true,
+				Decision:         authorizer.DecisionAllow,
+				Reason:           "delegate",
+			},
+		},
+		{
+			name:    "not permitted by policy",
+			exports: []*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+			group:   "widgets.example.io",
+			inner:   authorizer.DecisionNoOpinion,
+			want: DecisionTrace{
+				Bound:            true,
+				APIBindingName:   "widgets",
+				BoundResource:    &widgets,
+				APIExportName:    "widgets",
+				APIExportPath:    "root:provider",
+				APIExportCluster: logicalcluster.New("root:provider"),
+				PolicyPresent:    true,
+				PolicyApplicable: true,
+				PrefixedUser:     prefixedUser,
+				RBACDecision:     authorizer.DecisionNoOpinion,
+				RBACReason:       "inner",
+				Decision:         authorizer.DecisionNoOpinion,
+				Reason:           ReasonPolicyDenied,
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets", widgets)},
+				tt.exports,
+				&recordingAuthorizer{decision: tt.inner, reason: "inner"},
+				&recordingAuthorizer{decision: authorizer.DecisionAllow, reason: "delegate"},
+			)
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			got, err := a.Explain(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        tt.group,
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.NotEmpty(t, got.AuditAnnotations[MaximalPermissionPolicyAuditDecision])
+			require.Empty(t, ev.Annotations, "expected no audit annotations to be recorded")
+
+			got.AuditAnnotations = nil
+			require.Equal(t, &tt.want, got)
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerExplainDryRun(t *testing.T) {
+	deprecated := newAPIExport("root:provider", "widgets", withLocalPolicy())
+	conditions.MarkTrue(deprecated, apisv1alpha1.APIExportDeprecated)
+	var deprecationWarnings []string
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		)},
+		[]*apisv1alpha1.APIExport{deprecated},
+		&recordingAuthorizer{decision: authorizer.DecisionNoOpinion, reason: "no RBAC policy matched"},
+		&recordingAuthorizer{decision: authorizer.DecisionAllow},
+		WithWarningHandler(func(ctx context.Context, message string) {
+			deprecationWarnings = append(deprecationWarnings, message)
+		}),
+		WithDenialWarnings(),
+		WithDenialDeduplication(time.Minute),
+		WithDecisionCache(10, time.Minute),
+		WithMetrics(metrics.NewKubeRegistry()),
+	)
+
+	deniedBefore, err := testutil.GetCounterMetricValue(denials.WithLabelValues("root:consumer"))
+	require.NoError(t, err)
+	suppressedBefore, err := testutil.GetCounterMetricValue(suppressedDenials)
+	require.NoError(t, err)
+
+	var warnings recordingWarnings
+	ctx := warning.WithWarningRecorder(withCluster("root:consumer"), &warnings)
+	attr := &authorizer.AttributesRecord{
+		User:            &user.DefaultInfo{Name: "user-1"},
+		Verb:            "create",
+		APIGroup:        "widgets.example.io",
+		Resource:        "widgets",
+		ResourceRequest: true,
+	}
+	for i := 0; i < 2; i++ {
+		trace, err := a.Explain(ctx, attr)
+		require.NoError(t, err)
+		require.Equal(t, authorizer.DecisionNoOpinion, trace.Decision)
+	}
+
+	deniedAfter, err := testutil.GetCounterMetricValue(denials.WithLabelValues("root:consumer"))
+	require.NoError(t, err)
+	require.Equal(t, deniedBefore, deniedAfter, "expected Explain not to count denials")
+	suppressedAfter, err := testutil.GetCounterMetricValue(suppressedDenials)
+	require.NoError(t, err)
+	require.Equal(t, suppressedBefore, suppressedAfter, "expected Explain not to suppress denials")
+	require.Empty(t, a.denialDeduplicator.cache.Keys(), "expected Explain not to record denials for deduplication")
+	require.Empty(t, a.decisionCache.cache.Keys(), "expected Explain not to fill the decision cache")
+	require.Empty(t, warnings, "expected Explain not to warn about denials")
+	require.Empty(t, deprecationWarnings, "expected Explain not to warn about deprecation")
+
+	// the first denial after Explain is neither suppressed nor cached
+	_, _, err = a.Authorize(ctx, attr)
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	require.Len(t, deprecationWarnings, 1)
+}
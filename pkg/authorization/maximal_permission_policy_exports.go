@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+// ExportsConstrainingAttributes returns the distinct API exports with a maximal permission policy of all API bindings
+// binding the requested resource in the cluster of the context, in the order of the API bindings by name, without
+// making a decision, e.g. for debugging why a request is denied. Unlike Authorize, which evaluates the policy of the
+// first API binding only, it considers all API bindings binding the resource. API exports are resolved like by Authorize,
+// i.e. locally and through the cross-shard resolver if configured. API exports not found and API bindings with an
+// unsupported export reference are skipped. The user of the request, exempt groups and maintenance exemptions are ignored.
+func (a *MaximalPermissionPolicyAuthorizer) ExportsConstrainingAttributes(ctx context.Context, attr authorizer.Attributes) ([]*apisv1alpha1.APIExport, error) {
+	clusterName, err := genericapirequest.ClusterNameFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*APIBindingMatch
+	if a.customBindingMatcher || a.matchAllAPIBindings == nil {
+		match, bound, err := a.bindingMatcher.MatchAPIBinding(attr, clusterName)
+		if err != nil {
+			return nil, err
+		}
+		if bound {
+			matches = append(matches, match)
+		}
+	} else {
+		matches, err = a.matchAllAPIBindings(attr, clusterName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	type exportKey struct {
+		clusterName logicalcluster.Name
+		name        string
+	}
+	var ret []*apisv1alpha1.APIExport
+	seen := map[exportKey]bool{}
+	for _, match := range matches {
+		apiExport, found, err := a.getAPIExportByReference(match.ExportReference, match.ExportClusterName)
+		if errors.Is(err, ErrUnsupportedExportReference) {
+			continue
+		} else if err == nil && !found && a.crossShardExportResolver != nil {
+			apiExport, found, err = a.crossShardExportResolver.ResolveAPIExport(ctx, match.ExportReference)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !found || apiExport.Spec.MaximalPermissionPolicy == nil {
+			continue
+		}
+
+		key := exportKey{clusterName: logicalcluster.From(apiExport), name: apiExport.Name}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		ret = append(ret, apiExport)
+	}
+	return ret, nil
+}
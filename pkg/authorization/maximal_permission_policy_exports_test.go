@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+func TestExportsConstrainingAttributes(t *testing.T) {
+	bindings := []*apisv1alpha1.APIBinding{
+		newAPIBinding("root:consumer", "widgets-v2", "root:provider-2", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		),
+		newAPIBinding("root:consumer", "widgets-v1", "root:provider-1", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		),
+		newAPIBinding("root:consumer", "widgets-v1-again", "root:provider-1", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		),
+		newAPIBinding("root:consumer", "widgets-unconstrained", "root:provider-3", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		),
+		newAPIBinding("root:consumer", "gadgets", "root:provider-1", "gadgets",
+			apisv1alpha1.BoundAPIResource{Group: "gadgets.example.io", Resource: "gadgets"},
+		),
+		newAPIBinding("root:consumer", "doodads", "root:provider-1", "doodads",
+			apisv1alpha1.BoundAPIResource{Group: "doodads.example.io", Resource: "doodads"},
+		),
+	}
+	exports := []*apisv1alpha1.APIExport{
+		newAPIExport("root:provider-1", "widgets", withLocalPolicy()),
+		newAPIExport("root:provider-2", "widgets", withLocalPolicy()),
+		newAPIExport("root:provider-3", "widgets", nil),
+		newAPIExport("root:provider-1", "gadgets", withLocalPolicy()),
+	}
+
+	for _, tt := range []struct {
+		name        string
+		group       string
+		resource    string
+		wantExports []string
+	}{
+		{name: "no binding", group: "sprockets.example.io", resource: "sprockets"},
+		{name: "export not found", group: "doodads.example.io", resource: "doodads"},
+		{name: "one export", group: "gadgets.example.io", resource: "gadgets", wantExports: []string{"root:provider-1|gadgets"}},
+		{name: "multiple exports", group: "widgets.example.io", resource: "widgets", wantExports: []string{"root:provider-1|widgets", "root:provider-2|widgets"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestMaximalPermissionPolicyAuthorizer(t, bindings, exports, nil, nil)
+
+			apiExports, err := a.ExportsConstrainingAttributes(withCluster("root:consumer"), &authorizer.AttributesRecord{
+				Verb:            "get",
+				APIGroup:        tt.group,
+				Resource:        tt.resource,
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+
+			var got []string
+			for _, apiExport := range apiExports {
+				got = append(got, logicalcluster.From(apiExport).String()+"|"+apiExport.Name)
+			}
+			require.Equal(t, tt.wantExports, got)
+		})
+	}
+}
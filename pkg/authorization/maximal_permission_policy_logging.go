@@ -0,0 +1,114 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/klog/v2"
+)
+
+// MaximalPermissionPolicyDecisionLogLevel is the verbosity of the decision logs enabled with WithDecisionLogs.
+const MaximalPermissionPolicyDecisionLogLevel = 2
+
+// logDecision logs the decision as a single structured line if enabled with WithDecisionLogs.
+func (a *MaximalPermissionPolicyAuthorizer) logDecision(ctx context.Context, attr authorizer.Attributes, details *MaximalPermissionPolicyDecisionDetails, dec authorizer.Decision, reason string, err error) {
+	if !a.decisionLogs {
+		return
+	}
+	logger := klog.FromContext(ctx).V(MaximalPermissionPolicyDecisionLogLevel)
+	if !logger.Enabled() {
+		return
+	}
+
+	var cluster, exportPath, exportName, reasonCode string
+	if lcluster, err := genericapirequest.ClusterNameFrom(ctx); err == nil {
+		cluster = lcluster.String()
+	}
+	if details.ExportReference != nil && details.ExportReference.Workspace != nil {
+		exportPath = details.ExportReference.Workspace.Path
+		exportName = details.ExportReference.Workspace.ExportName
+	}
+	if reasonCodes.Has(reason) {
+		reasonCode = reason
+	}
+	logger.Info("maximal permission policy decision",
+		"cluster", cluster,
+		"exportPath", exportPath,
+		"exportName", exportName,
+		"request", RedactAttributes(attr),
+		"resource", resourceWithSubresource(attr),
+		"verb", attr.GetVerb(),
+		"decision", DecisionString(dec),
+		"reasonCode", reasonCode,
+		"err", err,
+	)
+}
+
+// logNoOpinion logs a request the authorizer has no opinion on at verbosity 4, redacting the user, see RedactAttributes.
+func logNoOpinion(ctx context.Context, attr authorizer.Attributes, details *MaximalPermissionPolicyDecisionDetails, reason string) {
+	logger := klog.FromContext(ctx).V(4)
+	if !logger.Enabled() {
+		return
+	}
+
+	var cluster, exportPath, exportName string
+	if lcluster, err := genericapirequest.ClusterNameFrom(ctx); err == nil {
+		cluster = lcluster.String()
+	}
+	if details.ExportReference != nil && details.ExportReference.Workspace != nil {
+		exportPath = details.ExportReference.Workspace.Path
+		exportName = details.ExportReference.Workspace.ExportName
+	}
+	logger.Info("no opinion on request",
+		"cluster", cluster,
+		"exportPath", exportPath,
+		"exportName", exportName,
+		"request", RedactAttributes(attr),
+		"reason", reason,
+	)
+}
+
+// RedactAttributes formats the attributes of a request for logging without revealing the identity of the user,
+// i.e. the user name and groups are left out, except for whether the user is authenticated, a service account
+// or privileged.
+func RedactAttributes(attr authorizer.Attributes) string {
+	var b strings.Builder
+	if attr.IsResourceRequest() {
+		fmt.Fprintf(&b, "verb=%q group=%q resource=%q namespace=%q name=%q", attr.GetVerb(), attr.GetAPIGroup(), resourceWithSubresource(attr), attr.GetNamespace(), attr.GetName())
+	} else {
+		fmt.Fprintf(&b, "verb=%q path=%q", attr.GetVerb(), attr.GetPath())
+	}
+
+	var groups sets.String
+	if attr.GetUser() != nil {
+		groups = sets.NewString(attr.GetUser().GetGroups()...)
+	}
+	fmt.Fprintf(&b, " user=<redacted> authenticated=%t serviceAccount=%t privileged=%t",
+		groups.Has(user.AllAuthenticated),
+		groups.Has(serviceaccount.AllServiceAccountsGroup),
+		groups.Has(user.SystemPrivilegedGroup),
+	)
+	return b.String()
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/klog/v2"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+func TestMaximalPermissionPolicyAuthorizerDecisionLogs(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("enabled=%v", enabled), func(t *testing.T) {
+			var opts []MaximalPermissionPolicyAuthorizerOption
+			if enabled {
+				opts = append(opts, WithDecisionLogs())
+			}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				&recordingAuthorizer{decision: authorizer.DecisionNoOpinion},
+				&recordingAuthorizer{decision: authorizer.DecisionAllow},
+				opts...,
+			)
+
+			var lines []map[string]interface{}
+			logger := funcr.NewJSON(func(obj string) {
+				var line map[string]interface{}
+				require.NoError(t, json.Unmarshal([]byte(obj), &line))
+				lines = append(lines, line)
+			}, funcr.Options{Verbosity: MaximalPermissionPolicyDecisionLogLevel})
+			ctx := klog.NewContext(withCluster("root:consumer"), logger)
+
+			dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1", Groups: []string{"group-1"}},
+				Verb:            "update",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				Subresource:     "status",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, authorizer.DecisionNoOpinion, dec)
+
+			if !enabled {
+				require.Empty(t, lines)
+				return
+			}
+			require.Len(t, lines, 1)
+			for k, v := range map[string]interface{}{
+				"msg":        "maximal permission policy decision",
+				"cluster":    "root:consumer",
+				"exportPath": "root:provider",
+				"exportName": "widgets",
+				"request":    `verb="update" group="widgets.example.io" resource="widgets/status" namespace="" name="" user=<redacted> authenticated=false serviceAccount=false privileged=false`,
+				"resource":   "widgets/status",
+				"verb":       "update",
+				"decision":   "NoOpinion",
+				"reasonCode": MaximalPermissionPolicyCeilingExceededReasonCode,
+			} {
+				require.Equal(t, v, lines[0][k], "log field %q", k)
+			}
+			require.NotContains(t, lines[0], "user")
+		})
+	}
+}
+
+func TestRedactAttributes(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		attr authorizer.Attributes
+		want string
+	}{
+		{
+			name: "resource request",
+			attr: &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "alice", UID: "alice-uid", Groups: []string{"alice-team", user.AllAuthenticated}},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				Subresource:     "status",
+				Namespace:       "default",
+				Name:            "widget-1",
+				ResourceRequest: true,
+			},
+			want: `verb="get" group="widgets.example.io" resource="widgets/status" namespace="default" name="widget-1" user=<redacted> authenticated=true serviceAccount=false privileged=false`,
+		},
+		{
+			name: "service account",
+			attr: &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "system:serviceaccount:default:alice", Groups: []string{"system:serviceaccounts", "system:serviceaccounts:default", user.AllAuthenticated}},
+				Verb:            "list",
+				Resource:        "configmaps",
+				ResourceRequest: true,
+			},
+			want: `verb="list" group="" resource="configmaps" namespace="" name="" user=<redacted> authenticated=true serviceAccount=true privileged=false`,
+		},
+		{
+			name: "non-resource request",
+			attr: &authorizer.AttributesRecord{
+				User: &user.DefaultInfo{Name: "alice", Groups: []string{user.SystemPrivilegedGroup}},
+				Verb: "get",
+				Path: "/healthz",
+			},
+			want: `verb="get" path="/healthz" user=<redacted> authenticated=false serviceAccount=false privileged=true`,
+		},
+		{
+			name: "without user",
+			attr: &authorizer.AttributesRecord{Verb: "get", Path: "/healthz"},
+			want: `verb="get" path="/healthz" user=<redacted> authenticated=false serviceAccount=false privileged=false`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RedactAttributes(tt.attr)
+			require.Equal(t, tt.want, got)
+			require.NotContains(t, got, "alice")
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerNoOpinionLogs(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		inner     authorizer.Decision
+		verbosity int
+		wantLog   bool
+	}{
+		{name: "no opinion", inner: authorizer.DecisionNoOpinion, verbosity: 4, wantLog: true},
+		{name: "no opinion at lower verbosity", inner: authorizer.DecisionNoOpinion, verbosity: 3},
+		{name: "allow", inner: authorizer.DecisionAllow, verbosity: 4},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+				)},
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				&recordingAuthorizer{decision: tt.inner},
+				&recordingAuthorizer{decision: authorizer.DecisionAllow},
+			)
+
+			var lines []map[string]interface{}
+			logger := funcr.NewJSON(func(obj string) {
+				var line map[string]interface{}
+				require.NoError(t, json.Unmarshal([]byte(obj), &line))
+				lines = append(lines, line)
+			}, funcr.Options{Verbosity: tt.verbosity})
+			ctx := klog.NewContext(withCluster("root:consumer"), logger)
+
+			_, reason, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1", Groups: []string{"group-1"}},
+				Verb:            "delete",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+
+			if !tt.wantLog {
+				require.Empty(t, lines)
+				return
+			}
+			require.Len(t, lines, 1)
+			for k, v := range map[string]interface{}{
+				"msg":        "no opinion on request",
+				"cluster":    "root:consumer",
+				"exportPath": "root:provider",
+				"exportName": "widgets",
+				"request":    `verb="delete" group="widgets.example.io" resource="widgets" namespace="" name="" user=<redacted> authenticated=false serviceAccount=false privileged=false`,
+				"reason":     reason,
+			} {
+				require.Equal(t, v, lines[0][k], "log field %q", k)
+			}
+			require.NotContains(t, lines[0], "user")
+			require.NotContains(t, lines[0], "groups")
+		})
+	}
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/prometheus/client_golang/prometheus"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/component-base/metrics"
+)
+
+// MaximalPermissionPolicyAuthorizerSubsystem is the subsystem name used for the maximal permission policy authorizer metrics.
+const MaximalPermissionPolicyAuthorizerSubsystem = "maximal_permission_policy_authorizer"
+
+var (
+	// apiBindingScanOverflows counts the requests for which the API binding scan limit was exceeded without a match.
+	apiBindingScanOverflows = metrics.NewCounter(
+		&metrics.CounterOpts{
+			Subsystem:      MaximalPermissionPolicyAuthorizerSubsystem,
+			Name:           "apibinding_scan_overflows_total",
+			Help:           "Number of requests exceeding the API binding scan limit without a matching API binding.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+
+	// denials counts the requests not permitted by the maximal permission policy, by consumer tenant.
+	denials = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      MaximalPermissionPolicyAuthorizerSubsystem,
+			Name:           "denials_total",
+			Help:           "Number of requests not permitted by the maximal permission policy, partitioned by the top-level workspace of the consumer.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"tenant"},
+	)
+
+	// candidateDecisions counts the would-be decisions of candidate policies of API exports.
+	candidateDecisions = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      MaximalPermissionPolicyAuthorizerSubsystem,
+			Name:           "candidate_decisions_total",
+			Help:           "Number of would-be decisions of audit-only candidate maximal permission policies, partitioned by decision.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"decision"},
+	)
+
+	// suppressedDenials counts the denials identical to a previous denial within the deduplication window.
+	suppressedDenials = metrics.NewCounter(
+		&metrics.CounterOpts{
+			Subsystem:      MaximalPermissionPolicyAuthorizerSubsystem,
+			Name:           "suppressed_denials_total",
+			Help:           "Number of requests not permitted by the maximal permission policy identical to a previous denial within the deduplication window.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+
+	// circuitBreakerOpens counts the openings of the circuit breakers of API export clusters.
+	circuitBreakerOpens = metrics.NewCounter(
+		&metrics.CounterOpts{
+			Subsystem:      MaximalPermissionPolicyAuthorizerSubsystem,
+			Name:           "circuit_breaker_opens_total",
+			Help:           "Number of times the RBAC evaluation of an API export cluster was suspended after consecutive errors.",
+			StabilityLevel: metrics.ALPHA,
+		},
+	)
+
+	// decisions counts the decisions of the authorizers enabled with WithMetrics, by decision, by whether the requested
+	// resource was bound and by API export name. Clusters are deliberately not a label to keep the cardinality bounded.
+	decisions = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      MaximalPermissionPolicyAuthorizerSubsystem,
+			Name:           "decisions_total",
+			Help:           "Number of decisions of the maximal permission policy authorizer, partitioned by decision, by whether the resource was bound and by API export name.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"decision", "bound", "export"},
+	)
+
+	// rbacDurations times the RBAC evaluations of local and global maximal permission policies in the API export
+	// clusters of the authorizers enabled with WithMetrics, by API export name, excluding the delegate.
+	rbacDurations = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      MaximalPermissionPolicyAuthorizerSubsystem,
+			Name:           "rbac_evaluation_duration_seconds",
+			Help:           "Duration of the RBAC evaluations of maximal permission policies in API export clusters, partitioned by API export name.",
+			Buckets:        metrics.ExponentialBuckets(0.0001, 2, 15),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"export"},
+	)
+
+	maximalPermissionPolicyMetrics = []metrics.Registerable{
+		decisions,
+		rbacDurations,
+		apiBindingScanOverflows,
+		denials,
+		candidateDecisions,
+		suppressedDenials,
+		circuitBreakerOpens,
+	}
+)
+
+// decisionLabel returns the value of the decision label of the decisions counter.
+func decisionLabel(dec authorizer.Decision) string {
+	switch dec {
+	case authorizer.DecisionAllow:
+		return "allow"
+	case authorizer.DecisionDeny:
+		return "deny"
+	default:
+		return "noopinion"
+	}
+}
+
+// MetricsRegistry is a registry the metrics of the authorizer are registered with, see WithMetrics.
+type MetricsRegistry interface {
+	Register(metrics.Registerable) error
+}
+
+// MetricsRegistryFunc is a function registering metrics, e.g. legacyregistry.Register.
+type MetricsRegistryFunc func(metrics.Registerable) error
+
+// Register calls f(m).
+func (f MetricsRegistryFunc) Register(m metrics.Registerable) error {
+	return f(m)
+}
+
+// registerMetrics registers the maximal permission policy authorizer metrics with the registry, tolerating metrics
+// already registered with it, e.g. by another authorizer sharing the registry.
+func registerMetrics(registry MetricsRegistry) error {
+	var errs []error
+	for _, m := range maximalPermissionPolicyMetrics {
+		if err := registry.Register(m); err != nil && !errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// consumerTenant returns the bounded-cardinality tenant label of the given request cluster, i.e. the
+// top-level workspace path like "root:org" for "root:org:team". Logical cluster names are workspace paths,
+// hence the tenant derives from the name directly. Clusters without a top-level workspace are returned as is.
+func consumerTenant(clusterName logicalcluster.Name) string {
+	segments := strings.SplitN(clusterName.String(), ":", 3)
+	if len(segments) > 2 {
+		segments = segments[:2]
+	}
+	return strings.Join(segments, ":")
+}
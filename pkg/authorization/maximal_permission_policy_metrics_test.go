@@ -0,0 +1,157 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"testing"
+
+	kcpfakeclient "github.com/kcp-dev/client-go/clients/clientset/versioned/fake"
+	kcpkubernetesinformers "github.com/kcp-dev/client-go/clients/informers"
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/testutil"
+	"k8s.io/kubernetes/pkg/controller"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	kcpfakeinformerclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned/fake"
+	kcpinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
+)
+
+func TestConsumerTenant(t *testing.T) {
+	for _, tt := range []struct {
+		cluster string
+		want    string
+	}{
+		{cluster: "root", want: "root"},
+		{cluster: "root:org", want: "root:org"},
+		{cluster: "root:org:team", want: "root:org"},
+		{cluster: "root:org:team:project:app", want: "root:org"},
+		{cluster: "system:admin", want: "system:admin"},
+	} {
+		t.Run(tt.cluster, func(t *testing.T) {
+			require.Equal(t, tt.want, consumerTenant(logicalcluster.New(tt.cluster)))
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyDenialsMetric(t *testing.T) {
+	for _, cluster := range []string{"root:org:team-a", "root:org:team-b"} {
+		a := newTestMaximalPermissionPolicyAuthorizer(t,
+			[]*apisv1alpha1.APIBinding{newAPIBinding(cluster, "widgets", "root:provider", "widgets",
+				apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+			)},
+			[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+			&recordingAuthorizer{decision: authorizer.DecisionNoOpinion},
+			&recordingAuthorizer{decision: authorizer.DecisionAllow},
+			WithMetrics(metrics.NewKubeRegistry()),
+		)
+
+		before, err := testutil.GetCounterMetricValue(denials.WithLabelValues("root:org"))
+		require.NoError(t, err)
+		dec, _, err := a.Authorize(withCluster(cluster), &authorizer.AttributesRecord{
+			User:            &user.DefaultInfo{Name: "user-1"},
+			Verb:            "get",
+			APIGroup:        "widgets.example.io",
+			Resource:        "widgets",
+			ResourceRequest: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, authorizer.DecisionNoOpinion, dec)
+		after, err := testutil.GetCounterMetricValue(denials.WithLabelValues("root:org"))
+		require.NoError(t, err)
+		require.Equal(t, before+1, after, "expected the denial in %q to be counted for tenant root:org", cluster)
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerMetrics(t *testing.T) {
+	inner := authorizer.AuthorizerFunc(func(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+		if attr.GetVerb() == "delete" {
+			return authorizer.DecisionNoOpinion, "", nil
+		}
+		return authorizer.DecisionAllow, "", nil
+	})
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		)},
+		[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+		inner, &recordingAuthorizer{decision: authorizer.DecisionAllow},
+		WithMetrics(metrics.NewKubeRegistry()),
+	)
+
+	wantDecisions := []struct {
+		labels []string
+		want   float64
+	}{
+		{labels: []string{"allow", "true", "widgets"}, want: 1},
+		{labels: []string{"noopinion", "true", "widgets"}, want: 1},
+		{labels: []string{"allow", "false", ""}, want: 2},
+	}
+	before := make([]float64, len(wantDecisions))
+	for i, tt := range wantDecisions {
+		var err error
+		before[i], err = testutil.GetCounterMetricValue(decisions.WithLabelValues(tt.labels...))
+		require.NoError(t, err)
+	}
+	beforeRBAC, err := testutil.GetHistogramMetricCount(rbacDurations.WithLabelValues("widgets"))
+	require.NoError(t, err)
+
+	for _, req := range []struct {
+		cluster, verb, group, resource string
+	}{
+		{"root:consumer", "get", "widgets.example.io", "widgets"},
+		{"root:other", "get", "widgets.example.io", "widgets"},
+		{"root:consumer", "delete", "widgets.example.io", "widgets"},
+		{"root:consumer", "get", "", "configmaps"},
+	} {
+		_, _, err := a.Authorize(withCluster(req.cluster), &authorizer.AttributesRecord{
+			User:            &user.DefaultInfo{Name: "user-1"},
+			Verb:            req.verb,
+			APIGroup:        req.group,
+			Resource:        req.resource,
+			ResourceRequest: true,
+		})
+		require.NoError(t, err)
+	}
+
+	for i, tt := range wantDecisions {
+		got, err := testutil.GetCounterMetricValue(decisions.WithLabelValues(tt.labels...))
+		require.NoError(t, err)
+		require.Equal(t, tt.want, got-before[i], "labels %v", tt.labels)
+	}
+
+	// one RBAC evaluation per bound request, none for the unbound ones
+	got, err := testutil.GetHistogramMetricCount(rbacDurations.WithLabelValues("widgets"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), got-beforeRBAC)
+}
+
+func TestMaximalPermissionPolicyAuthorizerMetricsSharedRegistry(t *testing.T) {
+	registry := metrics.NewKubeRegistry()
+	for i := 0; i < 2; i++ {
+		kubeInformers := kcpkubernetesinformers.NewSharedInformerFactory(kcpfakeclient.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+		kcpInformers := kcpinformers.NewSharedInformerFactory(kcpfakeinformerclient.NewSimpleClientset(), controller.NoResyncPeriodFunc())
+		a, err := NewMaximalPermissionPolicyAuthorizer(kubeInformers, kcpInformers, &recordingAuthorizer{decision: authorizer.DecisionAllow}, WithMetrics(registry))
+		require.NoError(t, err, "expected authorizer %d to register with the shared registry", i)
+		require.True(t, a.(*MaximalPermissionPolicyAuthorizer).metrics)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+// MaximalPermissionPolicyAuditDenyingExport is the name of the API export whose maximal permission policy does not
+// permit a request for a resource bound by multiple API bindings with RequireAll.
+const MaximalPermissionPolicyAuditDenyingExport = MaximalPermissionPolicyAuditPrefix + "denying-export"
+
+// MultiExportPolicy determines which API exports constrain requests for a resource bound by multiple API bindings
+// of the cluster, e.g. by layered APIs.
+type MultiExportPolicy string
+
+const (
+	// FirstMatch evaluates the maximal permission policy of the API export of the first API binding by name only.
+	// It is the default.
+	FirstMatch MultiExportPolicy = "FirstMatch"
+	// RequireAll evaluates the maximal permission policies of the API exports of all API bindings binding the resource,
+	// in the order of the API bindings by name, and delegates only if all of them permit the request.
+	RequireAll MultiExportPolicy = "RequireAll"
+)
+
+// WithMultiExportPolicy sets which API exports constrain requests for a resource bound by multiple API bindings,
+// FirstMatch by default. With RequireAll, the decision and reason of the first API export not permitting the request
+// are returned, i.e. DecisionNoOpinion unless the RBAC of its cluster denies explicitly, and the API export is recorded
+// in the MaximalPermissionPolicyAuditDenyingExport audit annotation. It has no effect with WithBindingMatcher.
+func WithMultiExportPolicy(policy MultiExportPolicy) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.multiExportPolicy = policy
+	}
+}
+
+// withoutDelegation returns a context for which requests permitted by the maximal permission policy are allowed
+// instead of delegated, i.e. for evaluating the policies of API exports before the last one with RequireAll.
+func withoutDelegation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutDelegationKey, true)
+}
+
+// isWithoutDelegation returns whether the context disables delegating.
+func isWithoutDelegation(ctx context.Context) bool {
+	without, _ := ctx.Value(withoutDelegationKey).(bool)
+	return without
+}
+
+// authorizeAllBindingMatches evaluates the maximal permission policies of the API exports of all API bindings of the
+// given match cluster binding the requested resource for a request to the given cluster without delegating, and
+// delegates once all of them permit it. API exports exempt for maintenance permit the request.
+func (a *MaximalPermissionPolicyAuthorizer) authorizeAllBindingMatches(ctx context.Context, attr authorizer.Attributes, lcluster, matchCluster logicalcluster.Name, details *MaximalPermissionPolicyDecisionDetails) (authorizer.Decision, string, error) {
+	matches, err := a.matchAllAPIBindings(attr, matchCluster)
+	if err != nil {
+		dec := a.failureDecision()
+		addAuditAnnotations(
+			ctx,
+			MaximalPermissionPolicyAuditDecision, DecisionString(dec),
+			MaximalPermissionPolicyAuditReason, fmt.Sprintf("error getting API binding references: %v", err),
+		)
+		return dec, ReasonBindingLookupFailed, err
+	}
+
+	if len(matches) == 0 {
+		// the API bindings changed since matching the first one
+		return a.allow(ctx, attr, details, ReasonNotBound, "no API binding bound")
+	}
+
+	// Each API export is evaluated with its own audit annotations and details, such that only those of the API export
+	// not permitting the request, or of the last one if all permit it, are recorded.
+	var annotations *auditAnnotations
+	var matchDetails MaximalPermissionPolicyDecisionDetails
+	for _, match := range matches {
+		var matchCtx context.Context
+		matchCtx, annotations = withAuditAnnotations(withoutDelegation(ctx))
+		matchDetails = *details
+		dec, reason, err := a.authorizeBindingMatch(matchCtx, attr, lcluster, match, &matchDetails)
+		if err == nil && dec == authorizer.DecisionAllow {
+			continue
+		}
+
+		addAuditAnnotations(ctx, annotations.keysAndValues()...)
+		*details = matchDetails
+		if err != nil {
+			return dec, reason, err
+		}
+		exportName := "unknown"
+		if match.ExportReference.Workspace != nil {
+			exportName = match.ExportReference.Workspace.ExportName
+		}
+		addAuditAnnotations(
+			ctx,
+			MaximalPermissionPolicyAuditBinding, match.APIBindingName,
+			MaximalPermissionPolicyAuditDenyingExport, exportName,
+		)
+		return dec, reason, nil
+	}
+	addAuditAnnotations(ctx, annotations.keysAndValues()...)
+	*details = matchDetails
+
+	if a.terminalDecision {
+		return authorizer.DecisionAllow, ReasonPolicyAllowed, nil
+	}
+	return a.authorizeDelegate(ctx, attr, details)
+}
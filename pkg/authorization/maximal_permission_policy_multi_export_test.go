@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+func TestMaximalPermissionPolicyAuthorizerMultiExportPolicy(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		policy            MultiExportPolicy
+		firstDecision     authorizer.Decision
+		secondDecision    authorizer.Decision
+		wantDecision      authorizer.Decision
+		wantReason        string
+		wantDenyingExport string
+		wantSecondCalled  bool
+	}{
+		{name: "first match by default", firstDecision: authorizer.DecisionAllow, secondDecision: authorizer.DecisionNoOpinion, wantDecision: authorizer.DecisionAllow, wantReason: "delegate"},
+		{name: "both allow", policy: RequireAll, firstDecision: authorizer.DecisionAllow, secondDecision: authorizer.DecisionAllow, wantDecision: authorizer.DecisionAllow, wantReason: "delegate", wantSecondCalled: true},
+		{name: "second denies", policy: RequireAll, firstDecision: authorizer.DecisionAllow, secondDecision: authorizer.DecisionNoOpinion, wantDecision: authorizer.DecisionNoOpinion, wantReason: ReasonPolicyDenied, wantDenyingExport: "widgets-v2", wantSecondCalled: true},
+		{name: "first denies", policy: RequireAll, firstDecision: authorizer.DecisionNoOpinion, secondDecision: authorizer.DecisionAllow, wantDecision: authorizer.DecisionNoOpinion, wantReason: ReasonPolicyDenied, wantDenyingExport: "widgets-v1"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			first := &recordingAuthorizer{decision: tt.firstDecision}
+			second := &recordingAuthorizer{decision: tt.secondDecision}
+			delegate := &recordingAuthorizer{decision: authorizer.DecisionAllow, reason: "delegate"}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{
+					newAPIBinding("root:consumer", "widgets-v1", "root:provider-1", "widgets-v1",
+						apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+					),
+					newAPIBinding("root:consumer", "widgets-v2", "root:provider-2", "widgets-v2",
+						apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+					),
+				},
+				[]*apisv1alpha1.APIExport{
+					newAPIExport("root:provider-1", "widgets-v1", withLocalPolicy()),
+					newAPIExport("root:provider-2", "widgets-v2", withLocalPolicy()),
+				},
+				nil, delegate,
+				WithClusterAuthorizerFactory(func(clusterName logicalcluster.Name) authorizer.Authorizer {
+					if clusterName.String() == "root:provider-2" {
+						return second
+					}
+					return first
+				}),
+				WithMultiExportPolicy(tt.policy),
+			)
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, reason, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, tt.wantReason, reason)
+			require.Equal(t, tt.wantDenyingExport, ev.Annotations[MaximalPermissionPolicyAuditDenyingExport])
+			require.NotNil(t, first.recordedAttributes, "expected the policy of the first API export to be evaluated")
+			require.Equal(t, tt.wantSecondCalled, second.recordedAttributes != nil, "unexpected evaluation of the policy of the second API export")
+			require.Equal(t, tt.wantDecision == authorizer.DecisionAllow, delegate.recordedAttributes != nil, "unexpected delegation")
+		})
+	}
+}
+
+func TestMaximalPermissionPolicyAuthorizerMultiExportPolicyAudit(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		firstPolicy       bool
+		exempt            string
+		secondDecision    authorizer.Decision
+		wantDecision      authorizer.Decision
+		wantReasonCode    string
+		wantDenyingExport string
+		wantSecondCalled  bool
+	}{
+		{name: "first without policy, second denies", secondDecision: authorizer.DecisionNoOpinion, wantDecision: authorizer.DecisionNoOpinion, wantDenyingExport: "widgets-v2", wantSecondCalled: true},
+		{name: "first without policy, second allows", secondDecision: authorizer.DecisionAllow, wantDecision: authorizer.DecisionAllow, wantReasonCode: ReasonPolicyAllowed, wantSecondCalled: true},
+		{name: "first exempt for maintenance, second denies", firstPolicy: true, exempt: "widgets-v1", secondDecision: authorizer.DecisionNoOpinion, wantDecision: authorizer.DecisionNoOpinion, wantDenyingExport: "widgets-v2", wantSecondCalled: true},
+		{name: "second exempt for maintenance", firstPolicy: true, exempt: "widgets-v2", secondDecision: authorizer.DecisionNoOpinion, wantDecision: authorizer.DecisionAllow, wantReasonCode: ReasonMaintenanceExempt},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			first := &recordingAuthorizer{decision: authorizer.DecisionAllow}
+			second := &recordingAuthorizer{decision: tt.secondDecision}
+			var firstPolicy *apisv1alpha1.MaximalPermissionPolicy
+			if tt.firstPolicy {
+				firstPolicy = withLocalPolicy()
+			}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{
+					newAPIBinding("root:consumer", "widgets-v1", "root:provider-1", "widgets-v1",
+						apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+					),
+					newAPIBinding("root:consumer", "widgets-v2", "root:provider-2", "widgets-v2",
+						apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+					),
+				},
+				[]*apisv1alpha1.APIExport{
+					newAPIExport("root:provider-1", "widgets-v1", firstPolicy),
+					newAPIExport("root:provider-2", "widgets-v2", withLocalPolicy()),
+				},
+				nil, &recordingAuthorizer{decision: authorizer.DecisionAllow, reason: "delegate"},
+				WithClusterAuthorizerFactory(func(clusterName logicalcluster.Name) authorizer.Authorizer {
+					if clusterName.String() == "root:provider-2" {
+						return second
+					}
+					return first
+				}),
+				WithMultiExportPolicy(RequireAll),
+			)
+			switch tt.exempt {
+			case "widgets-v1":
+				a.SetMaintenanceExemptExports(apisv1alpha1.WorkspaceExportReference{Path: "root:provider-1", ExportName: "widgets-v1"})
+			case "widgets-v2":
+				a.SetMaintenanceExemptExports(apisv1alpha1.WorkspaceExportReference{Path: "root:provider-2", ExportName: "widgets-v2"})
+			}
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, _, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        "widgets.example.io",
+				Resource:        "widgets",
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, DecisionString(tt.wantDecision), ev.Annotations[MaximalPermissionPolicyAuditDecision])
+			require.Equal(t, tt.wantReasonCode, ev.Annotations[MaximalPermissionPolicyAuditReasonCode], "expected no reason code of another API export")
+			require.Equal(t, tt.wantDenyingExport, ev.Annotations[MaximalPermissionPolicyAuditDenyingExport])
+			require.Equal(t, tt.wantSecondCalled, second.recordedAttributes != nil, "unexpected evaluation of the policy of the second API export")
+		})
+	}
+}
@@ -0,0 +1,431 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/utils/clock"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+// FailurePolicy determines the decision of requests whose API binding or API export lookup, or RBAC evaluation fails.
+type FailurePolicy string
+
+const (
+	// FailOpen returns DecisionNoOpinion, deferring to the other authorizers of a union. It is the default.
+	FailOpen FailurePolicy = "FailOpen"
+	// FailClosed returns DecisionDeny.
+	FailClosed FailurePolicy = "FailClosed"
+)
+
+// MaximalPermissionPolicyAuthorizerOption configures optional behaviour of a MaximalPermissionPolicyAuthorizer.
+type MaximalPermissionPolicyAuthorizerOption func(*MaximalPermissionPolicyAuthorizer)
+
+// WithDenialWarnings makes the authorizer attach a warning to write requests denied by
+// the maximal permission policy, naming the API export and the reason of the denial.
+func WithDenialWarnings() MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.denialWarnings = true
+	}
+}
+
+// WithAPIBindingScanLimit limits the number of API bindings scanned per request when looking for the one binding the requested resource.
+// If the limit is exceeded without a match, the request is not further authorized and the given decision is returned.
+// The decision must be DecisionDeny or DecisionNoOpinion, NewMaximalPermissionPolicyAuthorizer fails otherwise. A limit of
+// zero disables the limit. With the indexers.APIBindingByClusterAndBoundGroupResource index, which avoids the scan,
+// the limit is a backstop against more API bindings binding the requested resource than the limit.
+func WithAPIBindingScanLimit(limit int, overflowDecision authorizer.Decision) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		if overflowDecision == authorizer.DecisionAllow {
+			a.optionErrs = append(a.optionErrs, errors.New("API binding scan overflow decision must not be DecisionAllow"))
+			return
+		}
+		a.apiBindingScanLimit = limit
+		a.apiBindingScanOverflowDecision = overflowDecision
+	}
+}
+
+// WithConsumerParentRBAC makes the authorizer merge the ClusterRoles, ClusterRoleBindings and Roles of the
+// parent workspace of the requesting cluster into the RBAC the maximal permission policy is evaluated against.
+func WithConsumerParentRBAC() MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.consumerParentRBAC = true
+	}
+}
+
+// WithInheritanceLookup makes the authorizer look for the API binding of the requested resource in the ancestors of the
+// requesting cluster if the cluster has none, e.g. for workspaces inheriting the API bindings of their parent. The given
+// function returns the parent of a cluster, or the empty name for a cluster without parent. The first ancestor binding
+// the resource is evaluated, and it is recorded in the MaximalPermissionPolicyAuditInheritedFrom audit annotation.
+func WithInheritanceLookup(parent func(clusterName logicalcluster.Name) logicalcluster.Name) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.inheritanceLookup = parent
+	}
+}
+
+// WithVirtualResourceMatcher makes the authorizer evaluate the maximal permission policy of the API export referenced by
+// the given matcher for requested resources not bound by any API binding, e.g. virtual resources served through the
+// virtual workspace of an API export. The matcher is consulted after the API bindings, including inherited ones.
+// A matched request is evaluated like a request for a bound resource, and recorded in the
+// MaximalPermissionPolicyAuditVirtualResource audit annotation.
+func WithVirtualResourceMatcher(matcher func(attr authorizer.Attributes) (*apisv1alpha1.ExportReference, bool)) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.virtualResourceMatcher = matcher
+	}
+}
+
+// WithVerbFilter makes the authorizer evaluate the maximal permission policy only for requests whose verb the given
+// filter returns true for, e.g. only for mutating verbs. Requests with other verbs are delegated right away, without
+// looking up API bindings and API exports, i.e. they are not constrained by the policy at all. By default, requests
+// of all verbs are evaluated.
+func WithVerbFilter(filter func(verb string) bool) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.verbFilter = filter
+	}
+}
+
+// WithStrictEmptyPolicy makes the authorizer deny requests, i.e. return DecisionDeny, if the local maximal permission
+// policy of the API export grants nothing on the requested resource, i.e. no (Cluster)RoleBinding the policy is evaluated
+// against binds a prefixed user or group to a rule for the resource. A policy empty for the resource is then taken as
+// "deny" rather than having no opinion, which other authorizers of the chain might turn into an allow. The bindings of
+// prefixed users and groups are indexed, hence the option must be passed to NewMaximalPermissionPolicyAuthorizer before
+// the informers are started. By default, empty policies have no opinion like any other request not permitted by the policy.
+func WithStrictEmptyPolicy(strict bool) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.strictEmptyPolicy = strict
+	}
+}
+
+// WithPendingAPIBindingsNoOpinion makes the authorizer have no opinion on requests for resources not bound by any
+// API binding of the requesting cluster, but served by the latest resource schemas of the API export of an API binding
+// of the cluster without bound resources in its status yet, e.g. during its initial reconciliation. Otherwise, requests
+// for the resources about to be bound are not constrained by the policy until the status catches up. Requests for other
+// resources, e.g. core resources, are delegated as usual, as are those of pending API bindings whose API export is not found.
+func WithPendingAPIBindingsNoOpinion() MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.pendingAPIBindingsNoOpinion = true
+	}
+}
+
+// WithIncompleteRequestInfoDecision sets the decision the authorizer returns for requests with incomplete request info,
+// i.e. resource requests without resource or non-resource requests without path, DecisionNoOpinion by default.
+// The decision must be DecisionDeny or DecisionNoOpinion, NewMaximalPermissionPolicyAuthorizer fails otherwise.
+func WithIncompleteRequestInfoDecision(decision authorizer.Decision) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		if decision == authorizer.DecisionAllow {
+			a.optionErrs = append(a.optionErrs, errors.New("incomplete request info decision must not be DecisionAllow"))
+			return
+		}
+		a.incompleteRequestInfoDecision = decision
+	}
+}
+
+// WithBindingMatcher replaces the default matcher finding the API binding of the requested resource,
+// e.g. to plug in experimental binding schemes. WithAPIBindingScanLimit does not apply to a custom matcher.
+func WithBindingMatcher(matcher BindingMatcher) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.bindingMatcher = matcher
+		a.customBindingMatcher = true
+	}
+}
+
+// WithClusterAuthorizerFactory replaces the RBAC authorizers the maximal permission policy of an API export is evaluated
+// with by those of the given factory, called with the logical cluster of the API export, e.g. to evaluate policies
+// stored in an external system through a SubjectAccessReview webhook. The authorizers are cached like the RBAC ones.
+// The RBAC of the merge clusters, see WithConsumerParentRBAC, and WithExplicitRBACVerbs do not apply to them.
+func WithClusterAuthorizerFactory(factory func(clusterName logicalcluster.Name) authorizer.Authorizer) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.clusterAuthorizerFactory = factory
+	}
+}
+
+// WithCrossShardExportResolver makes the authorizer fall back to the given resolver for API exports
+// not found locally, e.g. because they live on another shard. Results are cached for the given ttl,
+// each resolution is bounded by the given timeout.
+func WithCrossShardExportResolver(resolver CrossShardExportResolver, ttl, timeout time.Duration) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.crossShardExportResolver = newCachingCrossShardExportResolver(resolver, ttl, timeout, clock.RealClock{})
+	}
+}
+
+// WithExplicitRBACVerbs makes the authorizer ignore "*" verbs of the RBAC rules the maximal permission policy is
+// evaluated against, i.e. only explicitly listed verbs count toward the ceiling. By default "*" matches all verbs.
+func WithExplicitRBACVerbs() MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.explicitRBACVerbs = true
+	}
+}
+
+// WithRBACRetry makes the authorizer retry the RBAC evaluation in the API export cluster up to the given number
+// of attempts if it fails with a transient error, e.g. a timeout. The backoff between attempts starts with
+// the given duration and doubles with every attempt. By default the RBAC evaluation is not retried.
+func WithRBACRetry(attempts int, backoff time.Duration) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.rbacRetryAttempts = attempts
+		a.rbacRetryBackoff = backoff
+	}
+}
+
+// WithDenialDeduplication makes the authorizer suppress the denial warnings, see WithDenialWarnings, of denials
+// identical to a previous one within the given window, i.e. of the same user, API export, resource and verb.
+// Suppressed denials are still audited and counted.
+func WithDenialDeduplication(window time.Duration) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.denialDeduplicator = newDenialDeduplicator(window, clock.RealClock{})
+	}
+}
+
+// WithCollectionGetAsList makes the authorizer evaluate the RBAC of the maximal permission policy for a get without
+// name and subresource as list, e.g. for SubjectAccessReviews of clients asking for get on a collection.
+// By default the verb is evaluated as is.
+func WithCollectionGetAsList() MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.collectionGetAsList = true
+	}
+}
+
+// WithFallbackClusters sets the clusters whose ClusterRoles, ClusterRoleBindings and Roles are merged into the RBAC
+// the maximal permission policy is evaluated against, e.g. multiple bootstrap clusters. By default, it is the
+// local admin cluster. Whether they are merged is controlled like for the local admin cluster,
+// see WithoutAdminClusterRBACMergeByDefault.
+func WithFallbackClusters(clusters ...logicalcluster.Name) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.fallbackClusters = clusters
+	}
+}
+
+// WithoutAdminClusterRBACMergeByDefault makes the authorizer not merge the RBAC of the local admin cluster,
+// unless enabled for an API export with MaximalPermissionPolicyAdminClusterMergeAnnotationKey.
+func WithoutAdminClusterRBACMergeByDefault() MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.withoutAdminClusterRBACMergeByDefault = true
+	}
+}
+
+// WithSystemMastersBypass makes the authorizer delegate requests of members of the system:masters group without
+// evaluating the maximal permission policy, like RBAC is bypassed for them, and record the bypass in the
+// MaximalPermissionPolicyAuditSystemMastersBypass audit annotation. The system:masters group is exempt
+// by default, this adds it back to exempt groups set with WithExemptGroups.
+func WithSystemMastersBypass() MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		if a.exemptGroups == nil {
+			a.exemptGroups = sets.NewString()
+		}
+		a.exemptGroups.Insert(user.SystemPrivilegedGroup)
+	}
+}
+
+// WithExemptGroups replaces the groups whose members are never constrained by the maximal permission policy,
+// by default only system:masters. Their requests are delegated without evaluating the policy, recording the
+// exempt group in the MaximalPermissionPolicyAuditExemptGroup audit annotation. No groups disables the exemption.
+func WithExemptGroups(groups []string) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.exemptGroups = sets.NewString(groups...)
+	}
+}
+
+// WithExemptNamespaces sets the namespaces of consumer workspaces whose requests are never constrained by the maximal
+// permission policy, e.g. shared system namespaces. Their requests are delegated without evaluating the policy, recording
+// the namespace in the MaximalPermissionPolicyAuditExemptNamespace audit annotation. Cluster-scoped requests are never exempt.
+func WithExemptNamespaces(namespaces []string) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.exemptNamespaces = sets.NewString(namespaces...)
+	}
+}
+
+// WithDecisionLogs makes the authorizer log every decision as a single structured line at
+// MaximalPermissionPolicyDecisionLogLevel through the contextual logger. It is meant for local development.
+func WithDecisionLogs() MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.decisionLogs = true
+	}
+}
+
+// WithCircuitBreaker makes the authorizer stop evaluating the RBAC of an API export cluster for the given cooldown
+// after threshold consecutive evaluation errors within the given window, e.g. because of corrupt RBAC data, returning
+// the given decision instead. Threshold, window and cooldown must be positive, and the decision must be DecisionDeny
+// or DecisionNoOpinion, NewMaximalPermissionPolicyAuthorizer fails otherwise. After the cooldown, the next request
+// evaluates the RBAC again, closing the breaker on success and reopening it on error.
+func WithCircuitBreaker(threshold int, window, cooldown time.Duration, openDecision authorizer.Decision) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		if threshold <= 0 || window <= 0 || cooldown <= 0 {
+			a.optionErrs = append(a.optionErrs, fmt.Errorf("circuit breaker threshold, window and cooldown must be positive, got threshold %d, window %v and cooldown %v", threshold, window, cooldown))
+			return
+		}
+		if openDecision == authorizer.DecisionAllow {
+			a.optionErrs = append(a.optionErrs, errors.New("circuit breaker decision must not be DecisionAllow"))
+			return
+		}
+		a.circuitBreakers = newCircuitBreakers(threshold, window, cooldown, openDecision, clock.RealClock{})
+	}
+}
+
+// WithRBACConcurrencyLimit limits the number of concurrent RBAC evaluations of the maximal permission policy per
+// API export cluster, e.g. to avoid thundering herds of expensive evaluations. Requests waiting longer than the
+// given timeout for an evaluation to finish are not permitted. A limit of zero or less disables the limit.
+func WithRBACConcurrencyLimit(limit int, timeout time.Duration) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		if limit <= 0 {
+			a.rbacSemaphores = nil
+			return
+		}
+		a.rbacSemaphores = newClusterSemaphores(limit, timeout)
+	}
+}
+
+// WithUserGroupPrefix overrides the prefix of the user and group names when evaluating a local maximal permission policy,
+// apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix by default. It allows running multiple authorizers side by side
+// without RBAC collisions, e.g. in tests. An empty prefix means the default.
+func WithUserGroupPrefix(prefix string) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.userGroupPrefix = prefix
+	}
+}
+
+// WithUserNameNormalizer normalizes the user name, e.g. with strings.ToLower, before it is prefixed and evaluated
+// against the RBAC of the API export cluster, for identity providers sending the same user with differing
+// capitalization. The groups are normalized with the same function. By default, names are used as they are.
+func WithUserNameNormalizer(normalize func(name string) string) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.userNameNormalizer = normalize
+	}
+}
+
+// IdentityRewriter rewrites the user a local maximal permission policy is evaluated for against the RBAC of the
+// API export cluster, see WithIdentityRewriter.
+type IdentityRewriter interface {
+	// Rewrite returns the user to evaluate the policy for. The given user is a copy and may be returned modified.
+	Rewrite(user.Info) user.Info
+}
+
+// IdentityRewriterFunc is an IdentityRewriter function.
+type IdentityRewriterFunc func(user.Info) user.Info
+
+// Rewrite calls f(u).
+func (f IdentityRewriterFunc) Rewrite(u user.Info) user.Info {
+	return f(u)
+}
+
+// PrefixIdentityRewriter returns the default IdentityRewriter prefixing the user and group names with the given prefix,
+// after normalizing them with the given function unless nil. Custom rewriters may delegate to it.
+func PrefixIdentityRewriter(prefix string, normalize func(name string) string) IdentityRewriter {
+	if normalize == nil {
+		normalize = func(name string) string { return name }
+	}
+	return IdentityRewriterFunc(func(u user.Info) user.Info {
+		userInfo := &user.DefaultInfo{
+			Name:   prefix + normalize(u.GetName()),
+			UID:    u.GetUID(),
+			Groups: make([]string, 0, len(u.GetGroups())),
+			Extra:  u.GetExtra(),
+		}
+		for _, g := range u.GetGroups() {
+			userInfo.Groups = append(userInfo.Groups, prefix+normalize(g))
+		}
+		return userInfo
+	})
+}
+
+// WithIdentityRewriter replaces the prefixing of the user and group names a local maximal permission policy is
+// evaluated for, e.g. to map external groups to the groups the RBAC of the API export clusters binds. The rewriter
+// is responsible for the prefix, see PrefixIdentityRewriter, and for WithUserNameNormalizer. Global and candidate
+// policies are not affected, and WithStrictEmptyPolicy does not apply as the rewritten names are unknown up front.
+// By default, user and group names are prefixed with the prefix of WithUserGroupPrefix.
+func WithIdentityRewriter(rewriter IdentityRewriter) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.identityRewriter = rewriter
+	}
+}
+
+// WithMetrics enables the metrics of the authorizer, registering them with the given registry, e.g. a
+// metrics.KubeRegistry or MetricsRegistryFunc(legacyregistry.Register). Decisions are counted by decision, by whether
+// the resource was bound and by API export name, RBAC evaluations are timed by API export name, and denials are counted
+// by the top-level workspace of the consumer, none by cluster. The metrics are shared by all authorizers, hence several
+// authorizers may register with the same registry.
+func WithMetrics(registry MetricsRegistry) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		if err := registerMetrics(registry); err != nil {
+			a.optionErrs = append(a.optionErrs, err)
+			return
+		}
+		a.metrics = true
+	}
+}
+
+// WithDecisionCache caches the RBAC decisions of maximal permission policies in an LRU cache of the given size
+// for the given TTL, e.g. for controllers repeating the same requests. Cached decisions are not invalidated
+// when RBAC changes, hence they may be stale for up to the TTL. Size and TTL must be positive,
+// NewMaximalPermissionPolicyAuthorizer fails otherwise.
+func WithDecisionCache(size int, ttl time.Duration) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		if size <= 0 || ttl <= 0 {
+			a.optionErrs = append(a.optionErrs, fmt.Errorf("decision cache size and TTL must be positive, got size %d and TTL %v", size, ttl))
+			return
+		}
+		a.decisionCache = newDecisionCache(size, ttl, clock.RealClock{})
+	}
+}
+
+// WithWarningHandler sets a handler called with a warning for requests to resources bound to an API export
+// with the apisv1alpha1.APIExportDeprecated condition, e.g. to add a Warning header to the response.
+// The warning does not change the decision.
+func WithWarningHandler(handler func(ctx context.Context, message string)) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.warningHandler = handler
+	}
+}
+
+// WithTerminalDecision makes the authorizer allow requests permitted by the maximal permission policy of the API export
+// instead of delegating them, if terminal is true. Requests the policy does not apply to, e.g. for resources that are not
+// bound, for API exports without policy or of exempt groups, are still delegated. Combined with a nil delegate,
+// only requests permitted by a policy are allowed.
+func WithTerminalDecision(terminal bool) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.terminalDecision = terminal
+	}
+}
+
+// WithFailurePolicy sets the decision of requests whose API binding or API export lookup, or RBAC evaluation fails,
+// FailOpen by default. The policy must be FailOpen or FailClosed, NewMaximalPermissionPolicyAuthorizer fails otherwise.
+func WithFailurePolicy(policy FailurePolicy) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		if policy != FailOpen && policy != FailClosed {
+			a.optionErrs = append(a.optionErrs, fmt.Errorf("failure policy must be %s or %s, got %q", FailOpen, FailClosed, policy))
+			return
+		}
+		a.failurePolicy = policy
+	}
+}
+
+// WithEnforcement enables or disables enforcing the decisions of the authorizer, enabled by default. Without enforcement,
+// requests are evaluated fully, recording the would-be decisions in audit annotations, logs and metrics, but they are
+// delegated, e.g. to dark-launch policies. The audit annotations are marked with MaximalPermissionPolicyAuditEnforcement.
+func WithEnforcement(enforce bool) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.withoutEnforcement = !enforce
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+)
+
+// OrphanedAPIBindings returns the names of the API bindings referencing API exports that do not exist, by consumer cluster.
+// Requests for resources bound by them are not permitted because the maximal permission policy fails closed.
+// API exports are resolved like by Authorize, i.e. locally and through the cross-shard resolver if configured.
+// API bindings with an unsupported export reference are reported as well.
+func (a *MaximalPermissionPolicyAuthorizer) OrphanedAPIBindings(ctx context.Context) (map[logicalcluster.Name][]string, error) {
+	apiBindings, err := a.listAPIBindings()
+	if err != nil {
+		return nil, err
+	}
+
+	orphaned := map[logicalcluster.Name][]string{}
+	for _, apiBinding := range apiBindings {
+		_, found, err := a.getAPIExportByReference(&apiBinding.Spec.Reference, logicalcluster.New(apiBinding.Status.APIExportClusterName))
+		if errors.Is(err, ErrUnsupportedExportReference) {
+			// cannot be resolved, hence not permitted just like a missing API export
+			err = nil
+		} else if err == nil && !found && a.crossShardExportResolver != nil {
+			_, found, err = a.crossShardExportResolver.ResolveAPIExport(ctx, &apiBinding.Spec.Reference)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			clusterName := logicalcluster.From(apiBinding)
+			orphaned[clusterName] = append(orphaned[clusterName], apiBinding.Name)
+		}
+	}
+
+	for _, names := range orphaned {
+		sort.Strings(names)
+	}
+	return orphaned, nil
+}
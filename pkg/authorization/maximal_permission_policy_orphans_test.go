@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+func TestOrphanedAPIBindings(t *testing.T) {
+	bindings := []*apisv1alpha1.APIBinding{
+		newAPIBinding("root:consumer-1", "widgets", "root:provider", "widgets"),
+		newAPIBinding("root:consumer-1", "gadgets", "root:provider", "gadgets"),
+		newAPIBinding("root:consumer-1", "doodads", "root:provider", "doodads"),
+		newAPIBinding("root:consumer-2", "gadgets", "root:provider", "gadgets"),
+		newAPIBinding("root:consumer-2", "sprockets", "root:remote", "sprockets"),
+		newAPIBinding("root:consumer-3", "widgets", "root:provider", "widgets"),
+		newAPIBinding("root:consumer-3", "future", "root:provider", "future"),
+	}
+	bindings[len(bindings)-1].Spec.Reference = apisv1alpha1.ExportReference{}
+
+	for _, tt := range []struct {
+		name         string
+		crossShard   bool
+		wantOrphaned map[logicalcluster.Name][]string
+	}{
+		{
+			name: "local exports only",
+			wantOrphaned: map[logicalcluster.Name][]string{
+				logicalcluster.New("root:consumer-1"): {"doodads", "gadgets"},
+				logicalcluster.New("root:consumer-2"): {"gadgets", "sprockets"},
+				logicalcluster.New("root:consumer-3"): {"future"},
+			},
+		},
+		{
+			name:       "with cross-shard exports",
+			crossShard: true,
+			wantOrphaned: map[logicalcluster.Name][]string{
+				logicalcluster.New("root:consumer-1"): {"doodads", "gadgets"},
+				logicalcluster.New("root:consumer-2"): {"gadgets"},
+				logicalcluster.New("root:consumer-3"): {"future"},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []MaximalPermissionPolicyAuthorizerOption
+			if tt.crossShard {
+				opts = append(opts, WithCrossShardExportResolver(&fakeCrossShardExportResolver{exports: map[apisv1alpha1.WorkspaceExportReference]*apisv1alpha1.APIExport{
+					{Path: "root:remote", ExportName: "sprockets"}: newAPIExport("root:remote", "sprockets", withLocalPolicy()),
+				}}, time.Minute, time.Second))
+			}
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				bindings,
+				[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+				nil, nil,
+				opts...,
+			)
+
+			orphaned, err := a.OrphanedAPIBindings(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, tt.wantOrphaned, orphaned)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/client-go/tools/cache"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+// pendingAPIBindingsServing returns the names of the pending API bindings of the given cluster, see getPendingAPIBindings,
+// whose API export serves the requested resource by its latest resource schemas.
+func (a *MaximalPermissionPolicyAuthorizer) pendingAPIBindingsServing(ctx context.Context, attr authorizer.Attributes, clusterName logicalcluster.Name) ([]string, error) {
+	apiBindings, err := a.listPendingAPIBindings(clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	for _, apiBinding := range apiBindings {
+		apiExport, found, err := a.resolveAPIExport(ctx, &apiBinding.Spec.Reference, logicalcluster.New(apiBinding.Status.APIExportClusterName))
+		if err == nil && !found && a.crossShardExportResolver != nil {
+			apiExport, found, err = a.crossShardExportResolver.ResolveAPIExport(ctx, &apiBinding.Spec.Reference)
+		}
+		if err != nil {
+			return nil, err
+		}
+		group := mappedGroup(apiBinding.Annotations, MaximalPermissionPolicyGroupAliasesAnnotationKey, attr.GetAPIGroup())
+		if found && apiExportServes(apiExport, group, attr.GetResource()) {
+			pending = append(pending, apiBinding.Name)
+		}
+	}
+	return pending, nil
+}
+
+// getPendingAPIBindings returns the API bindings of the given cluster referencing an API export
+// without bound resources in their status yet, sorted by name, by the indexers.ByLogicalCluster index.
+func getPendingAPIBindings(apiBindingIndexer cache.Indexer, clusterName logicalcluster.Name) ([]*apisv1alpha1.APIBinding, error) {
+	objs, err := apiBindingIndexer.ByIndex(indexers.ByLogicalCluster, clusterName.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*apisv1alpha1.APIBinding
+	for _, obj := range objs {
+		apiBinding := obj.(*apisv1alpha1.APIBinding)
+		if apiBinding.Spec.Reference.Workspace != nil && len(apiBinding.Status.BoundResources) == 0 {
+			pending = append(pending, apiBinding)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Name < pending[j].Name
+	})
+	return pending, nil
+}
+
+// apiExportServes returns whether any of the latest resource schemas of the API export serves the resource of the
+// given group. Resource schema names are of the form "<prefix>.<resource>.<group>", with "core" for the core group.
+func apiExportServes(apiExport *apisv1alpha1.APIExport, group, resource string) bool {
+	for _, schemaName := range apiExport.Spec.LatestResourceSchemas {
+		parts := strings.SplitN(schemaName, ".", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		schemaGroup := parts[2]
+		if schemaGroup == "core" {
+			schemaGroup = ""
+		}
+		if parts[1] == resource && schemaGroup == group {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+func TestMaximalPermissionPolicyAuthorizerPendingAPIBindings(t *testing.T) {
+	bindings := []*apisv1alpha1.APIBinding{
+		newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		),
+		newAPIBinding("root:consumer", "gadgets", "root:provider", "gadgets"),
+		newAPIBinding("root:other", "widgets", "root:provider", "widgets",
+			apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+		),
+		newAPIBinding("root:unknown", "gadgets", "root:provider", "unknown"),
+	}
+	gadgets := newAPIExport("root:provider", "gadgets", withLocalPolicy())
+	gadgets.Spec.LatestResourceSchemas = []string{"v1.gadgets.gadgets.example.io", "v1.configmaps.core"}
+
+	for _, tt := range []struct {
+		name         string
+		opts         []MaximalPermissionPolicyAuthorizerOption
+		cluster      string
+		group        string
+		resource     string
+		wantDecision authorizer.Decision
+		wantReason   string
+	}{
+		{name: "pending binding ignored by default", cluster: "root:consumer", group: "gadgets.example.io", resource: "gadgets", wantDecision: authorizer.DecisionAllow, wantReason: "delegate"},
+		{name: "pending binding", opts: []MaximalPermissionPolicyAuthorizerOption{WithPendingAPIBindingsNoOpinion()}, cluster: "root:consumer", group: "gadgets.example.io", resource: "gadgets", wantDecision: authorizer.DecisionNoOpinion, wantReason: ReasonBindingPending},
+		{name: "bound resource next to pending binding", opts: []MaximalPermissionPolicyAuthorizerOption{WithPendingAPIBindingsNoOpinion()}, cluster: "root:consumer", group: "widgets.example.io", resource: "widgets", wantDecision: authorizer.DecisionAllow, wantReason: "delegate"},
+		{name: "core resource of pending binding", opts: []MaximalPermissionPolicyAuthorizerOption{WithPendingAPIBindingsNoOpinion()}, cluster: "root:consumer", group: "", resource: "configmaps", wantDecision: authorizer.DecisionNoOpinion, wantReason: ReasonBindingPending},
+		{name: "unrelated core resource next to pending binding", opts: []MaximalPermissionPolicyAuthorizerOption{WithPendingAPIBindingsNoOpinion()}, cluster: "root:consumer", group: "", resource: "secrets", wantDecision: authorizer.DecisionAllow, wantReason: "delegate"},
+		{name: "other resource of the group of pending binding", opts: []MaximalPermissionPolicyAuthorizerOption{WithPendingAPIBindingsNoOpinion()}, cluster: "root:consumer", group: "gadgets.example.io", resource: "gizmos", wantDecision: authorizer.DecisionAllow, wantReason: "delegate"},
+		{name: "pending binding of unknown API export", opts: []MaximalPermissionPolicyAuthorizerOption{WithPendingAPIBindingsNoOpinion()}, cluster: "root:unknown", group: "gadgets.example.io", resource: "gadgets", wantDecision: authorizer.DecisionAllow, wantReason: "delegate"},
+		{name: "no pending binding", opts: []MaximalPermissionPolicyAuthorizerOption{WithPendingAPIBindingsNoOpinion()}, cluster: "root:other", group: "gadgets.example.io", resource: "gadgets", wantDecision: authorizer.DecisionAllow, wantReason: "delegate"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestMaximalPermissionPolicyAuthorizer(t, bindings,
+				[]*apisv1alpha1.APIExport{
+					newAPIExport("root:provider", "widgets", withLocalPolicy()),
+					gadgets,
+				},
+				&recordingAuthorizer{decision: authorizer.DecisionAllow},
+				&recordingAuthorizer{decision: authorizer.DecisionAllow, reason: "delegate"},
+				tt.opts...,
+			)
+
+			ctx, ev := withAuditEvent(withCluster(tt.cluster))
+			dec, reason, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            "get",
+				APIGroup:        tt.group,
+				Resource:        tt.resource,
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, tt.wantReason, reason)
+			if tt.wantReason == ReasonBindingPending {
+				require.Contains(t, ev.Annotations[MaximalPermissionPolicyAuditReason], "API bindings gadgets pending")
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+// PrewarmRBACAuthorizers constructs the RBAC authorizers of the clusters owning API exports with a maximal local or global
+// permission policy and evaluates them once, such that the first request for a bound resource does not pay the cost of
+// resolving the aggregation rules of the bound ClusterRoles. The evaluation resolves the rules of every ClusterRoleBinding
+// of the API export cluster and the merge clusters binding a subject a request can be evaluated as, i.e. one bearing the
+// user and group prefix for a local policy. RoleBindings are namespaced and left to the first request.
+//
+// It is meant to be called on startup after the informers have synced. At most limit clusters are prewarmed,
+// zero meaning unlimited. It stops early with the context error if the context is done.
+// The prewarmed clusters are returned.
+//
+// The RBAC of the parent of the requesting cluster (see WithConsumerParentRBAC) is only known per request,
+// hence only the merge with the local admin cluster, or the fallback clusters, is prewarmed.
+func (a *MaximalPermissionPolicyAuthorizer) PrewarmRBACAuthorizers(ctx context.Context, limit int) ([]logicalcluster.Name, error) {
+	apiExports, err := a.listAPIExportsWithPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	var prewarmed []logicalcluster.Name
+	seen := sets.NewString()
+	for _, apiExport := range apiExports {
+		if limit > 0 && len(prewarmed) >= limit {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return prewarmed, err
+		}
+
+		policy := apiExport.Spec.MaximalPermissionPolicy
+		if policy == nil || (policy.Local == nil && policy.Global == nil) {
+			continue
+		}
+		clusterName := logicalcluster.From(apiExport)
+		if seen.Has(clusterName.String()) {
+			continue
+		}
+		seen.Insert(clusterName.String())
+
+		var mergeClusters []logicalcluster.Name
+		if a.adminClusterRBACMerge(ctx, apiExport) {
+			mergeClusters = append(mergeClusters, a.adminClusters()...)
+		}
+		// a global policy, or a local one with a custom identity rewriter, evaluates unprefixed subjects
+		prefix := ""
+		if policy.Local != nil && a.identityRewriter == nil {
+			prefix = a.rbacUserGroupPrefix()
+		}
+		if err := a.prewarm(ctx, a.newAuthorizer(clusterName, mergeClusters), clusterName, mergeClusters, prefix); err != nil {
+			return prewarmed, err
+		}
+		prewarmed = append(prewarmed, clusterName)
+	}
+	return prewarmed, nil
+}
+
+// prewarm resolves the rules of the given RBAC authorizer for the subjects bearing the prefix of the ClusterRoleBindings
+// of the given cluster and the merge clusters. Authorizers not resolving rules, e.g. of WithClusterAuthorizerFactory,
+// are not evaluated.
+func (a *MaximalPermissionPolicyAuthorizer) prewarm(ctx context.Context, clusterAuthorizer authorizer.Authorizer, clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name, prefix string) error {
+	ruleResolver, ok := clusterAuthorizer.(authorizer.RuleResolver)
+	if !ok {
+		return nil
+	}
+	clusterRoleBindings, err := a.listClusterRoleBindings(clusterName, mergeClusters)
+	if err != nil {
+		return err
+	}
+	for _, u := range prewarmUsers(clusterRoleBindings, prefix) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// errors, e.g. of bindings of missing roles, are returned again by the first request
+		ruleResolver.RulesFor(u, "") //nolint:errcheck
+	}
+	return nil
+}
+
+// prewarmUsers returns users such that every subject bearing the prefix of the given ClusterRoleBindings applies
+// to one of them: one user per user name, respectively service account, all carrying all groups.
+// Service accounts never bear a non-empty prefix.
+func prewarmUsers(clusterRoleBindings []*rbacv1.ClusterRoleBinding, prefix string) []user.Info {
+	names, groups := sets.NewString(), sets.NewString()
+	for _, clusterRoleBinding := range clusterRoleBindings {
+		for _, subject := range clusterRoleBinding.Subjects {
+			switch {
+			case subject.Kind == rbacv1.UserKind && strings.HasPrefix(subject.Name, prefix):
+				names.Insert(subject.Name)
+			case subject.Kind == rbacv1.GroupKind && strings.HasPrefix(subject.Name, prefix):
+				groups.Insert(subject.Name)
+			case subject.Kind == rbacv1.ServiceAccountKind && prefix == "" && subject.Namespace != "":
+				names.Insert(serviceaccount.MakeUsername(subject.Namespace, subject.Name))
+			}
+		}
+	}
+	if names.Len() == 0 && groups.Len() == 0 {
+		return nil
+	}
+	if names.Len() == 0 {
+		return []user.Info{&user.DefaultInfo{Groups: groups.List()}}
+	}
+
+	users := make([]user.Info, 0, names.Len())
+	for _, name := range names.List() {
+		users = append(users, &user.DefaultInfo{Name: name, Groups: groups.List()})
+	}
+	return users
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"testing"
+
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/genericcontrolplane"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+func TestPrewarmRBACAuthorizers(t *testing.T) {
+	apiExportIndexer := cache.NewIndexer(kcpcache.MetaClusterNamespaceKeyFunc, cache.Indexers{
+		indexers.APIExportByMaximalPermissionPolicy: indexers.IndexAPIExportByMaximalPermissionPolicy,
+	})
+	for _, apiExport := range []*apisv1alpha1.APIExport{
+		newAPIExport("root:provider-1", "widgets", withLocalPolicy()),
+		newAPIExport("root:provider-1", "gadgets", withLocalPolicy()),
+		newAPIExport("root:provider-2", "widgets", withLocalPolicy()),
+		newAPIExport("root:global", "widgets", &apisv1alpha1.MaximalPermissionPolicy{Global: &apisv1alpha1.GlobalAPIExportPolicy{}}),
+		newAPIExport("root:verbs", "widgets", &apisv1alpha1.MaximalPermissionPolicy{Verbs: []apisv1alpha1.ResourceVerbsPolicy{{Resource: "widgets", Verbs: []string{"get"}}}}),
+		newAPIExport("root:unrestricted", "widgets", nil),
+	} {
+		require.NoError(t, apiExportIndexer.Add(apiExport))
+	}
+
+	clusterRoleBindings := map[string][]*rbacv1.ClusterRoleBinding{
+		"root:provider-1": {newClusterRoleBinding("widgets-getter", "widgets-getter",
+			rbacv1.Subject{Kind: rbacv1.UserKind, Name: "apis.kcp.dev:binding:user-1"},
+			rbacv1.Subject{Kind: rbacv1.GroupKind, Name: "apis.kcp.dev:binding:system:authenticated"},
+			rbacv1.Subject{Kind: rbacv1.UserKind, Name: "user-1"},
+		)},
+		"system:admin": {newClusterRoleBinding("gadgets-getter", "gadgets-getter",
+			rbacv1.Subject{Kind: rbacv1.GroupKind, Name: "apis.kcp.dev:binding:system:masters"},
+			rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: "default", Name: "gadgets"},
+		)},
+		"root:global": {newClusterRoleBinding("widgets-getter", "widgets-getter",
+			rbacv1.Subject{Kind: rbacv1.UserKind, Name: "user-1"},
+			rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: "default", Name: "widgets"},
+		)},
+	}
+
+	for _, tt := range []struct {
+		name          string
+		ctx           func() context.Context
+		limit         int
+		wantPrewarmed []string
+		wantLimited   bool
+		wantErr       bool
+	}{
+		{
+			name:          "all clusters with a local policy",
+			ctx:           context.Background,
+			wantPrewarmed: []string{"root:provider-1", "root:provider-2", "root:global"},
+		},
+		{
+			name:          "limited",
+			ctx:           context.Background,
+			limit:         1,
+			wantPrewarmed: []string{"root:provider-1", "root:provider-2", "root:global"},
+			wantLimited:   true,
+		},
+		{
+			name: "cancelled",
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			wantErr: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var constructed []string
+			resolved := map[string][]user.Info{}
+			a := &MaximalPermissionPolicyAuthorizer{
+				listAPIExportsWithPolicy: func() ([]*apisv1alpha1.APIExport, error) {
+					return indexers.ByIndex[*apisv1alpha1.APIExport](apiExportIndexer, indexers.APIExportByMaximalPermissionPolicy, "true")
+				},
+				newAuthorizer: func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+					require.Equal(t, []logicalcluster.Name{genericcontrolplane.LocalAdminCluster}, mergeClusters)
+					constructed = append(constructed, clusterName.String())
+					return &rulesRecordingAuthorizer{resolved: resolved, clusterName: clusterName}
+				},
+				listClusterRoleBindings: func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) ([]*rbacv1.ClusterRoleBinding, error) {
+					bindings := clusterRoleBindings[clusterName.String()]
+					for _, mergeCluster := range mergeClusters {
+						bindings = append(bindings, clusterRoleBindings[mergeCluster.String()]...)
+					}
+					return bindings, nil
+				},
+			}
+
+			prewarmed, err := a.PrewarmRBACAuthorizers(tt.ctx(), tt.limit)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			var got []string
+			for _, clusterName := range prewarmed {
+				got = append(got, clusterName.String())
+			}
+			require.Equal(t, got, constructed)
+			if tt.wantLimited {
+				require.Len(t, got, tt.limit)
+				require.Subset(t, tt.wantPrewarmed, got)
+				return
+			}
+			require.ElementsMatch(t, tt.wantPrewarmed, got)
+			if tt.wantErr {
+				require.Empty(t, resolved)
+				return
+			}
+
+			// the prefixed subjects of the export cluster and the admin cluster apply to the users of a local policy
+			require.Equal(t, []user.Info{
+				&user.DefaultInfo{Name: "apis.kcp.dev:binding:user-1", Groups: []string{"apis.kcp.dev:binding:system:authenticated", "apis.kcp.dev:binding:system:masters"}},
+			}, resolved["root:provider-1"])
+			require.Equal(t, []user.Info{
+				&user.DefaultInfo{Groups: []string{"apis.kcp.dev:binding:system:masters"}},
+			}, resolved["root:provider-2"])
+			// the unprefixed subjects apply to the users of a global policy
+			require.Equal(t, []user.Info{
+				&user.DefaultInfo{Name: "system:serviceaccount:default:gadgets", Groups: []string{"apis.kcp.dev:binding:system:masters"}},
+				&user.DefaultInfo{Name: "system:serviceaccount:default:widgets", Groups: []string{"apis.kcp.dev:binding:system:masters"}},
+				&user.DefaultInfo{Name: "user-1", Groups: []string{"apis.kcp.dev:binding:system:masters"}},
+			}, resolved["root:global"])
+		})
+	}
+}
+
+// rulesRecordingAuthorizer records the users rules are resolved for by cluster.
+type rulesRecordingAuthorizer struct {
+	recordingAuthorizer
+	resolved    map[string][]user.Info
+	clusterName logicalcluster.Name
+}
+
+func (a *rulesRecordingAuthorizer) RulesFor(u user.Info, namespace string) ([]authorizer.ResourceRuleInfo, []authorizer.NonResourceRuleInfo, bool, error) {
+	a.resolved[a.clusterName.String()] = append(a.resolved[a.clusterName.String()], u)
+	return nil, nil, false, nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	rbacv1 "k8s.io/api/rbac/v1"
+	rbacregistryvalidation "k8s.io/kubernetes/pkg/registry/rbac/validation"
+)
+
+// explicitVerbsRoleGetter returns Roles without "*" verbs in their rules.
+type explicitVerbsRoleGetter struct {
+	delegate rbacregistryvalidation.RoleGetter
+}
+
+func (g *explicitVerbsRoleGetter) GetRole(namespace, name string) (*rbacv1.Role, error) {
+	role, err := g.delegate.GetRole(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	role = role.DeepCopy()
+	role.Rules = withoutWildcardVerbs(role.Rules)
+	return role, nil
+}
+
+// explicitVerbsClusterRoleGetter returns ClusterRoles without "*" verbs in their rules.
+type explicitVerbsClusterRoleGetter struct {
+	delegate rbacregistryvalidation.ClusterRoleGetter
+}
+
+func (g *explicitVerbsClusterRoleGetter) GetClusterRole(name string) (*rbacv1.ClusterRole, error) {
+	clusterRole, err := g.delegate.GetClusterRole(name)
+	if err != nil {
+		return nil, err
+	}
+	clusterRole = clusterRole.DeepCopy()
+	clusterRole.Rules = withoutWildcardVerbs(clusterRole.Rules)
+	return clusterRole, nil
+}
+
+// withoutWildcardVerbs removes the "*" verbs from the rules. Rules left without verbs grant nothing.
+func withoutWildcardVerbs(rules []rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	for i := range rules {
+		verbs := make([]string, 0, len(rules[i].Verbs))
+		for _, verb := range rules[i].Verbs {
+			if verb != rbacv1.VerbAll {
+				verbs = append(verbs, verb)
+			}
+		}
+		rules[i].Verbs = verbs
+	}
+	return rules
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"sort"
+
+	kcpkubernetesinformers "github.com/kcp-dev/client-go/clients/informers"
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/kubernetes/pkg/genericcontrolplane"
+	rbacregistryvalidation "k8s.io/kubernetes/pkg/registry/rbac/validation"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+// NewMaximalPermissionPolicyRuleResolver returns a rule resolver for the RBAC the maximal permission policy
+// of an API export in the given cluster is evaluated against.
+func NewMaximalPermissionPolicyRuleResolver(kubeInformers kcpkubernetesinformers.SharedInformerFactory, clusterName logicalcluster.Name) rbacregistryvalidation.AuthorizationRuleResolver {
+	return rbacregistryvalidation.NewDefaultRuleResolver(newMergedRBACGetters(kubeInformers, clusterName, genericcontrolplane.LocalAdminCluster))
+}
+
+// EffectiveMaximalPermissionPolicyRules returns the rules a maximal permission policy grants to the given user
+// in the namespace, i.e. the rules bound to the prefixed user and groups. For cluster-wide rules use an empty namespace.
+func EffectiveMaximalPermissionPolicyRules(resolver rbacregistryvalidation.AuthorizationRuleResolver, u user.Info, namespace string) ([]rbacv1.PolicyRule, error) {
+	prefixedUser := &user.DefaultInfo{
+		Name:  apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + u.GetName(),
+		UID:   u.GetUID(),
+		Extra: u.GetExtra(),
+	}
+	for _, g := range u.GetGroups() {
+		prefixedUser.Groups = append(prefixedUser.Groups, apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix+g)
+	}
+
+	return resolver.RulesFor(prefixedUser, namespace)
+}
+
+// DiffMaximalPermissionPolicyRules compares two sets of effective rules, e.g. computed before and after an RBAC change,
+// and returns the rules granted only by newRules as added, and those granted only by oldRules as removed.
+// Rules are broken down into rules with a single verb, API group, resource and resource name or
+// non-resource URL, such that rules granting the same permissions compare equal regardless of their layout.
+func DiffMaximalPermissionPolicyRules(oldRules, newRules []rbacv1.PolicyRule) (added, removed []rbacv1.PolicyRule) {
+	oldSet := breakdownRules(oldRules)
+	newSet := breakdownRules(newRules)
+
+	for key, rule := range newSet {
+		if _, found := oldSet[key]; !found {
+			added = append(added, rule)
+		}
+	}
+	for key, rule := range oldSet {
+		if _, found := newSet[key]; !found {
+			removed = append(removed, rule)
+		}
+	}
+
+	sortRules(added)
+	sortRules(removed)
+	return added, removed
+}
+
+func breakdownRules(rules []rbacv1.PolicyRule) map[string]rbacv1.PolicyRule {
+	ret := map[string]rbacv1.PolicyRule{}
+	for _, rule := range rules {
+		for _, r := range breakdownRule(rule) {
+			ret[r.String()] = r
+		}
+	}
+	return ret
+}
+
+// breakdownRule splits a rule into rules with a single verb, API group, resource and resource name,
+// or a single verb and non-resource URL.
+func breakdownRule(rule rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	var ret []rbacv1.PolicyRule
+	for _, verb := range rule.Verbs {
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				if len(rule.ResourceNames) == 0 {
+					ret = append(ret, rbacv1.PolicyRule{Verbs: []string{verb}, APIGroups: []string{group}, Resources: []string{resource}})
+					continue
+				}
+				for _, name := range rule.ResourceNames {
+					ret = append(ret, rbacv1.PolicyRule{Verbs: []string{verb}, APIGroups: []string{group}, Resources: []string{resource}, ResourceNames: []string{name}})
+				}
+			}
+		}
+		for _, url := range rule.NonResourceURLs {
+			ret = append(ret, rbacv1.PolicyRule{Verbs: []string{verb}, NonResourceURLs: []string{url}})
+		}
+	}
+	return ret
+}
+
+func sortRules(rules []rbacv1.PolicyRule) {
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].String() < rules[j].String()
+	})
+}
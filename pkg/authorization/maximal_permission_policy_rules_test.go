@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	rbacregistryvalidation "k8s.io/kubernetes/pkg/registry/rbac/validation"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+func newClusterRole(name string, rules ...rbacv1.PolicyRule) *rbacv1.ClusterRole {
+	return &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Rules:      rules,
+	}
+}
+
+func newClusterRoleBinding(name, roleName string, subjects ...rbacv1.Subject) *rbacv1.ClusterRoleBinding {
+	return &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: roleName},
+		Subjects:   subjects,
+	}
+}
+
+func TestEffectiveMaximalPermissionPolicyRules(t *testing.T) {
+	resolver, _ := rbacregistryvalidation.NewTestRuleResolver(nil, nil,
+		[]*rbacv1.ClusterRole{
+			newClusterRole("user-role", rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}}),
+			newClusterRole("group-role", rbacv1.PolicyRule{Verbs: []string{"list"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}}),
+			newClusterRole("unprefixed-role", rbacv1.PolicyRule{Verbs: []string{"delete"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}}),
+		},
+		[]*rbacv1.ClusterRoleBinding{
+			newClusterRoleBinding("user", "user-role", rbacv1.Subject{Kind: rbacv1.UserKind, Name: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "user-1"}),
+			newClusterRoleBinding("group", "group-role", rbacv1.Subject{Kind: rbacv1.GroupKind, Name: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "group-1"}),
+			newClusterRoleBinding("unprefixed", "unprefixed-role", rbacv1.Subject{Kind: rbacv1.UserKind, Name: "user-1"}),
+		},
+	)
+
+	rules, err := EffectiveMaximalPermissionPolicyRules(resolver, &user.DefaultInfo{Name: "user-1", Groups: []string{"group-1"}}, "")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []rbacv1.PolicyRule{
+		{Verbs: []string{"get"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}},
+		{Verbs: []string{"list"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}},
+	}, rules)
+}
+
+func TestDiffMaximalPermissionPolicyRules(t *testing.T) {
+	subject := rbacv1.Subject{Kind: rbacv1.UserKind, Name: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "user-1"}
+	before, _ := rbacregistryvalidation.NewTestRuleResolver(nil, nil,
+		[]*rbacv1.ClusterRole{
+			newClusterRole("widgets", rbacv1.PolicyRule{Verbs: []string{"get", "list", "delete"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}}),
+		},
+		[]*rbacv1.ClusterRoleBinding{newClusterRoleBinding("widgets", "widgets", subject)},
+	)
+	after, _ := rbacregistryvalidation.NewTestRuleResolver(nil, nil,
+		[]*rbacv1.ClusterRole{
+			newClusterRole("widgets",
+				rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}},
+				rbacv1.PolicyRule{Verbs: []string{"list", "watch"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}},
+			),
+		},
+		[]*rbacv1.ClusterRoleBinding{newClusterRoleBinding("widgets", "widgets", subject)},
+	)
+
+	u := &user.DefaultInfo{Name: "user-1"}
+	oldRules, err := EffectiveMaximalPermissionPolicyRules(before, u, "")
+	require.NoError(t, err)
+	newRules, err := EffectiveMaximalPermissionPolicyRules(after, u, "")
+	require.NoError(t, err)
+
+	added, removed := DiffMaximalPermissionPolicyRules(oldRules, newRules)
+	require.Equal(t, []rbacv1.PolicyRule{{Verbs: []string{"watch"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}}}, added)
+	require.Equal(t, []rbacv1.PolicyRule{{Verbs: []string{"delete"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}}}, removed)
+
+	added, removed = DiffMaximalPermissionPolicyRules(newRules, newRules)
+	require.Empty(t, added)
+	require.Empty(t, removed)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/kubernetes/plugin/pkg/auth/authorizer/rbac"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+func TestMaximalPermissionPolicyAuthorizerStrictEmptyPolicy(t *testing.T) {
+	kubeInformers := newKubeInformers(t,
+		inCluster("root:widgets-provider", newClusterRole("widgets", rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}})),
+		inCluster("root:widgets-provider", newClusterRoleBinding("widgets", "widgets", rbacv1.Subject{Kind: rbacv1.UserKind, Name: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "user-1"})),
+		// a binding of a missing role grants nothing
+		inCluster("root:widgets-provider", newClusterRoleBinding("missing", "missing", rbacv1.Subject{Kind: rbacv1.GroupKind, Name: apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix + "system:authenticated"})),
+		// not granted by the maximal permission policy
+		inCluster("root:gadgets-provider", newClusterRole("widgets", rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}})),
+		inCluster("root:gadgets-provider", newClusterRoleBinding("widgets", "widgets", rbacv1.Subject{Kind: rbacv1.UserKind, Name: "user-1"})),
+	)
+
+	for _, tt := range []struct {
+		name         string
+		strict       bool
+		exportName   string
+		verb         string
+		resource     string
+		wantDecision authorizer.Decision
+		wantReason   string
+		wantAudit    string
+	}{
+		{name: "empty policy has no opinion by default", exportName: "gadgets", verb: "get", resource: "widgets", wantDecision: authorizer.DecisionNoOpinion, wantReason: ReasonPolicyDenied},
+		{name: "empty policy denies if strict", strict: true, exportName: "gadgets", verb: "get", resource: "widgets", wantDecision: authorizer.DecisionDeny, wantReason: ReasonPolicyEmpty,
+			wantAudit: `local maximal permission policy of API export "gadgets", path: "root:gadgets-provider" grants nothing on "widgets"`},
+		{name: "policy granting", strict: true, exportName: "widgets", verb: "get", resource: "widgets", wantDecision: authorizer.DecisionAllow, wantReason: "delegate"},
+		{name: "policy not granting the request", strict: true, exportName: "widgets", verb: "delete", resource: "widgets", wantDecision: authorizer.DecisionNoOpinion, wantReason: ReasonPolicyDenied},
+		{name: "policy granting other resources only denies if strict", strict: true, exportName: "widgets", verb: "get", resource: "gizmos", wantDecision: authorizer.DecisionDeny, wantReason: ReasonPolicyEmpty,
+			wantAudit: `local maximal permission policy of API export "widgets", path: "root:widgets-provider" grants nothing on "gizmos"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestMaximalPermissionPolicyAuthorizer(t,
+				[]*apisv1alpha1.APIBinding{newAPIBinding("root:consumer", tt.exportName, "root:"+tt.exportName+"-provider", tt.exportName,
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+					apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "gizmos"},
+				)},
+				[]*apisv1alpha1.APIExport{
+					newAPIExport("root:widgets-provider", "widgets", withLocalPolicy()),
+					newAPIExport("root:gadgets-provider", "gadgets", withLocalPolicy()),
+				},
+				nil,
+				&recordingAuthorizer{decision: authorizer.DecisionAllow, reason: "delegate"},
+				WithStrictEmptyPolicy(tt.strict),
+			)
+			a.newAuthorizer = func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name) authorizer.Authorizer {
+				return rbac.New(newMergedRBACGetters(kubeInformers, clusterName, mergeClusters...))
+			}
+			a.prefixedBindingsGrantResource = func(clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name, prefix, group, resource, subresource string) (bool, error) {
+				return prefixedBindingsGrantResource(kubeInformers, clusterName, mergeClusters, prefix, group, resource, subresource)
+			}
+
+			ctx, ev := withAuditEvent(withCluster("root:consumer"))
+			dec, reason, err := a.Authorize(ctx, &authorizer.AttributesRecord{
+				User:            &user.DefaultInfo{Name: "user-1"},
+				Verb:            tt.verb,
+				APIGroup:        "widgets.example.io",
+				Resource:        tt.resource,
+				ResourceRequest: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, dec)
+			require.Equal(t, tt.wantReason, reason)
+			if tt.wantReason == ReasonPolicyEmpty {
+				require.Equal(t, DecisionDenied, ev.Annotations[MaximalPermissionPolicyAuditDecision])
+				require.Equal(t, tt.wantAudit, ev.Annotations[MaximalPermissionPolicyAuditReason])
+			}
+		})
+	}
+}
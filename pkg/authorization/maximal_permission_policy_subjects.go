@@ -0,0 +1,204 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	kcpkubernetesinformers "github.com/kcp-dev/client-go/clients/informers"
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	rbacv1helpers "k8s.io/kubernetes/pkg/apis/rbac/v1"
+	rbacregistryvalidation "k8s.io/kubernetes/pkg/registry/rbac/validation"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+)
+
+// PrefixedSubject is a user or group subject of a (Cluster)RoleBinding whose name bears a prefix
+// the maximal permission policy prefixes users and groups with.
+type PrefixedSubject struct {
+	// BindingKind is either "ClusterRoleBinding" or "RoleBinding".
+	BindingKind string
+	// Namespace is the namespace of a RoleBinding, empty for ClusterRoleBindings.
+	Namespace string
+	// BindingName is the name of the (Cluster)RoleBinding.
+	BindingName string
+	// Subject is the prefixed subject.
+	Subject rbacv1.Subject
+}
+
+// FindPrefixedSubjects returns the prefixed subjects of the RBAC bindings of the given API export cluster,
+// ordered by binding kind, namespace and name. See PrefixedSubjects.
+func FindPrefixedSubjects(kubeInformers kcpkubernetesinformers.SharedInformerFactory, clusterName logicalcluster.Name) ([]PrefixedSubject, error) {
+	clusterRoleBindings, err := kubeInformers.Rbac().V1().ClusterRoleBindings().Lister().Cluster(clusterName).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	roleBindings, err := kubeInformers.Rbac().V1().RoleBindings().Lister().Cluster(clusterName).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	// listers return objects in random order
+	sort.Slice(clusterRoleBindings, func(i, j int) bool {
+		return clusterRoleBindings[i].Name < clusterRoleBindings[j].Name
+	})
+	sort.Slice(roleBindings, func(i, j int) bool {
+		if roleBindings[i].Namespace != roleBindings[j].Namespace {
+			return roleBindings[i].Namespace < roleBindings[j].Namespace
+		}
+		return roleBindings[i].Name < roleBindings[j].Name
+	})
+	return PrefixedSubjects(clusterRoleBindings, roleBindings), nil
+}
+
+// PrefixedSubjects returns the user and group subjects of the given RBAC bindings of an API export cluster
+// whose names bear a maximal permission policy prefix, i.e. MaximalPermissionPolicyRBACUserGroupPrefix or
+// MaximalPermissionPolicyCandidateRBACUserGroupPrefix. Such subjects are meant to grant the maximal permission
+// policy, but they also apply to real users and groups named like that, e.g. a user literally named
+// "apis.kcp.dev:binding:alice". It is meant for admission and command line tooling warning of these collisions.
+func PrefixedSubjects(clusterRoleBindings []*rbacv1.ClusterRoleBinding, roleBindings []*rbacv1.RoleBinding) []PrefixedSubject {
+	var ret []PrefixedSubject
+	for _, binding := range clusterRoleBindings {
+		for _, subject := range binding.Subjects {
+			if isPrefixedSubject(subject) {
+				ret = append(ret, PrefixedSubject{BindingKind: "ClusterRoleBinding", BindingName: binding.Name, Subject: subject})
+			}
+		}
+	}
+	for _, binding := range roleBindings {
+		for _, subject := range binding.Subjects {
+			if isPrefixedSubject(subject) {
+				ret = append(ret, PrefixedSubject{BindingKind: "RoleBinding", Namespace: binding.Namespace, BindingName: binding.Name, Subject: subject})
+			}
+		}
+	}
+	return ret
+}
+
+// prefixedBindingsIndexName returns the name of the index of the (Cluster)RoleBindings binding a user or group whose
+// name bears the prefix, see addPrefixedBindingsIndexers.
+func prefixedBindingsIndexName(prefix string) string {
+	return "maximalPermissionPolicyPrefixedBindings:" + prefix
+}
+
+// addPrefixedBindingsIndexers indexes the ClusterRoleBindings and RoleBindings binding a user or group whose name bears
+// the prefix by logical cluster.
+func addPrefixedBindingsIndexers(kubeInformers kcpkubernetesinformers.SharedInformerFactory, prefix string) {
+	indexName := prefixedBindingsIndexName(prefix)
+	indexers.AddIfNotPresentOrDie(kubeInformers.Rbac().V1().ClusterRoleBindings().Informer().GetIndexer(), cache.Indexers{
+		indexName: indexPrefixedBindingsByCluster(prefix),
+	})
+	indexers.AddIfNotPresentOrDie(kubeInformers.Rbac().V1().RoleBindings().Informer().GetIndexer(), cache.Indexers{
+		indexName: indexPrefixedBindingsByCluster(prefix),
+	})
+}
+
+// indexPrefixedBindingsByCluster returns an index function indexing a (Cluster)RoleBinding by logical cluster
+// if it binds a user or group whose name bears the prefix.
+func indexPrefixedBindingsByCluster(prefix string) cache.IndexFunc {
+	return func(obj interface{}) ([]string, error) {
+		var subjects []rbacv1.Subject
+		switch binding := obj.(type) {
+		case *rbacv1.ClusterRoleBinding:
+			subjects = binding.Subjects
+		case *rbacv1.RoleBinding:
+			subjects = binding.Subjects
+		default:
+			return []string{}, fmt.Errorf("obj %T is not a ClusterRoleBinding or RoleBinding", obj)
+		}
+		if !hasPrefixedSubject(subjects, prefix) {
+			return []string{}, nil
+		}
+		return []string{logicalcluster.From(obj.(metav1.Object)).String()}, nil
+	}
+}
+
+// prefixedBindingsGrantResource returns whether any ClusterRoleBinding of the given cluster or the merge clusters, or any
+// RoleBinding of the given cluster, binds a user or group whose name bears the prefix to a role with a rule for the
+// resource of the given group, i.e. whether a maximal local permission policy evaluated against the merged RBAC may grant
+// anything on the resource. The bindings are looked up by the index of addPrefixedBindingsIndexers, which must have been
+// added for the prefix. Bindings of missing roles grant nothing.
+func prefixedBindingsGrantResource(kubeInformers kcpkubernetesinformers.SharedInformerFactory, clusterName logicalcluster.Name, mergeClusters []logicalcluster.Name, prefix, group, resource, subresource string) (bool, error) {
+	indexName := prefixedBindingsIndexName(prefix)
+	resolver := rbacregistryvalidation.NewDefaultRuleResolver(newMergedRBACGetters(kubeInformers, clusterName, mergeClusters...))
+	grants := func(roleRef rbacv1.RoleRef, namespace string) (bool, error) {
+		rules, err := resolver.GetRoleReferenceRules(roleRef, namespace)
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		for i := range rules {
+			if rbacv1helpers.APIGroupMatches(&rules[i], group) && rbacv1helpers.ResourceMatches(&rules[i], resource, subresource) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	for _, cluster := range append([]logicalcluster.Name{clusterName}, mergeClusters...) {
+		objs, err := kubeInformers.Rbac().V1().ClusterRoleBindings().Informer().GetIndexer().ByIndex(indexName, cluster.String())
+		if err != nil {
+			return false, err
+		}
+		for _, obj := range objs {
+			if found, err := grants(obj.(*rbacv1.ClusterRoleBinding).RoleRef, ""); err != nil || found {
+				return found, err
+			}
+		}
+	}
+
+	objs, err := kubeInformers.Rbac().V1().RoleBindings().Informer().GetIndexer().ByIndex(indexName, clusterName.String())
+	if err != nil {
+		return false, err
+	}
+	for _, obj := range objs {
+		binding := obj.(*rbacv1.RoleBinding)
+		if found, err := grants(binding.RoleRef, binding.Namespace); err != nil || found {
+			return found, err
+		}
+	}
+	return false, nil
+}
+
+// hasPrefixedSubject returns whether any of the subjects is a user or group whose name bears the prefix.
+func hasPrefixedSubject(subjects []rbacv1.Subject, prefix string) bool {
+	for _, subject := range subjects {
+		if (subject.Kind == rbacv1.UserKind || subject.Kind == rbacv1.GroupKind) && strings.HasPrefix(subject.Name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPrefixedSubject returns whether the subject is a user or group bearing a maximal permission policy prefix.
+func isPrefixedSubject(subject rbacv1.Subject) bool {
+	if subject.Kind != rbacv1.UserKind && subject.Kind != rbacv1.GroupKind {
+		return false
+	}
+	return strings.HasPrefix(subject.Name, apisv1alpha1.MaximalPermissionPolicyRBACUserGroupPrefix) ||
+		strings.HasPrefix(subject.Name, MaximalPermissionPolicyCandidateRBACUserGroupPrefix)
+}
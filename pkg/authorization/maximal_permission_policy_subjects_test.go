@@ -0,0 +1,162 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPrefixedSubjects(t *testing.T) {
+	for _, tt := range []struct {
+		name                string
+		clusterRoleBindings []*rbacv1.ClusterRoleBinding
+		roleBindings        []*rbacv1.RoleBinding
+		want                []PrefixedSubject
+	}{
+		{
+			name: "clean cluster",
+			clusterRoleBindings: []*rbacv1.ClusterRoleBinding{
+				newClusterRoleBinding("admin", "cluster-admin",
+					rbacv1.Subject{Kind: rbacv1.UserKind, Name: "alice"},
+					rbacv1.Subject{Kind: rbacv1.GroupKind, Name: "system:authenticated"},
+				),
+			},
+			roleBindings: []*rbacv1.RoleBinding{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "edit"},
+				Subjects: []rbacv1.Subject{
+					{Kind: rbacv1.ServiceAccountKind, Namespace: "default", Name: "apis.kcp.dev:binding:robot"},
+				},
+			}},
+		},
+		{
+			name: "colliding subjects",
+			clusterRoleBindings: []*rbacv1.ClusterRoleBinding{
+				newClusterRoleBinding("admin", "cluster-admin",
+					rbacv1.Subject{Kind: rbacv1.UserKind, Name: "alice"},
+					rbacv1.Subject{Kind: rbacv1.UserKind, Name: "apis.kcp.dev:binding:alice"},
+				),
+				newClusterRoleBinding("candidate", "view",
+					rbacv1.Subject{Kind: rbacv1.GroupKind, Name: "apis.kcp.dev:candidate-binding:system:authenticated"},
+				),
+			},
+			roleBindings: []*rbacv1.RoleBinding{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "edit"},
+				Subjects: []rbacv1.Subject{
+					{Kind: rbacv1.GroupKind, Name: "apis.kcp.dev:binding:team"},
+				},
+			}},
+			want: []PrefixedSubject{
+				{BindingKind: "ClusterRoleBinding", BindingName: "admin", Subject: rbacv1.Subject{Kind: rbacv1.UserKind, Name: "apis.kcp.dev:binding:alice"}},
+				{BindingKind: "ClusterRoleBinding", BindingName: "candidate", Subject: rbacv1.Subject{Kind: rbacv1.GroupKind, Name: "apis.kcp.dev:candidate-binding:system:authenticated"}},
+				{BindingKind: "RoleBinding", Namespace: "default", BindingName: "edit", Subject: rbacv1.Subject{Kind: rbacv1.GroupKind, Name: "apis.kcp.dev:binding:team"}},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, PrefixedSubjects(tt.clusterRoleBindings, tt.roleBindings))
+		})
+	}
+}
+
+func TestFindPrefixedSubjects(t *testing.T) {
+	kubeInformers := newKubeInformers(t,
+		inCluster("root:provider", newClusterRoleBinding("widgets-getter", "widgets-getter",
+			rbacv1.Subject{Kind: rbacv1.UserKind, Name: "apis.kcp.dev:binding:alice"},
+			rbacv1.Subject{Kind: rbacv1.UserKind, Name: "bob"},
+		)),
+		inCluster("root:provider", newClusterRoleBinding("admin", "cluster-admin",
+			rbacv1.Subject{Kind: rbacv1.GroupKind, Name: "apis.kcp.dev:binding:system:authenticated"},
+		)),
+		inCluster("root:other", newClusterRoleBinding("widgets-getter", "widgets-getter",
+			rbacv1.Subject{Kind: rbacv1.UserKind, Name: "apis.kcp.dev:binding:eve"},
+		)),
+		inCluster("root:clean", newClusterRoleBinding("widgets-getter", "widgets-getter",
+			rbacv1.Subject{Kind: rbacv1.UserKind, Name: "alice"},
+		)),
+	)
+
+	t.Run("colliding subjects", func(t *testing.T) {
+		subjects, err := FindPrefixedSubjects(kubeInformers, logicalcluster.New("root:provider"))
+		require.NoError(t, err)
+		require.Equal(t, []PrefixedSubject{
+			{BindingKind: "ClusterRoleBinding", BindingName: "admin", Subject: rbacv1.Subject{Kind: rbacv1.GroupKind, Name: "apis.kcp.dev:binding:system:authenticated"}},
+			{BindingKind: "ClusterRoleBinding", BindingName: "widgets-getter", Subject: rbacv1.Subject{Kind: rbacv1.UserKind, Name: "apis.kcp.dev:binding:alice"}},
+		}, subjects)
+	})
+
+	t.Run("clean cluster", func(t *testing.T) {
+		subjects, err := FindPrefixedSubjects(kubeInformers, logicalcluster.New("root:clean"))
+		require.NoError(t, err)
+		require.Empty(t, subjects)
+	})
+}
+
+func TestPrefixedBindingsGrantResource(t *testing.T) {
+	kubeInformers := newKubeInformers(t,
+		inCluster("root:provider", newClusterRole("widgets-getter", rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}})),
+		inCluster("root:provider", newClusterRoleBinding("widgets-getter", "widgets-getter",
+			rbacv1.Subject{Kind: rbacv1.UserKind, Name: "apis.kcp.dev:binding:alice"},
+		)),
+		inCluster("root:provider", newClusterRole("gizmos-getter", rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"gizmos"}})),
+		inCluster("root:provider", newClusterRoleBinding("gizmos-getter", "gizmos-getter",
+			rbacv1.Subject{Kind: rbacv1.UserKind, Name: "bob"},
+		)),
+		inCluster("root:provider", &rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "sprockets-status"},
+			Rules:      []rbacv1.PolicyRule{{Verbs: []string{"update"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"sprockets/status"}}},
+		}),
+		inCluster("root:provider", &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "sprockets-status"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "sprockets-status"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, Name: "apis.kcp.dev:binding:system:authenticated"}},
+		}),
+		inCluster("root:admin", newClusterRole("gadgets-getter", rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"gadgets.example.io"}, Resources: []string{"*"}})),
+		inCluster("root:admin", newClusterRoleBinding("gadgets-getter", "gadgets-getter",
+			rbacv1.Subject{Kind: rbacv1.UserKind, Name: "apis.kcp.dev:binding:alice"},
+		)),
+	)
+
+	for _, tt := range []struct {
+		name          string
+		mergeClusters []logicalcluster.Name
+		group         string
+		resource      string
+		subresource   string
+		want          bool
+	}{
+		{name: "cluster role binding", group: "widgets.example.io", resource: "widgets", want: true},
+		{name: "other resource of the group", group: "widgets.example.io", resource: "doodads"},
+		{name: "binding of an unprefixed user", group: "widgets.example.io", resource: "gizmos"},
+		{name: "role binding to a subresource", group: "widgets.example.io", resource: "sprockets/status", subresource: "status", want: true},
+		{name: "parent of a subresource", group: "widgets.example.io", resource: "sprockets"},
+		{name: "merge cluster", mergeClusters: []logicalcluster.Name{logicalcluster.New("root:admin")}, group: "gadgets.example.io", resource: "gadgets", want: true},
+		{name: "merge cluster not merged", group: "gadgets.example.io", resource: "gadgets"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := prefixedBindingsGrantResource(kubeInformers, logicalcluster.New("root:provider"), tt.mergeClusters, "apis.kcp.dev:binding:", tt.group, tt.resource, tt.subresource)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	maximalPermissionPolicyTracerName = "github.com/kcp-dev/kcp/pkg/authorization"
+
+	// spans of the maximal permission policy authorizer
+	spanMatchAPIBinding   = "MaximalPermissionPolicy.MatchAPIBinding"
+	spanResolveAPIExport  = "MaximalPermissionPolicy.ResolveAPIExport"
+	spanAuthorizeRBAC     = "MaximalPermissionPolicy.AuthorizeRBAC"
+	spanAttributeCluster  = "kcp.cluster"
+	spanAttributeExport   = "kcp.apiexport.name"
+	spanAttributeExportWS = "kcp.apiexport.cluster"
+)
+
+var noopTracer = trace.NewNoopTracerProvider().Tracer(maximalPermissionPolicyTracerName)
+
+// WithTracerProvider makes the authorizer record spans around the API binding lookup, the API export lookup and the
+// RBAC evaluation in the API export cluster with a tracer of the given provider, e.g. the TracerProvider of the
+// generic apiserver config, to correlate slow authorization with lister latency.
+func WithTracerProvider(tp trace.TracerProvider) MaximalPermissionPolicyAuthorizerOption {
+	return func(a *MaximalPermissionPolicyAuthorizer) {
+		a.tracer = tp.Tracer(maximalPermissionPolicyTracerName)
+	}
+}
+
+// startSpan starts a span with the configured tracer, or a no-op span without.
+func (a *MaximalPermissionPolicyAuthorizer) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	tracer := a.tracer
+	if tracer == nil {
+		tracer = noopTracer
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+func TestMaximalPermissionPolicyAuthorizerTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	a := newTestMaximalPermissionPolicyAuthorizer(t,
+		[]*apisv1alpha1.APIBinding{
+			newAPIBinding("root:consumer", "widgets", "root:provider", "widgets",
+				apisv1alpha1.BoundAPIResource{Group: "widgets.example.io", Resource: "widgets"},
+			),
+		},
+		[]*apisv1alpha1.APIExport{newAPIExport("root:provider", "widgets", withLocalPolicy())},
+		&recordingAuthorizer{decision: authorizer.DecisionAllow},
+		&recordingAuthorizer{decision: authorizer.DecisionAllow},
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))),
+	)
+
+	dec, _, err := a.Authorize(withCluster("root:consumer"), &authorizer.AttributesRecord{
+		User:            &user.DefaultInfo{Name: "user-1"},
+		Verb:            "get",
+		APIGroup:        "widgets.example.io",
+		Resource:        "widgets",
+		ResourceRequest: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, authorizer.DecisionAllow, dec)
+
+	spans := map[string]map[attribute.Key]string{}
+	for _, span := range exporter.GetSpans() {
+		attrs := map[attribute.Key]string{}
+		for _, kv := range span.Attributes {
+			attrs[kv.Key] = kv.Value.AsString()
+		}
+		spans[span.Name] = attrs
+	}
+	require.Equal(t, map[string]map[attribute.Key]string{
+		spanMatchAPIBinding: {
+			spanAttributeCluster: "root:consumer",
+		},
+		spanResolveAPIExport: {
+			spanAttributeExport:   "widgets",
+			spanAttributeExportWS: "root:provider",
+		},
+		spanAuthorizeRBAC: {
+			spanAttributeExport:   "widgets",
+			spanAttributeExportWS: "root:provider",
+		},
+	}, spans)
+}
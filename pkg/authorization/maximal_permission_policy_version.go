@@ -0,0 +1,114 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	kcpkubernetesinformers "github.com/kcp-dev/client-go/clients/informers"
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/martinlindhe/base36"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+// maximalPermissionPolicyVersionAnnotations are the APIExport annotations changing decisions of the maximal permission
+// policy, covered by MaximalPermissionPolicyVersion.
+var maximalPermissionPolicyVersionAnnotations = []string{
+	MaximalPermissionPolicyOldGroupsAnnotationKey,
+	MaximalPermissionPolicyAdminClusterMergeAnnotationKey,
+	MaximalPermissionPolicyCandidateAnnotationKey,
+}
+
+// MaximalPermissionPolicyVersion returns a compact version token of the maximal permission policy of the API export,
+// e.g. to validate cached decisions. It changes when the policy, the old-groups, admin-cluster-merge or candidate-policy
+// annotations of the API export, the given merge clusters or any RBAC object of the API export cluster or the merge
+// clusters change. The merge clusters depend on the authorizer configuration and the request, i.e. callers pass the
+// clusters whose RBAC is merged with the RBAC of the API export cluster, e.g. the local admin cluster.
+func MaximalPermissionPolicyVersion(kubeInformers kcpkubernetesinformers.SharedInformerFactory, apiExport *apisv1alpha1.APIExport, mergeClusters ...logicalcluster.Name) (string, error) {
+	policy, err := json.Marshal(apiExport.Spec.MaximalPermissionPolicy)
+	if err != nil {
+		return "", err
+	}
+	header := []string{"policy=" + string(policy)}
+	for _, key := range maximalPermissionPolicyVersionAnnotations {
+		if value, ok := apiExport.Annotations[key]; ok {
+			header = append(header, fmt.Sprintf("annotations/%s=%q", key, value))
+		}
+	}
+	merged := make([]string, 0, len(mergeClusters))
+	for _, mergeCluster := range mergeClusters {
+		merged = append(merged, mergeCluster.String())
+	}
+	// the order of merge clusters matters for the getters of the merged RBAC
+	header = append(header, "merge-clusters="+strings.Join(merged, ","))
+
+	var lines []string
+	for _, clusterName := range append([]logicalcluster.Name{logicalcluster.From(apiExport)}, mergeClusters...) {
+		clusterLines, err := rbacVersionLines(kubeInformers, clusterName)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, clusterLines...)
+	}
+
+	// listers return objects in random order
+	sort.Strings(lines)
+	hash := sha256.Sum224([]byte(strings.Join(append(header, lines...), "\n")))
+	// convert the hash to base36 (alphanumeric) to keep the token compact
+	return strings.ToLower(base36.EncodeBytes(hash[:]))[:16], nil
+}
+
+// rbacVersionLines returns a line with the resource version of every RBAC object of the given cluster.
+func rbacVersionLines(kubeInformers kcpkubernetesinformers.SharedInformerFactory, clusterName logicalcluster.Name) ([]string, error) {
+	var lines []string
+	roles, err := kubeInformers.Rbac().V1().Roles().Lister().Cluster(clusterName).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		lines = append(lines, fmt.Sprintf("%s/roles/%s/%s=%s", clusterName, role.Namespace, role.Name, role.ResourceVersion))
+	}
+	roleBindings, err := kubeInformers.Rbac().V1().RoleBindings().Lister().Cluster(clusterName).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, roleBinding := range roleBindings {
+		lines = append(lines, fmt.Sprintf("%s/rolebindings/%s/%s=%s", clusterName, roleBinding.Namespace, roleBinding.Name, roleBinding.ResourceVersion))
+	}
+	clusterRoles, err := kubeInformers.Rbac().V1().ClusterRoles().Lister().Cluster(clusterName).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, clusterRole := range clusterRoles {
+		lines = append(lines, fmt.Sprintf("%s/clusterroles/%s=%s", clusterName, clusterRole.Name, clusterRole.ResourceVersion))
+	}
+	clusterRoleBindings, err := kubeInformers.Rbac().V1().ClusterRoleBindings().Lister().Cluster(clusterName).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, clusterRoleBinding := range clusterRoleBindings {
+		lines = append(lines, fmt.Sprintf("%s/clusterrolebindings/%s=%s", clusterName, clusterRoleBinding.Name, clusterRoleBinding.ResourceVersion))
+	}
+	return lines, nil
+}
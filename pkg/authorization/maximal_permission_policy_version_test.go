@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+func TestMaximalPermissionPolicyVersion(t *testing.T) {
+	clusterRole := func(clusterName, resourceVersion string) *rbacv1.ClusterRole {
+		return inCluster(clusterName, &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "widgets-getter", ResourceVersion: resourceVersion}})
+	}
+	clusterRoleBinding := func(clusterName, resourceVersion string) *rbacv1.ClusterRoleBinding {
+		return inCluster(clusterName, &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "widgets-getter", ResourceVersion: resourceVersion}})
+	}
+	versionWithMergeClusters := func(t *testing.T, apiExport *apisv1alpha1.APIExport, mergeClusters []logicalcluster.Name, objs ...runtime.Object) string {
+		t.Helper()
+		v, err := MaximalPermissionPolicyVersion(newKubeInformers(t, objs...), apiExport, mergeClusters...)
+		require.NoError(t, err)
+		return v
+	}
+	version := func(t *testing.T, apiExport *apisv1alpha1.APIExport, objs ...runtime.Object) string {
+		t.Helper()
+		return versionWithMergeClusters(t, apiExport, nil, objs...)
+	}
+
+	apiExport := newAPIExport("root:provider", "widgets", withLocalPolicy())
+	base := version(t, apiExport, clusterRole("root:provider", "1"), clusterRoleBinding("root:provider", "2"))
+
+	t.Run("stable", func(t *testing.T) {
+		require.Equal(t, base, version(t, apiExport, clusterRoleBinding("root:provider", "2"), clusterRole("root:provider", "1")))
+	})
+
+	t.Run("RBAC of other clusters ignored", func(t *testing.T) {
+		require.Equal(t, base, version(t, apiExport, clusterRole("root:provider", "1"), clusterRoleBinding("root:provider", "2"), clusterRole("root:other", "3")))
+	})
+
+	t.Run("RBAC changed", func(t *testing.T) {
+		require.NotEqual(t, base, version(t, apiExport, clusterRole("root:provider", "3"), clusterRoleBinding("root:provider", "2")))
+	})
+
+	t.Run("RBAC added", func(t *testing.T) {
+		role := inCluster("root:provider", &rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "widgets-getter", ResourceVersion: "3"}})
+		require.NotEqual(t, base, version(t, apiExport, clusterRole("root:provider", "1"), clusterRoleBinding("root:provider", "2"), role))
+	})
+
+	t.Run("policy changed", func(t *testing.T) {
+		verbsExport := newAPIExport("root:provider", "widgets", &apisv1alpha1.MaximalPermissionPolicy{Verbs: []apisv1alpha1.ResourceVerbsPolicy{{Resource: "widgets", Verbs: []string{"get"}}}})
+		require.NotEqual(t, base, version(t, verbsExport, clusterRole("root:provider", "1"), clusterRoleBinding("root:provider", "2")))
+	})
+
+	for _, key := range []string{
+		MaximalPermissionPolicyOldGroupsAnnotationKey,
+		MaximalPermissionPolicyAdminClusterMergeAnnotationKey,
+		MaximalPermissionPolicyCandidateAnnotationKey,
+	} {
+		t.Run("annotation "+key+" changed", func(t *testing.T) {
+			annotatedExport := apiExport.DeepCopy()
+			annotatedExport.Annotations[key] = "false"
+			annotated := version(t, annotatedExport, clusterRole("root:provider", "1"), clusterRoleBinding("root:provider", "2"))
+			require.NotEqual(t, base, annotated)
+
+			annotatedExport.Annotations[key] = "true"
+			require.NotEqual(t, annotated, version(t, annotatedExport, clusterRole("root:provider", "1"), clusterRoleBinding("root:provider", "2")))
+		})
+	}
+
+	t.Run("other annotations ignored", func(t *testing.T) {
+		annotatedExport := apiExport.DeepCopy()
+		annotatedExport.Annotations["example.io/owner"] = "widgets-team"
+		require.Equal(t, base, version(t, annotatedExport, clusterRole("root:provider", "1"), clusterRoleBinding("root:provider", "2")))
+	})
+
+	mergeClusters := []logicalcluster.Name{logicalcluster.New("system:admin")}
+	merged := versionWithMergeClusters(t, apiExport, mergeClusters, clusterRole("root:provider", "1"), clusterRoleBinding("root:provider", "2"), clusterRole("system:admin", "3"))
+
+	t.Run("merge clusters changed", func(t *testing.T) {
+		require.NotEqual(t, base, merged)
+		require.NotEqual(t, base, versionWithMergeClusters(t, apiExport, mergeClusters, clusterRole("root:provider", "1"), clusterRoleBinding("root:provider", "2")))
+	})
+
+	t.Run("RBAC of merge clusters changed", func(t *testing.T) {
+		require.Equal(t, merged, versionWithMergeClusters(t, apiExport, mergeClusters, clusterRole("system:admin", "3"), clusterRoleBinding("root:provider", "2"), clusterRole("root:provider", "1")))
+		require.NotEqual(t, merged, versionWithMergeClusters(t, apiExport, mergeClusters, clusterRole("root:provider", "1"), clusterRoleBinding("root:provider", "2"), clusterRole("system:admin", "4")))
+	})
+}
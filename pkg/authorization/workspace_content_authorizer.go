@@ -278,13 +278,24 @@ func (a *workspaceContentAuthorizer) Authorize(ctx context.Context, attr authori
 	return a.delegate.Authorize(ctx, withGroups)
 }
 
+// deepCopyAttributes returns a copy of the attributes whose user, including its groups and extra, can be modified
+// without affecting the given attributes. It copies every field, i.e. authorizers evaluating the copy see the same
+// request; TestDeepCopyAttributes fails for fields added to authorizer.AttributesRecord.
 func deepCopyAttributes(attr authorizer.Attributes) authorizer.AttributesRecord {
+	var extra map[string][]string
+	if attr.GetUser().GetExtra() != nil {
+		extra = make(map[string][]string, len(attr.GetUser().GetExtra()))
+		for k, v := range attr.GetUser().GetExtra() {
+			extra[k] = append([]string(nil), v...)
+		}
+	}
+
 	return authorizer.AttributesRecord{
 		User: &user.DefaultInfo{
 			Name:   attr.GetUser().GetName(),
 			UID:    attr.GetUser().GetUID(),
-			Groups: attr.GetUser().GetGroups(),
-			Extra:  attr.GetUser().GetExtra(),
+			Groups: append([]string(nil), attr.GetUser().GetGroups()...),
+			Extra:  extra,
 		},
 		Verb:            attr.GetVerb(),
 		Namespace:       attr.GetNamespace(),
@@ -439,3 +439,51 @@ func TestWorkspaceContentAuthorizer(t *testing.T) {
 		})
 	}
 }
+
+func TestDeepCopyAttributes(t *testing.T) {
+	fullyPopulated := authorizer.AttributesRecord{
+		User: &user.DefaultInfo{
+			Name:   "user-1",
+			UID:    "uid-1",
+			Groups: []string{"team-1"},
+			Extra:  map[string][]string{"scopes": {"scope-1"}},
+		},
+		Verb:            "update",
+		Namespace:       "default",
+		APIGroup:        "widgets.example.io",
+		APIVersion:      "v1",
+		Resource:        "widgets",
+		Subresource:     "status",
+		Name:            "widget-1",
+		ResourceRequest: true,
+		Path:            "/apis/widgets.example.io/v1/namespaces/default/widgets/widget-1/status",
+	}
+
+	// every field of the fixture is set, i.e. fields added upstream fail here until copied and covered below
+	v := reflect.ValueOf(fullyPopulated)
+	for i := 0; i < v.NumField(); i++ {
+		require.False(t, v.Field(i).IsZero(), "field %s of the fixture is not set", v.Type().Field(i).Name)
+	}
+
+	for _, tt := range []struct {
+		name string
+		attr authorizer.AttributesRecord
+	}{
+		{name: "fully populated resource request", attr: fullyPopulated},
+		{name: "non-resource request", attr: authorizer.AttributesRecord{
+			User: &user.DefaultInfo{Name: "user-1"},
+			Verb: "get",
+			Path: "/healthz",
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.attr, deepCopyAttributes(&tt.attr))
+
+			// the prefixed attributes differ in the user only
+			prefixedAttr := prefixedAttributes(&tt.attr, tt.attr.APIGroup, "prefix:", nil)
+			require.Equal(t, "prefix:"+tt.attr.User.GetName(), prefixedAttr.User.GetName())
+			prefixedAttr.User = tt.attr.User
+			require.Equal(t, tt.attr, prefixedAttr)
+		})
+	}
+}
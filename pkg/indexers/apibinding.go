@@ -18,10 +18,13 @@ package indexers
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/kcp-dev/logicalcluster/v2"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
 
 	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
 )
@@ -86,3 +89,52 @@ func IndexAPIBindingByAPIExport(obj interface{}) ([]string, error) {
 
 	return []string{ClusterPathAndAPIExportName(apiBinding.Spec.Reference.Workspace.Path, apiBinding.Spec.Reference.Workspace.ExportName)}, nil
 }
+
+// APIBindingByClusterAndBoundGroupResource is the indexer name for retrieving APIBindings by logical cluster
+// and the group and resource of their bound resources.
+const APIBindingByClusterAndBoundGroupResource = "APIBindingByClusterAndBoundGroupResource"
+
+// ClusterAndBoundGroupResourceValue returns the index value for use with IndexAPIBindingByClusterAndBoundGroupResource
+// of the form <cluster name>|<group>|<resource>.
+func ClusterAndBoundGroupResourceValue(clusterName logicalcluster.Name, group, resource string) string {
+	return fmt.Sprintf("%s|%s|%s", clusterName, group, resource)
+}
+
+// IndexAPIBindingByClusterAndBoundGroupResource returns an index function that indexes an APIBinding by its logical
+// cluster and the group and resource of each of its bound resources, see ClusterAndBoundGroupResourceValue. If
+// groupAliasesAnnotationKey is not empty, the APIBinding is also indexed by the alias groups of its bound groups
+// listed in the annotation with that key, of the form "<alias group>=<group>" and comma separated.
+func IndexAPIBindingByClusterAndBoundGroupResource(groupAliasesAnnotationKey string) cache.IndexFunc {
+	return func(obj interface{}) ([]string, error) {
+		apiBinding, ok := obj.(*apisv1alpha1.APIBinding)
+		if !ok {
+			return []string{}, fmt.Errorf("obj %T is not an APIBinding", obj)
+		}
+
+		clusterName := logicalcluster.From(apiBinding)
+		aliases := map[string][]string{}
+		if mappings, ok := apiBinding.Annotations[groupAliasesAnnotationKey]; ok && groupAliasesAnnotationKey != "" {
+			for _, mapping := range strings.Split(mappings, ",") {
+				parts := strings.SplitN(strings.TrimSpace(mapping), "=", 2)
+				if len(parts) == 2 {
+					aliases[parts[1]] = append(aliases[parts[1]], parts[0])
+				}
+			}
+		}
+
+		var ret []string
+		for _, r := range apiBinding.Status.BoundResources {
+			// an empty bound resource is invalid and binds nothing
+			if r.Group == "" && r.Resource == "" {
+				klog.Warningf("Ignoring empty bound resource of APIBinding %s|%s", clusterName, apiBinding.Name)
+				continue
+			}
+			ret = append(ret, ClusterAndBoundGroupResourceValue(clusterName, r.Group, r.Resource))
+			for _, alias := range aliases[r.Group] {
+				ret = append(ret, ClusterAndBoundGroupResourceValue(clusterName, alias, r.Resource))
+			}
+		}
+
+		return ret, nil
+	}
+}
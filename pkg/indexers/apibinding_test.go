@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package indexers
+
+import (
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
+)
+
+func TestIndexAPIBindingByClusterAndBoundGroupResource(t *testing.T) {
+	const aliasesKey = "example.io/group-aliases"
+
+	for _, tt := range []struct {
+		name        string
+		annotations map[string]string
+		resources   []apisv1alpha1.BoundAPIResource
+		aliasesKey  string
+		want        []string
+	}{
+		{
+			name: "no bound resources",
+		},
+		{
+			name: "bound resources",
+			resources: []apisv1alpha1.BoundAPIResource{
+				{Group: "widgets.example.io", Resource: "widgets"},
+				{Group: "widgets.example.io", Resource: "widgets/scale"},
+				{Group: "", Resource: "configmaps"},
+			},
+			want: []string{
+				"root:consumer|widgets.example.io|widgets",
+				"root:consumer|widgets.example.io|widgets/scale",
+				"root:consumer||configmaps",
+			},
+		},
+		{
+			name: "empty bound resource",
+			resources: []apisv1alpha1.BoundAPIResource{
+				{},
+				{Group: "widgets.example.io", Resource: "widgets"},
+			},
+			want: []string{"root:consumer|widgets.example.io|widgets"},
+		},
+		{
+			name:        "alias groups",
+			annotations: map[string]string{aliasesKey: "widgets.alias.io=widgets.example.io, other.alias.io=other.example.io,invalid"},
+			resources:   []apisv1alpha1.BoundAPIResource{{Group: "widgets.example.io", Resource: "widgets"}},
+			aliasesKey:  aliasesKey,
+			want: []string{
+				"root:consumer|widgets.example.io|widgets",
+				"root:consumer|widgets.alias.io|widgets",
+			},
+		},
+		{
+			name:        "alias groups without annotation key",
+			annotations: map[string]string{aliasesKey: "widgets.alias.io=widgets.example.io"},
+			resources:   []apisv1alpha1.BoundAPIResource{{Group: "widgets.example.io", Resource: "widgets"}},
+			want:        []string{"root:consumer|widgets.example.io|widgets"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{logicalcluster.AnnotationKey: "root:consumer"}
+			for k, v := range tt.annotations {
+				annotations[k] = v
+			}
+			apiBinding := &apisv1alpha1.APIBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "widgets", Annotations: annotations},
+				Status:     apisv1alpha1.APIBindingStatus{BoundResources: tt.resources},
+			}
+
+			got, err := IndexAPIBindingByClusterAndBoundGroupResource(tt.aliasesKey)(apiBinding)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := IndexAPIBindingByClusterAndBoundGroupResource("")(&apisv1alpha1.APIExport{})
+	require.Error(t, err)
+}
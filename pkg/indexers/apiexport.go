@@ -18,6 +18,7 @@ package indexers
 
 import (
 	"fmt"
+	"strconv"
 
 	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
 	"github.com/kcp-dev/logicalcluster/v2"
@@ -30,6 +31,10 @@ const (
 	APIExportByIdentity = "APIExportByIdentity"
 	// APIExportBySecret is the indexer name for retrieving APIExports by secret.
 	APIExportBySecret = "APIExportSecret"
+	// APIExportByMaximalPermissionPolicy is the indexer name for retrieving APIExports by whether they have a maximal permission policy.
+	APIExportByMaximalPermissionPolicy = "APIExportByMaximalPermissionPolicy"
+	// APIExportByClusterAndName is the indexer name for retrieving APIExports by logical cluster and name.
+	APIExportByClusterAndName = "APIExportByClusterAndName"
 )
 
 // IndexAPIExportByIdentity is an index function that indexes an APIExport by its identity hash.
@@ -66,6 +71,28 @@ func IndexAPIExportBySecret(obj interface{}) ([]string, error) {
 	return []string{kcpcache.ToClusterAwareKey(logicalcluster.From(apiExport).String(), ref.Namespace, ref.Name)}, nil
 }
 
+// IndexAPIExportByMaximalPermissionPolicy is an index function that indexes an APIExport by whether it has
+// a maximal permission policy. Index values are "true" and "false".
+func IndexAPIExportByMaximalPermissionPolicy(obj interface{}) ([]string, error) {
+	apiExport, ok := obj.(*apisv1alpha1.APIExport)
+	if !ok {
+		return []string{}, fmt.Errorf("obj %T is not an APIExport", obj)
+	}
+
+	return []string{strconv.FormatBool(apiExport.Spec.MaximalPermissionPolicy != nil)}, nil
+}
+
+// IndexAPIExportByClusterAndName is an index function that indexes an APIExport by its logical cluster and name.
+// Index values are of the form <cluster name>|<export name>, see ClusterPathAndAPIExportName.
+func IndexAPIExportByClusterAndName(obj interface{}) ([]string, error) {
+	apiExport, ok := obj.(*apisv1alpha1.APIExport)
+	if !ok {
+		return []string{}, fmt.Errorf("obj %T is not an APIExport", obj)
+	}
+
+	return []string{ClusterPathAndAPIExportName(logicalcluster.From(apiExport).String(), apiExport.Name)}, nil
+}
+
 func ClusterPathAndAPIExportName(clusterPath, exportName string) string {
 	return fmt.Sprintf("%s|%s", clusterPath, exportName)
 }
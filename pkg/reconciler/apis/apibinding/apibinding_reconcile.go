@@ -132,6 +132,9 @@ func (c *controller) reconcileBinding(ctx context.Context, apiBinding *apisv1alp
 
 	logger = logging.WithObject(logger, apiExport)
 
+	// Record the resolved cluster of the export, e.g. for the maximal permission policy authorizer
+	apiBinding.Status.APIExportClusterName = logicalcluster.From(apiExport).String()
+
 	// Record the export's permission claims
 	apiBinding.Status.ExportPermissionClaims = apiExport.Spec.PermissionClaims
 
@@ -340,6 +343,15 @@ func (c *controller) reconcileBinding(ctx context.Context, apiBinding *apisv1alp
 			}
 		}
 
+		// Restrict the verbs of the bound resource as listed by the APIExport
+		var verbs []string
+		for _, v := range apiExport.Spec.BoundResourceVerbs {
+			if v.Group == schema.Spec.Group && v.Resource == schema.Spec.Names.Plural {
+				verbs = v.Verbs
+				break
+			}
+		}
+
 		sortedStorageVersions := storageVersions.List()
 		sort.Strings(sortedStorageVersions)
 
@@ -353,6 +365,7 @@ func (c *controller) reconcileBinding(ctx context.Context, apiBinding *apisv1alp
 				IdentityHash: apiExport.Status.IdentityHash,
 			},
 			StorageVersions: sortedStorageVersions,
+			Verbs:           verbs,
 		}
 
 		found := false
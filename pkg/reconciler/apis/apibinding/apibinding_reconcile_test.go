@@ -81,6 +81,17 @@ var (
 				BoundAPIResource,
 		)
 
+	rebindingWithVerbs = binding.DeepCopy().
+				WithWorkspaceReference("org:some-workspace", "restricted-verbs").
+				WithBoundResources(
+			new(boundAPIResourceBuilder).
+				WithGroupResource("kcp.dev", "widgets").
+				WithSchema("today.widgets.kcp.dev", "todaywidgetsuid").
+				WithStorageVersions("v0", "v1").
+				WithVerbs("delete").
+				BoundAPIResource,
+		)
+
 	invalidSchema = binding.DeepCopy().WithWorkspaceReference("org:some-workspace", "invalid-schema")
 
 	bound = unbound.DeepCopy().
@@ -356,6 +367,31 @@ func TestReconcileBinding(t *testing.T) {
 			wantPhaseBound:             true,
 			wantInitialBindingComplete: true,
 		},
+		"Ensure verbs are set from the APIExport": {
+			apiBinding:         rebindingWithVerbs.Build(),
+			getCRDError:        nil,
+			crdExists:          true,
+			crdEstablished:     true,
+			crdStorageVersions: []string{"v0", "v1"},
+			wantAPIExportValid: true,
+			wantReady:          true,
+			wantBoundAPIExport: true,
+			wantBoundResources: []apisv1alpha1.BoundAPIResource{
+				{
+					Group:    "kcp.dev",
+					Resource: "widgets",
+					Schema: apisv1alpha1.BoundAPIResourceSchema{
+						Name:         "today.widgets.kcp.dev",
+						UID:          "todaywidgetsuid",
+						IdentityHash: "hash1",
+					},
+					StorageVersions: []string{"v0", "v1"},
+					Verbs:           []string{"get", "list"},
+				},
+			},
+			wantPhaseBound:             true,
+			wantInitialBindingComplete: true,
+		},
 	}
 
 	for testName, tc := range tests {
@@ -399,6 +435,22 @@ func TestReconcileBinding(t *testing.T) {
 					},
 					Status: apisv1alpha1.APIExportStatus{IdentityHash: "hash3"},
 				},
+				"restricted-verbs": {
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{
+							logicalcluster.AnnotationKey: "some-workspace",
+						},
+						Name: "restricted-verbs",
+					},
+					Spec: apisv1alpha1.APIExportSpec{
+						LatestResourceSchemas: []string{"today.widgets.kcp.dev"},
+						BoundResourceVerbs: []apisv1alpha1.ResourceVerbsPolicy{
+							{Group: "kcp.dev", Resource: "gadgets", Verbs: []string{"get"}},
+							{Group: "kcp.dev", Resource: "widgets", Verbs: []string{"get", "list"}},
+						},
+					},
+					Status: apisv1alpha1.APIExportStatus{IdentityHash: "hash1"},
+				},
 				"no-identity-hash": {
 					ObjectMeta: metav1.ObjectMeta{
 						Annotations: map[string]string{
@@ -915,3 +967,8 @@ func (b *boundAPIResourceBuilder) WithStorageVersions(v ...string) *boundAPIReso
 	b.StorageVersions = v
 	return b
 }
+
+func (b *boundAPIResourceBuilder) WithVerbs(verbs ...string) *boundAPIResourceBuilder {
+	b.Verbs = verbs
+	return b
+}
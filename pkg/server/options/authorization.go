@@ -26,6 +26,7 @@ import (
 	"k8s.io/apiserver/pkg/authorization/path"
 	"k8s.io/apiserver/pkg/authorization/union"
 	genericapiserver "k8s.io/apiserver/pkg/server"
+	"k8s.io/component-base/metrics/legacyregistry"
 
 	"github.com/kcp-dev/kcp/pkg/authorization"
 	kcpinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
@@ -38,6 +39,10 @@ type Authorization struct {
 
 	// AlwaysAllowGroups are groups which are allowed to take any actions.  In kube, this is system:masters.
 	AlwaysAllowGroups []string
+
+	// MaximalPermissionPolicyDecisionLogs enables logging every decision of the maximal permission policy authorizer.
+	// It is meant for local development.
+	MaximalPermissionPolicyDecisionLogs bool
 }
 
 func NewAuthorization() *Authorization {
@@ -79,6 +84,8 @@ func (s *Authorization) AddFlags(fs *pflag.FlagSet) {
 	fs.StringSliceVar(&s.AlwaysAllowPaths, "authorization-always-allow-paths", s.AlwaysAllowPaths,
 		"A list of HTTP paths to skip during authorization, i.e. these are authorized without "+
 			"contacting the 'core' kubernetes server.")
+	fs.BoolVar(&s.MaximalPermissionPolicyDecisionLogs, "authorization-maximal-permission-policy-decision-logs", s.MaximalPermissionPolicyDecisionLogs,
+		"Log every decision of the maximal permission policy of API exports at verbosity 2. Meant for local development.")
 }
 
 func (s *Authorization) ApplyTo(config *genericapiserver.Config, informer kcpkubernetesinformers.SharedInformerFactory, kcpinformer kcpinformers.SharedInformerFactory) error {
@@ -103,8 +110,15 @@ func (s *Authorization) ApplyTo(config *genericapiserver.Config, informer kcpkub
 	// kcp authorizers
 	bootstrapAuth, bootstrapRules := authorization.NewBootstrapPolicyAuthorizer(informer)
 	localAuth, localResolver := authorization.NewLocalAuthorizer(informer)
+	maximalPermissionPolicyOpts := []authorization.MaximalPermissionPolicyAuthorizerOption{
+		authorization.WithMetrics(authorization.MetricsRegistryFunc(legacyregistry.Register)),
+	}
+	if s.MaximalPermissionPolicyDecisionLogs {
+		maximalPermissionPolicyOpts = append(maximalPermissionPolicyOpts, authorization.WithDecisionLogs())
+	}
 	apiBindingAuth, err := authorization.NewMaximalPermissionPolicyAuthorizer(informer, kcpinformer,
 		union.New(bootstrapAuth, localAuth),
+		maximalPermissionPolicyOpts...,
 	)
 	if err != nil {
 		return err
@@ -19,12 +19,20 @@ limitations under the License.
 package rbac
 
 import (
+	"sort"
+	"time"
+
 	"github.com/kcp-dev/logicalcluster/v2"
 
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apimachinery/pkg/util/sets"
 	rbaclisters "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/utils/clock"
 )
 
 var _ rbaclisters.ClusterRoleBindingLister = (*mergedClusterRoleBindingLister)(nil)
@@ -103,6 +111,133 @@ func (l *mergedClusterRoleLister) Get(name string) (*rbacv1.ClusterRole, error)
 	return mergedItem, errorHolder
 }
 
+var _ rbaclisters.ClusterRoleLister = (*aggregatingClusterRoleLister)(nil)
+
+const (
+	// aggregatedClusterRoleCacheSize is the maximal number of aggregated ClusterRoles cached by an aggregating lister.
+	aggregatedClusterRoleCacheSize = 1000
+	// aggregatedClusterRoleCacheTTL bounds how long changes of the ClusterRoles matching an aggregation rule take
+	// to be reflected by an aggregating lister.
+	aggregatedClusterRoleCacheTTL = 10 * time.Second
+)
+
+// NewAggregatingClusterRoleLister returns a ClusterRole lister resolving the aggregation rules of the ClusterRoles of
+// the given lister, e.g. a merged one, like the Kubernetes ClusterRole aggregation controller does: the rules of a
+// ClusterRole with aggregation rule are the rules of the other ClusterRoles matching any of its selectors, in the
+// order of their names and without duplicates. Matching ClusterRoles with aggregation rule contribute their aggregated
+// rules. Aggregated ClusterRoles are returned as copies, and cached by resource version for aggregatedClusterRoleCacheTTL,
+// i.e. changes of the aggregated ClusterRoles are reflected only after that.
+func NewAggregatingClusterRoleLister(lister rbaclisters.ClusterRoleLister) rbaclisters.ClusterRoleLister {
+	return newAggregatingClusterRoleLister(lister, clock.RealClock{})
+}
+
+func newAggregatingClusterRoleLister(lister rbaclisters.ClusterRoleLister, clock clock.PassiveClock) *aggregatingClusterRoleLister {
+	return &aggregatingClusterRoleLister{
+		lister: lister,
+		cache:  utilcache.NewLRUExpireCacheWithClock(aggregatedClusterRoleCacheSize, clock),
+	}
+}
+
+type aggregatingClusterRoleLister struct {
+	lister rbaclisters.ClusterRoleLister
+	// cache maps the cluster, name and resource version of ClusterRoles with aggregation rule to the aggregated ClusterRole.
+	cache *utilcache.LRUExpireCache
+}
+
+func (l *aggregatingClusterRoleLister) List(selector labels.Selector) (ret []*rbacv1.ClusterRole, err error) {
+	list, err := l.lister.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	ret = make([]*rbacv1.ClusterRole, 0, len(list))
+	for _, clusterRole := range list {
+		aggregated, err := l.aggregate(clusterRole)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, aggregated)
+	}
+	return ret, nil
+}
+
+func (l *aggregatingClusterRoleLister) Get(name string) (*rbacv1.ClusterRole, error) {
+	clusterRole, err := l.lister.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return l.aggregate(clusterRole)
+}
+
+// aggregate returns the ClusterRole with the rules of the ClusterRoles matching its aggregation rule, if any.
+func (l *aggregatingClusterRoleLister) aggregate(clusterRole *rbacv1.ClusterRole) (*rbacv1.ClusterRole, error) {
+	if clusterRole.AggregationRule == nil {
+		return clusterRole, nil
+	}
+
+	key := logicalcluster.From(clusterRole).String() + "|" + clusterRole.Name + "|" + clusterRole.ResourceVersion
+	if aggregated, ok := l.cache.Get(key); ok {
+		return aggregated.(*rbacv1.ClusterRole), nil
+	}
+
+	rules, err := l.aggregatedRules(clusterRole, sets.NewString())
+	if err != nil {
+		return nil, err
+	}
+	aggregated := clusterRole.DeepCopy()
+	aggregated.Rules = rules
+	l.cache.Add(key, aggregated, aggregatedClusterRoleCacheTTL)
+	return aggregated, nil
+}
+
+// aggregatedRules returns the rules of the ClusterRoles matching the aggregation rule of the given ClusterRole,
+// resolving their aggregation rules in turn. The names of the ClusterRoles being aggregated are passed to break cycles.
+func (l *aggregatingClusterRoleLister) aggregatedRules(clusterRole *rbacv1.ClusterRole, aggregating sets.String) ([]rbacv1.PolicyRule, error) {
+	aggregating.Insert(clusterRole.Name)
+	defer aggregating.Delete(clusterRole.Name)
+
+	var rules []rbacv1.PolicyRule
+	for i := range clusterRole.AggregationRule.ClusterRoleSelectors {
+		selector, err := metav1.LabelSelectorAsSelector(&clusterRole.AggregationRule.ClusterRoleSelectors[i])
+		if err != nil {
+			return nil, err
+		}
+		matching, err := l.lister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(matching, func(i, j int) bool {
+			return matching[i].Name < matching[j].Name
+		})
+		for _, other := range matching {
+			if aggregating.Has(other.Name) {
+				continue
+			}
+			otherRules := other.Rules
+			if other.AggregationRule != nil {
+				otherRules, err = l.aggregatedRules(other, aggregating)
+				if err != nil {
+					return nil, err
+				}
+			}
+			for _, rule := range otherRules {
+				if !ruleExists(rules, rule) {
+					rules = append(rules, rule)
+				}
+			}
+		}
+	}
+	return rules, nil
+}
+
+func ruleExists(rules []rbacv1.PolicyRule, rule rbacv1.PolicyRule) bool {
+	for _, existing := range rules {
+		if equality.Semantic.DeepEqual(existing, rule) {
+			return true
+		}
+	}
+	return false
+}
+
 var _ rbaclisters.RoleLister = (*mergedRoleLister)(nil)
 var _ rbaclisters.RoleNamespaceLister = (*mergedRoleNamespaceLister)(nil)
 
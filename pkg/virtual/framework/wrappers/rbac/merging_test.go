@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rbac
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	rbaclisters "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/client-go/tools/cache"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func newClusterRole(name string, labels map[string]string, aggregateLabels map[string]string, rules ...rbacv1.PolicyRule) *rbacv1.ClusterRole {
+	clusterRole := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels, ResourceVersion: "1"},
+		Rules:      rules,
+	}
+	if aggregateLabels != nil {
+		clusterRole.AggregationRule = &rbacv1.AggregationRule{
+			ClusterRoleSelectors: []metav1.LabelSelector{{MatchLabels: aggregateLabels}},
+		}
+	}
+	return clusterRole
+}
+
+func newClusterRoleIndexer(t *testing.T, clusterRoles ...*rbacv1.ClusterRole) cache.Indexer {
+	t.Helper()
+
+	indexer := cache.NewIndexer(cache.LegacyMetaNamespaceKeyFunc, cache.Indexers{})
+	for _, clusterRole := range clusterRoles {
+		require.NoError(t, indexer.Add(clusterRole))
+	}
+	return indexer
+}
+
+func TestAggregatingClusterRoleLister(t *testing.T) {
+	getWidgets := rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}}
+	updateWidgets := rbacv1.PolicyRule{Verbs: []string{"update"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}}
+	getGadgets := rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"gadgets.example.io"}, Resources: []string{"gadgets"}}
+
+	for _, tt := range []struct {
+		name         string
+		clusterRoles []*rbacv1.ClusterRole
+		get          string
+		wantRules    []rbacv1.PolicyRule
+		wantNotFound bool
+	}{
+		{
+			name: "label selector",
+			clusterRoles: []*rbacv1.ClusterRole{
+				newClusterRole("admin", nil, map[string]string{"aggregate-to-admin": "true"}),
+				newClusterRole("widgets-view", map[string]string{"aggregate-to-admin": "true"}, nil, getWidgets),
+				newClusterRole("widgets-edit", map[string]string{"aggregate-to-admin": "true"}, nil, updateWidgets, getWidgets),
+				newClusterRole("gadgets-view", nil, nil, getGadgets),
+			},
+			get:       "admin",
+			wantRules: []rbacv1.PolicyRule{updateWidgets, getWidgets},
+		},
+		{
+			name: "without aggregation rule",
+			clusterRoles: []*rbacv1.ClusterRole{
+				newClusterRole("widgets-view", map[string]string{"aggregate-to-admin": "true"}, nil, getWidgets),
+			},
+			get:       "widgets-view",
+			wantRules: []rbacv1.PolicyRule{getWidgets},
+		},
+		{
+			name: "missing role",
+			clusterRoles: []*rbacv1.ClusterRole{
+				newClusterRole("widgets-view", nil, nil, getWidgets),
+			},
+			get:          "admin",
+			wantNotFound: true,
+		},
+		{
+			name: "no matching roles",
+			clusterRoles: []*rbacv1.ClusterRole{
+				newClusterRole("admin", nil, map[string]string{"aggregate-to-admin": "true"}),
+				newClusterRole("widgets-view", nil, nil, getWidgets),
+			},
+			get: "admin",
+		},
+		{
+			name: "multi-level",
+			clusterRoles: []*rbacv1.ClusterRole{
+				newClusterRole("admin", nil, map[string]string{"aggregate-to-admin": "true"}),
+				newClusterRole("edit", map[string]string{"aggregate-to-admin": "true"}, map[string]string{"aggregate-to-edit": "true"}),
+				newClusterRole("widgets-edit", map[string]string{"aggregate-to-edit": "true"}, nil, updateWidgets),
+				newClusterRole("gadgets-view", map[string]string{"aggregate-to-admin": "true"}, nil, getGadgets),
+			},
+			get:       "admin",
+			wantRules: []rbacv1.PolicyRule{updateWidgets, getGadgets},
+		},
+		{
+			name: "cycle",
+			clusterRoles: []*rbacv1.ClusterRole{
+				newClusterRole("a", map[string]string{"aggregate-to-b": "true"}, map[string]string{"aggregate-to-a": "true"}, getGadgets),
+				newClusterRole("b", map[string]string{"aggregate-to-a": "true"}, map[string]string{"aggregate-to-b": "true"}, updateWidgets),
+				newClusterRole("c", map[string]string{"aggregate-to-b": "true"}, nil, getWidgets),
+			},
+			get:       "a",
+			wantRules: []rbacv1.PolicyRule{getWidgets},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			lister := NewAggregatingClusterRoleLister(rbaclisters.NewClusterRoleLister(newClusterRoleIndexer(t, tt.clusterRoles...)))
+
+			got, err := lister.Get(tt.get)
+			if tt.wantNotFound {
+				require.True(t, apierrors.IsNotFound(err), "expected a not found error, got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantRules, got.Rules)
+		})
+	}
+}
+
+func TestAggregatingClusterRoleListerCache(t *testing.T) {
+	getWidgets := rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"widgets.example.io"}, Resources: []string{"widgets"}}
+	getGadgets := rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"gadgets.example.io"}, Resources: []string{"gadgets"}}
+
+	admin := newClusterRole("admin", nil, map[string]string{"aggregate-to-admin": "true"})
+	indexer := newClusterRoleIndexer(t,
+		admin,
+		newClusterRole("widgets-view", map[string]string{"aggregate-to-admin": "true"}, nil, getWidgets),
+	)
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	lister := newAggregatingClusterRoleLister(rbaclisters.NewClusterRoleLister(indexer), fakeClock)
+
+	got, err := lister.Get("admin")
+	require.NoError(t, err)
+	require.Equal(t, []rbacv1.PolicyRule{getWidgets}, got.Rules)
+	require.Empty(t, admin.Rules, "expected the listed ClusterRole not to be mutated")
+
+	// a new matching role is not reflected until the cached aggregation expires
+	require.NoError(t, indexer.Add(newClusterRole("gadgets-view", map[string]string{"aggregate-to-admin": "true"}, nil, getGadgets)))
+	got, err = lister.Get("admin")
+	require.NoError(t, err)
+	require.Equal(t, []rbacv1.PolicyRule{getWidgets}, got.Rules)
+
+	fakeClock.Step(aggregatedClusterRoleCacheTTL + time.Second)
+	got, err = lister.Get("admin")
+	require.NoError(t, err)
+	require.Equal(t, []rbacv1.PolicyRule{getGadgets, getWidgets}, got.Rules)
+
+	// a change of the aggregating role is reflected right away
+	updated := admin.DeepCopy()
+	updated.ResourceVersion = "2"
+	updated.AggregationRule.ClusterRoleSelectors = []metav1.LabelSelector{{MatchLabels: map[string]string{"aggregate-to-view": "true"}}}
+	require.NoError(t, indexer.Update(updated))
+	got, err = lister.Get("admin")
+	require.NoError(t, err)
+	require.Empty(t, got.Rules)
+}